@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+)
+
+const testToken = "0123456789abcdef"
+
+func authApp(protectReads bool, logs *bytes.Buffer) *Application {
+	logger, _ := logging.New(logs, logging.FormatJSON, slog.LevelInfo)
+	return &Application{
+		Logger: logger,
+		Auth:   config.Auth{Tokens: map[string]string{"ci": testToken}, ProtectReads: protectReads},
+		DB: &MockDatabaseRepo{
+			GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+				return []*models.Post{}, nil
+			},
+			DeletePostFunc: func(ctx context.Context, id int32) (int32, error) {
+				return id, nil
+			},
+		},
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name          string
+		protectReads  bool
+		method, path  string
+		authorization string
+		want          int
+	}{
+		{"anonymous read", false, http.MethodGet, "/posts", "", http.StatusOK},
+		{"anonymous write", false, http.MethodDelete, "/posts/1", "", http.StatusUnauthorized},
+		{"wrong token", false, http.MethodDelete, "/posts/1", "Bearer fedcba9876543210", http.StatusUnauthorized},
+		{"wrong scheme", false, http.MethodDelete, "/posts/1", "Basic " + testToken, http.StatusUnauthorized},
+		{"valid write", false, http.MethodDelete, "/posts/1", "Bearer " + testToken, http.StatusOK},
+		{"invalid token on a read", false, http.MethodGet, "/posts", "Bearer nope", http.StatusUnauthorized},
+		{"protected read", true, http.MethodGet, "/posts", "", http.StatusUnauthorized},
+		{"protected read with token", true, http.MethodGet, "/posts", "bearer " + testToken, http.StatusOK},
+		{"probes stay open", true, http.MethodGet, "/livez", "", http.StatusOK},
+		{"webhook reads always need a token", false, http.MethodGet, "/webhooks", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			app := authApp(tt.protectReads, &logs)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, rr.Code, rr.Body)
+			}
+			if tt.want == http.StatusUnauthorized {
+				if got := rr.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, "Bearer") {
+					t.Errorf("unexpected WWW-Authenticate %q", got)
+				}
+				if got := rr.Header().Get("Content-Type"); got != problemContentType {
+					t.Errorf("expected a problem response, got %q", got)
+				}
+			}
+			if tt.authorization != "" && tt.want == http.StatusOK && !strings.Contains(logs.String(), `"principal":"ci"`) {
+				t.Errorf("expected the principal in the access log, got %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	app := &Application{Logger: logging.Discard(), DB: &MockDatabaseRepo{}}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/posts/1", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected writes to be open without tokens, got %d", rr.Code)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	app := &Application{
+		Logger: logging.Discard(),
+		DB:     &MockDatabaseRepo{},
+		CORS:   config.CORS{AllowedOrigins: []string{"https://news.example"}, AllowedMethods: []string{"GET", "POST"}, MaxAge: time.Minute},
+	}
+
+	req := httptest.NewRequest(http.MethodOptions, "/posts", nil)
+	req.Header.Set("Origin", "https://news.example")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://news.example" {
+		t.Errorf("unexpected allowed origin %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Max-Age"); got != "60" {
+		t.Errorf("unexpected max age %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/livez", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no CORS headers for an unknown origin, got %q", got)
+	}
+}
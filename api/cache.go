@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+)
+
+// lastWriter is implemented by repositories that know when posts last
+// changed, such as cache.Repo
+type lastWriter interface {
+	LastWrite() time.Time
+}
+
+// listLastModified returns when a list of posts last changed: its newest
+// update, or a later write known to the repository, such as a deletion.
+// Without such a repository it returns the zero time, sending no
+// Last-Modified, as a deletion or a post pushing the list down leaves the
+// newest update unchanged.
+func (app *Application) listLastModified(posts []*models.Post) time.Time {
+	lw, ok := app.DB.(lastWriter)
+	if !ok {
+		return time.Time{}
+	}
+
+	last := lw.LastWrite()
+	for _, post := range posts {
+		if post.UpdatedAt.After(last) {
+			last = post.UpdatedAt
+		}
+	}
+	return last
+}
+
+// notModified sets the caching headers of a read and reports whether the
+// client's copy, per If-Modified-Since, is current. It then answers 304 Not
+// Modified and the caller must write nothing else.
+func (app *Application) notModified(w http.ResponseWriter, r *http.Request, lastModified time.Time) bool {
+	w.Header().Set("Cache-Control", app.cacheControl())
+	if lastModified.IsZero() {
+		return false
+	}
+	// HTTP dates have a resolution of one second
+	lastModified = lastModified.Truncate(time.Second)
+	w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.After(since) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// cacheControl lets clients and shared caches reuse reads as long as the
+// server-side cache would, and revalidate them otherwise
+func (app *Application) cacheControl() string {
+	if !app.Cache.Enabled {
+		return "no-cache"
+	}
+
+	scope := "public"
+	if app.Auth.ProtectReads {
+		scope = "private"
+	}
+	return fmt.Sprintf("%s, max-age=%d, stale-while-revalidate=%d",
+		scope, int(app.Cache.TTL.Seconds()), int(app.Cache.StaleWhileRevalidate.Seconds()))
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/cache"
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+)
+
+func TestCacheHeaders(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 10, 0, 0, 500, time.UTC)
+	reads := 0
+	db := &MockDatabaseRepo{
+		GetPostFunc: func(ctx context.Context, id int32) (*models.Post, error) {
+			reads++
+			return &models.Post{ID: int(id), Title: "Title", Content: "Content", UpdatedAt: updated}, nil
+		},
+	}
+	app := &Application{
+		Logger: logging.Discard(),
+		Cache:  config.Cache{Enabled: true, TTL: 5 * time.Second, StaleWhileRevalidate: 30 * time.Second},
+		DB:     cache.NewRepo(db, cache.Options{MaxEntries: 10, TTL: 5 * time.Second, StaleWhileRevalidate: 30 * time.Second}),
+	}
+	handler := app.routes()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/posts/1", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "public, max-age=5, stale-while-revalidate=30" {
+		t.Errorf("unexpected Cache-Control %q", got)
+	}
+	lastModified := rr.Header().Get("Last-Modified")
+	if lastModified != "Fri, 01 Mar 2024 10:00:00 GMT" {
+		t.Errorf("unexpected Last-Modified %q", lastModified)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified || rr.Body.Len() != 0 {
+		t.Errorf("expected an empty 304, got %d with %q", rr.Code, rr.Body)
+	}
+	if reads != 1 {
+		t.Errorf("expected the second read to be served from the cache, got %d reads", reads)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+	req.Header.Set("If-Modified-Since", updated.Add(-time.Hour).Format(http.TimeFormat))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected 200 for an outdated copy, got %d", rr.Code)
+	}
+}
+
+func TestListLastModifiedAfterDelete(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	db := cache.NewRepo(&MockDatabaseRepo{
+		GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+			return []*models.Post{{ID: 1, Title: "Title", Content: "Content", UpdatedAt: updated}}, nil
+		},
+	}, cache.Options{MaxEntries: 10, TTL: time.Minute})
+	app := &Application{Logger: logging.Discard(), DB: db}
+
+	// a deletion changes the list without changing the newest update
+	app.routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/posts/2", nil))
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/posts", nil))
+	lastModified, err := http.ParseTime(rr.Header().Get("Last-Modified"))
+	if err != nil || !lastModified.After(updated) {
+		t.Errorf("expected Last-Modified after the deletion, got %q", rr.Header().Get("Last-Modified"))
+	}
+	if got := rr.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("expected no-cache with the cache headers disabled, got %q", got)
+	}
+}
+
+func TestListNotModifiedWithoutCache(t *testing.T) {
+	updated := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	posts := []*models.Post{
+		{ID: 1, Title: "First", Content: "Content", UpdatedAt: updated},
+		{ID: 2, Title: "Second", Content: "Content", UpdatedAt: updated.Add(-time.Hour)},
+	}
+	app := &Application{Logger: logging.Discard(), DB: &MockDatabaseRepo{
+		GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+			return posts, nil
+		},
+		DeletePostFunc: func(ctx context.Context, id int32) (int32, error) {
+			posts = posts[:1]
+			return id, nil
+		},
+	}}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/posts", nil))
+	if got := rr.Header().Get("Last-Modified"); got != "" {
+		t.Errorf("expected no Last-Modified for a list without the cache, got %q", got)
+	}
+
+	// the deletion leaves the newest update unchanged
+	app.routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/posts/2", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("If-Modified-Since", updated.Format(http.TimeFormat))
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected the changed list, got %d", rr.Code)
+	}
+}
+
+func TestHandleChangeInvalidatesCache(t *testing.T) {
+	reads := 0
+	db := cache.NewRepo(&MockDatabaseRepo{
+		GetPostFunc: func(ctx context.Context, id int32) (*models.Post, error) {
+			reads++
+			return &models.Post{ID: int(id)}, nil
+		},
+	}, cache.Options{MaxEntries: 10, TTL: time.Minute})
+	app := &Application{Logger: logging.Discard(), DB: db}
+	ctx := context.Background()
+
+	db.GetPost(ctx, 1)
+	db.GetPost(ctx, 2)
+	app.handleChange(database.Change{Op: database.ChangeUpdate, ID: 1})
+	db.GetPost(ctx, 1)
+	db.GetPost(ctx, 2)
+	if reads != 3 {
+		t.Errorf("expected only the changed post to be read again, got %d reads", reads)
+	}
+
+	app.handleChange(database.Change{Op: database.ChangeResync})
+	db.GetPost(ctx, 2)
+	if reads != 4 {
+		t.Errorf("expected a resync to drop every post, got %d reads", reads)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "github.com/freshusername/news-api/database"
+
+// invalidator is implemented by repositories caching reads, such as cache.Repo
+type invalidator interface {
+	Invalidate(id int32)
+	InvalidateAll()
+}
+
+// handleChange applies a post change notified by Postgres, whichever
+// instance made it
+func (app *Application) handleChange(change database.Change) {
+	app.Logger.Debug("post changed", "op", change.Op, "post_id", change.ID)
+
+	if cache, ok := app.DB.(invalidator); ok {
+		if change.Op == database.ChangeResync {
+			cache.InvalidateAll()
+		} else {
+			cache.Invalidate(change.ID)
+		}
+	}
+	// the change committed an outbox event, possibly on another instance
+	if app.Relay != nil {
+		app.Relay.Wake()
+	}
+	if app.Tail != nil {
+		app.Tail.Wake()
+	}
+}
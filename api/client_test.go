@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freshusername/news-api/client"
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/models"
+)
+
+// newClientServer runs the real routes over db and returns a client for them
+func newClientServer(t *testing.T, db *MockDatabaseRepo) *client.Client {
+	t.Helper()
+	app := &Application{DB: db}
+	srv := httptest.NewServer(app.routes())
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientCRUD(t *testing.T) {
+	store := map[int32]*models.Post{}
+	db := &MockDatabaseRepo{
+		GetPostFunc: func(ctx context.Context, id int32) (*models.Post, error) {
+			if post, ok := store[id]; ok {
+				return post, nil
+			}
+			return nil, database.ErrNotFound
+		},
+		CreatePostFunc: func(ctx context.Context, post *models.Post) (*models.Post, error) {
+			post.ID = len(store) + 1
+			store[int32(post.ID)] = post
+			return post, nil
+		},
+		UpdatePostFunc: func(ctx context.Context, id int32, post *models.Post) (*models.Post, error) {
+			if _, ok := store[id]; !ok {
+				return nil, fmt.Errorf("update post %d: %w", id, database.ErrNotFound)
+			}
+			post.ID = int(id)
+			store[id] = post
+			return post, nil
+		},
+		DeletePostFunc: func(ctx context.Context, id int32) (int32, error) {
+			delete(store, id)
+			return id, nil
+		},
+	}
+	c := newClientServer(t, db)
+	ctx := context.Background()
+
+	created, err := c.CreatePost(ctx, &models.Post{Title: "Title", Content: "Content"})
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if created.ID != 1 {
+		t.Errorf("CreatePost returned id %d", created.ID)
+	}
+
+	updated, err := c.UpdatePost(ctx, 1, &models.Post{Title: "New title", Content: "Content"})
+	if err != nil {
+		t.Fatalf("UpdatePost: %v", err)
+	}
+	if updated.Title != "New title" {
+		t.Errorf("UpdatePost returned title %q", updated.Title)
+	}
+
+	got, err := c.GetPost(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if got.Title != "New title" {
+		t.Errorf("GetPost returned title %q", got.Title)
+	}
+
+	if err := c.DeletePost(ctx, 1); err != nil {
+		t.Fatalf("DeletePost: %v", err)
+	}
+	if _, err := c.GetPost(ctx, 1); !client.IsNotFound(err) {
+		t.Errorf("GetPost after delete: expected not found, got %v", err)
+	}
+	if _, err := c.UpdatePost(ctx, 1, &models.Post{Title: "Title", Content: "Content"}); !client.IsNotFound(err) {
+		t.Errorf("UpdatePost after delete: expected not found, got %v", err)
+	}
+}
+
+func TestClientValidationError(t *testing.T) {
+	c := newClientServer(t, &MockDatabaseRepo{})
+
+	_, err := c.CreatePost(context.Background(), &models.Post{Title: "   ", Content: "Content"})
+	if !client.IsValidation(err) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	apiErr := err.(*client.Error)
+	if apiErr.Errors[0].Field != "title" || apiErr.Errors[0].Code != "blank" {
+		t.Errorf("unexpected field errors: %+v", apiErr.Errors)
+	}
+}
+
+func TestClientPostIterator(t *testing.T) {
+	var all []*models.Post
+	for i := 1; i <= 7; i++ {
+		all = append(all, &models.Post{ID: i, Title: "Title", Content: "Content"})
+	}
+
+	var pages int
+	db := &MockDatabaseRepo{
+		GetPostsPageFunc: func(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+			pages++
+			end := min(offset+limit, len(all))
+			if offset >= end {
+				return nil, nil
+			}
+			return all[offset:end], nil
+		},
+	}
+	c := newClientServer(t, db)
+
+	it := c.Posts(3)
+	var ids []int
+	for it.Next(context.Background()) {
+		ids = append(ids, it.Post().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(ids) != "[1 2 3 4 5 6 7]" {
+		t.Errorf("iterated %v", ids)
+	}
+	// the short third page ends the iteration without another request
+	if pages != 3 {
+		t.Errorf("expected 3 page requests, got %d", pages)
+	}
+}
@@ -1,33 +1,228 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"math/rand"
+	"time"
 
-	_ "github.com/jackc/pgconn"
-	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/database"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/jackc/pgx/v4/stdlib"
 )
 
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("pgx", dsn)
+// newDB opens a pool sized by cfg without connecting yet
+func newDB(cfg config.Database) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.ConnString())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return db, nil
+}
+
+// openDB opens a pool sized by cfg and checks that Postgres is reachable
+func openDB(ctx context.Context, cfg config.Database) (*sql.DB, error) {
+	db, err := newDB(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.Ping()
+	err = db.PingContext(ctx)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
 
-func (app *Application) connectToDB() (*sql.DB, error) {
-	connection, err := openDB(app.DSN)
+// poolConfig translates cfg into a pgxpool configuration
+func poolConfig(cfg config.Database) (*pgxpool.Config, error) {
+	pc, err := pgxpool.ParseConfig(cfg.ConnString())
 	if err != nil {
 		return nil, err
 	}
+	pc.MaxConns = int32(cfg.MaxOpenConns)
+	pc.MinConns = int32(cfg.MinConns)
+	pc.MaxConnLifetime = cfg.ConnMaxLifetime
+	pc.MaxConnIdleTime = cfg.ConnMaxIdleTime
+	pc.HealthCheckPeriod = cfg.HealthCheckPeriod
 
-	log.Println("Connected to Postgres!")
+	if cfg.StatementCacheMode == "off" {
+		pc.ConnConfig.BuildStatementCache = nil
+	} else {
+		mode := stmtcache.ModePrepare
+		if cfg.StatementCacheMode == "describe" {
+			mode = stmtcache.ModeDescribe
+		}
+		capacity := cfg.StatementCacheCapacity
+		pc.ConnConfig.BuildStatementCache = func(conn *pgconn.PgConn) stmtcache.Cache {
+			return stmtcache.New(conn, mode, capacity)
+		}
+	}
+	return pc, nil
+}
+
+// openPool opens a pgxpool pool configured by cfg and checks that Postgres is reachable
+func openPool(ctx context.Context, cfg config.Database) (*pgxpool.Pool, error) {
+	pc, err := poolConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.ConnectConfig(ctx, pc)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+func (app *Application) connectToDB(ctx context.Context) (*sql.DB, error) {
+	connection, err := openDB(ctx, app.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	app.logger().Info("connected to Postgres")
 	return connection, nil
 }
+
+// openRepository connects the repository implementation selected by app.Database.Driver
+func (app *Application) openRepository(ctx context.Context) (database.DatabaseRepo, error) {
+	if app.Database.Driver != config.DriverPgxPool {
+		conn, err := app.connectToDB(ctx)
+		if err != nil {
+			return nil, err
+		}
+		repo := &database.PostgresDBRepo{DB: conn, Timeout: app.Database.QueryTimeout}
+		if len(app.Database.ReplicaDSNs) > 0 {
+			if repo.Replicas, err = app.openReplicas(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		return repo, nil
+	}
+
+	pool, err := openPool(ctx, app.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	// a small database/sql handle for the migrations check
+	conn := stdlib.OpenDB(*pool.Config().ConnConfig)
+	conn.SetMaxOpenConns(2)
+	conn.SetConnMaxIdleTime(app.Database.ConnMaxIdleTime)
+
+	app.logger().Info("connected to Postgres", "driver", config.DriverPgxPool, "max_conns", pool.Config().MaxConns)
+	return &database.PgxPoolRepo{Pool: pool, DB: conn, Timeout: app.Database.QueryTimeout}, nil
+}
+
+// openReplicas opens the configured read replicas. An unreachable replica
+// does not fail startup; it serves no reads until a later check reaches it.
+func (app *Application) openReplicas(ctx context.Context) (*database.ReplicaSet, error) {
+	set := &database.ReplicaSet{MaxLag: app.Database.ReplicaMaxLag, Logger: app.logger()}
+	for i := range app.Database.ReplicaDSNs {
+		cfg := app.Database.ReplicaConfig(i)
+		db, err := newDB(cfg)
+		if err != nil {
+			set.Close()
+			return nil, fmt.Errorf("replica %d: %w", i+1, err)
+		}
+		set.Replicas = append(set.Replicas, &database.Replica{Name: replicaName(cfg, i), DB: db})
+	}
+
+	set.Check(ctx, app.Database.ConnectTimeout)
+	return set, nil
+}
+
+// replicaName names a replica by its address, never by its DSN, which may hold a password
+func replicaName(cfg config.Database, i int) string {
+	if pc, err := pgconn.ParseConfig(cfg.ConnString()); err == nil {
+		return fmt.Sprintf("%s:%d", pc.Host, pc.Port)
+	}
+	return fmt.Sprintf("replica-%d", i+1)
+}
+
+// registerDB adds the readiness check, pool metrics and replica monitoring
+// that need a connected repository
+func (app *Application) registerDB(repo database.DatabaseRepo, dbName string) error {
+	app.Health.Register("migrations", 2*time.Second, database.CheckMigrations(repo.Connection()))
+
+	pg, _ := repo.(*database.PostgresDBRepo)
+	if pg != nil && pg.Replicas != nil {
+		app.goWorker("replica-monitor", func(ctx context.Context) {
+			pg.Replicas.Monitor(ctx, app.Database.ReplicaCheckInterval)
+		})
+	}
+
+	if app.Metrics == nil {
+		return nil
+	}
+	if pgx, ok := repo.(*database.PgxPoolRepo); ok {
+		return app.Metrics.RegisterPool(pgx.Pool, dbName)
+	}
+	if err := app.Metrics.RegisterDB(repo.Connection(), dbName); err != nil {
+		return err
+	}
+	if pg != nil && pg.Replicas != nil {
+		for _, replica := range pg.Replicas.Replicas {
+			if err := app.Metrics.RegisterDB(replica.DB, replica.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// connectWithRetry calls connect until it succeeds, waiting with exponential
+// backoff and jitter between attempts, and gives up when ctx is done. Each
+// attempt is bounded by the connect timeout and each failure is logged.
+func (app *Application) connectWithRetry(ctx context.Context, connect func(context.Context) (database.DatabaseRepo, error)) (database.DatabaseRepo, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, app.Database.ConnectTimeout)
+		repo, err := connect(attemptCtx)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				app.logger().Info("connected to Postgres after retrying", "attempt", attempt, "elapsed", time.Since(start))
+			}
+			return repo, nil
+		}
+
+		delay := backoff(attempt, app.Database.RetryInitialBackoff, app.Database.RetryMaxBackoff)
+		app.logger().Warn("connecting to Postgres failed", "attempt", attempt, "error", err, "retry_in", delay, "elapsed", time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns the delay after the given failed attempt: initial doubled
+// per earlier attempt, capped at max, with up to half of it taken off at
+// random so instances restarted together do not retry in lockstep
+func backoff(attempt int, initial, max time.Duration) time.Duration {
+	delay := initial
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, max)
+	return delay - time.Duration(rand.Int63n(int64(delay)/2+1))
+}
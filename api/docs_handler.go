@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/swaggest/swgui"
+	"github.com/swaggest/swgui/v5emb"
+)
+
+// docsPath is where the interactive API documentation is served
+const docsPath = "/docs"
+
+// docsHandler serves Swagger UI for the embedded OpenAPI document. The UI
+// assets are compiled into the binary, so the page works without a CDN,
+// and "Try it out" sends requests to the server that served the page.
+func (app *Application) docsHandler() http.Handler {
+	return v5emb.NewHandlerWithConfig(swgui.Config{
+		Title:       "News API",
+		SwaggerJSON: "/openapi.json",
+		BasePath:    docsPath,
+		SettingsUI: map[string]string{
+			"tryItOutEnabled": "true",
+		},
+	})
+}
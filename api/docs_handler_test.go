@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDocsEnabled(t *testing.T) {
+	app := &Application{DocsEnabled: true}
+	routes := app.routes()
+
+	// The index page points Swagger UI at the embedded document and loads no remote assets
+	req := httptest.NewRequest("GET", "/docs", nil)
+	rr := httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("wrong status code for /docs: got %v want %v", rr.Code, http.StatusOK)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "/openapi.json") {
+		t.Error("docs page does not reference /openapi.json")
+	}
+	if strings.Contains(body, `src="http`) || strings.Contains(body, `href="http`) {
+		t.Error("docs page loads assets from a remote host")
+	}
+
+	// The UI assets are served from the binary
+	req = httptest.NewRequest("GET", "/docs/swagger-ui-bundle.js", nil)
+	rr = httptest.NewRecorder()
+	routes.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("wrong status code for the UI bundle: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestDocsDisabled(t *testing.T) {
+	app := &Application{DocsEnabled: false}
+
+	req := httptest.NewRequest("GET", "/docs", nil)
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
@@ -1,26 +1,70 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/freshusername/news-api/health"
+	"github.com/freshusername/news-api/version"
 )
 
+// healthCheckTimeout bounds the database ping of HealthCheck
+const healthCheckTimeout = 2 * time.Second
+
+// healthStatus is the body of a healthy HealthCheck response
+type healthStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Version string `json:"version"`
+}
+
+// probeStatus is the body of a /livez or /readyz response
+type probeStatus struct {
+	// Status is ok or fail
+	Status  string                        `json:"status"`
+	Version version.Info                  `json:"version"`
+	Checks  map[string]health.CheckResult `json:"checks,omitempty"`
+}
+
 func (app *Application) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	var payload = struct {
-		Status  string `json:"status"`
-		Message string `json:"message"`
-		Version string `json:"version"`
-	}{
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	if err := app.DB.Healthcheck(ctx); err != nil {
+		app.problemJSON(w, r, fmt.Errorf("database is unavailable: %w", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	var payload = healthStatus{
 		Status:  "Active",
 		Message: "News-api is Healthy",
-		Version: "1.0.0",
+		Version: version.Version,
 	}
+	_ = app.writeJSON(w, http.StatusOK, payload)
+}
 
-	check, err := app.DB.Healthcheck()
+// HandleLivez reports that the process is up and serving. It checks no
+// dependencies, so an unavailable database never gets the pod restarted.
+func (app *Application) HandleLivez(w http.ResponseWriter, r *http.Request) {
+	_ = app.writeJSON(w, http.StatusOK, probeStatus{Status: health.StatusOK, Version: version.Get()})
+}
 
-	if check != nil {
-		_ = app.writeJSON(w, http.StatusOK, payload)
-	} else {
-		_ = app.writeJSON(w, http.StatusOK, fmt.Sprintf("Unhealthy. Error: %s", err))
+// HandleReadyz runs the registered checks and answers 503 when any of them
+// fails, so the instance is taken out of the load balancer.
+func (app *Application) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := health.Report{Status: health.StatusOK}
+	if app.Health != nil {
+		report = app.Health.Run(r.Context())
 	}
+
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+		app.logger().WarnContext(r.Context(), "not ready", "failed_checks", strings.Join(report.Failed(), ","))
+	}
+
+	_ = app.writeJSON(w, status, probeStatus{Status: report.Status, Version: version.Get(), Checks: report.Checks})
 }
@@ -1,20 +1,26 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
-	"github.com/freshusername/news-api/models"
+	"github.com/freshusername/news-api/health"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/outbox"
+	"github.com/freshusername/news-api/version"
 )
 
 func TestHealthCheckHandler(t *testing.T) {
 	// Create an instance of the Application with the mock DB
 	mockDB := &MockDatabaseRepo{
-		HealthcheckFunc: func() (*models.Post, error) {
+		HealthcheckFunc: func(ctx context.Context) error {
 			// Return a healthy response
-			return &models.Post{}, nil
+			return nil
 		},
 	}
 	app := &Application{DB: mockDB}
@@ -52,9 +58,123 @@ func TestHealthCheckHandler(t *testing.T) {
 	}{
 		Status:  "Active",
 		Message: "News-api is Healthy",
-		Version: "1.0.0",
+		Version: version.Version,
 	}
 	if gotPayload != wantPayload {
 		t.Errorf("expected payload %+v; got %+v", wantPayload, gotPayload)
 	}
 }
+
+func TestHealthCheckHandlerUnhealthy(t *testing.T) {
+	mockDB := &MockDatabaseRepo{
+		HealthcheckFunc: func(ctx context.Context) error {
+			return errors.New("connection refused")
+		},
+	}
+	app := &Application{DB: mockDB}
+
+	rr := httptest.NewRecorder()
+	app.HealthCheck(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503; got %v", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != problemContentType {
+		t.Errorf("expected a problem response; got %q", ct)
+	}
+}
+
+func TestLivez(t *testing.T) {
+	// liveness never depends on the database
+	app := &Application{DB: &MockDatabaseRepo{
+		HealthcheckFunc: func(ctx context.Context) error { return errors.New("down") },
+	}}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/livez", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status OK; got %v", rr.Code)
+	}
+
+	var got probeStatus
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != health.StatusOK || got.Version.Version != version.Version || got.Version.GoVersion == "" {
+		t.Errorf("unexpected payload %+v", got)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	dbErr := error(nil)
+	registry := health.NewRegistry()
+	registry.Register("database", time.Second, func(ctx context.Context) error { return dbErr })
+	registry.Register("migrations", time.Second, func(ctx context.Context) error { return nil })
+	app := &Application{DB: &MockDatabaseRepo{}, Health: registry}
+
+	readyz := func() (int, probeStatus) {
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var got probeStatus
+		if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return rr.Code, got
+	}
+
+	code, got := readyz()
+	if code != http.StatusOK || got.Status != health.StatusOK || len(got.Checks) != 2 {
+		t.Errorf("expected a ready response; got %d %+v", code, got)
+	}
+
+	dbErr = errors.New("connection refused")
+	code, got = readyz()
+	if code != http.StatusServiceUnavailable || got.Status != health.StatusFail {
+		t.Errorf("expected a 503 response; got %d %+v", code, got)
+	}
+	if check := got.Checks["database"]; check.Status != health.StatusFail || check.Error != "connection refused" {
+		t.Errorf("unexpected database check %+v", check)
+	}
+	if check := got.Checks["migrations"]; check.Status != health.StatusOK {
+		t.Errorf("unexpected migrations check %+v", check)
+	}
+}
+
+func TestReadyzWorkerHeartbeat(t *testing.T) {
+	heartbeat := &health.Heartbeat{}
+	registry := health.NewRegistry()
+	registry.Register("outbox-relay", time.Second, heartbeat.Check(100*time.Millisecond))
+	app := &Application{DB: &MockDatabaseRepo{}, Health: registry}
+	readyz := func() int {
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rr.Code
+	}
+
+	// without a database the relay keeps polling, and beating
+	relay := outbox.NewRelay(&MockDatabaseRepo{}, nil, outbox.RelayOptions{
+		PollInterval: 10 * time.Millisecond,
+		Heartbeat:    heartbeat,
+		Logger:       logging.Discard(),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		relay.Run(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if code := readyz(); code != http.StatusOK {
+		t.Errorf("expected ready while the relay runs, got %d", code)
+	}
+
+	cancel()
+	<-stopped
+	time.Sleep(150 * time.Millisecond)
+	if code := readyz(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 once the relay stopped beating, got %d", code)
+	}
+}
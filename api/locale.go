@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/freshusername/news-api/validation"
+	"golang.org/x/text/language"
+)
+
+var (
+	messageLocales = validation.DefaultCatalog.Locales()
+	localeMatcher  = newLocaleMatcher(messageLocales)
+)
+
+func newLocaleMatcher(locales []string) language.Matcher {
+	tags := make([]language.Tag, len(locales))
+	for i, locale := range locales {
+		tags[i] = language.Make(locale)
+	}
+	return language.NewMatcher(tags)
+}
+
+// requestLocale picks the message locale best matching the request's
+// Accept-Language header, defaulting to the catalog's fallback locale
+func requestLocale(r *http.Request) string {
+	accepted, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(accepted) == 0 {
+		return messageLocales[0]
+	}
+
+	_, index, confidence := localeMatcher.Match(accepted...)
+	if confidence == language.No {
+		return messageLocales[0]
+	}
+	return messageLocales[index]
+}
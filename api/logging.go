@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/freshusername/news-api/logging"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds incoming request IDs, which end up in every log record of the request
+	maxRequestIDLength = 128
+	// unmatchedRoute stands in for the route pattern of requests no route matched
+	unmatchedRoute = "unmatched"
+)
+
+// logger returns the application logger, or slog.Default() when none is configured
+func (app *Application) logger() *slog.Logger {
+	if app.Logger != nil {
+		return app.Logger
+	}
+	return slog.Default()
+}
+
+// requestID tags the request with the incoming X-Request-ID, or a new random
+// one, echoes it in the response and puts a logger carrying it, and the trace
+// ID of the request's span, into the context.
+func (app *Application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		logger := app.logger().With("request_id", id)
+		if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+			logger = logger.With("trace_id", sc.TraceID().String())
+		}
+
+		ctx := logging.WithRequestID(r.Context(), id)
+		ctx = logging.WithLogger(ctx, logger)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestID accepts short IDs of printable ASCII without spaces, so they are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// accessLog writes one record per request once it has been served
+func (app *Application) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ctx := logging.WithPrincipalSlot(r.Context())
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", routePattern(r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		if principal := logging.Principal(ctx); principal != "" {
+			attrs = append(attrs, slog.String("principal", principal))
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(ctx).LogAttrs(ctx, level, "request served", attrs...)
+	})
+}
+
+// routePattern returns the chi pattern the request matched, e.g. /posts/{id}, so records of one route can be grouped
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return unmatchedRoute
+}
+
+// recoverPanic turns a panicking handler into a logged 500 problem response
+func (app *Application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				// the client went away, let net/http abort the connection
+				panic(rvr)
+			}
+
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "handler panicked",
+				"panic", fmt.Sprint(rvr), "stack", string(debug.Stack()))
+			app.problemJSON(w, r, errors.New("internal server error"), http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+)
+
+// newLoggedApp returns an app whose log records are decoded from the returned buffer
+func newLoggedApp(t *testing.T, db *MockDatabaseRepo) (*Application, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatJSON, slog.LevelDebug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Application{DB: db, Logger: logger}, &buf
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRequestID(t *testing.T) {
+	var dbRequestID string
+	app, buf := newLoggedApp(t, &MockDatabaseRepo{
+		GetPostFunc: func(ctx context.Context, id int32) (*models.Post, error) {
+			dbRequestID = logging.RequestID(ctx)
+			logging.FromContext(ctx).Info("from the database layer")
+			return &models.Post{ID: int(id), Title: "Title", Content: "Content"}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/7", nil)
+	req.Header.Set(requestIDHeader, "abc-123")
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+
+	if got := rr.Header().Get(requestIDHeader); got != "abc-123" {
+		t.Errorf("expected the incoming request ID to be echoed, got %q", got)
+	}
+	if dbRequestID != "abc-123" {
+		t.Errorf("database layer saw request ID %q", dbRequestID)
+	}
+
+	records := logRecords(t, buf)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 log records, got %d: %s", len(records), buf)
+	}
+	for _, record := range records {
+		if record["request_id"] != "abc-123" {
+			t.Errorf("record without the request ID: %v", record)
+		}
+	}
+
+	access := records[1]
+	if access["msg"] != "request served" || access["method"] != "GET" || access["route"] != "/posts/{id}" ||
+		access["path"] != "/posts/7" || access["status"] != float64(200) || access["bytes"].(float64) == 0 {
+		t.Errorf("unexpected access log record: %v", access)
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	app, _ := newLoggedApp(t, &MockDatabaseRepo{})
+
+	for _, incoming := range []string{"", "has spaces", strings.Repeat("x", maxRequestIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+		req.Header.Set(requestIDHeader, incoming)
+		rr := httptest.NewRecorder()
+		app.routes().ServeHTTP(rr, req)
+
+		got := rr.Header().Get(requestIDHeader)
+		if len(got) != 32 || got == incoming {
+			t.Errorf("incoming %q: expected a generated request ID, got %q", incoming, got)
+		}
+	}
+}
+
+func TestAccessLogPrincipal(t *testing.T) {
+	app, buf := newLoggedApp(t, nil)
+	handler := app.requestID(app.accessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.SetPrincipal(r.Context(), "editor@example.com")
+		w.WriteHeader(http.StatusNoContent)
+	})))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	records := logRecords(t, buf)
+	if records[0]["principal"] != "editor@example.com" || records[0]["route"] != "unmatched" || records[0]["status"] != float64(204) {
+		t.Errorf("unexpected access log record: %v", records[0])
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	app, buf := newLoggedApp(t, &MockDatabaseRepo{
+		GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+			panic("boom")
+		},
+	})
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/posts", nil))
+
+	if rr.Code != http.StatusInternalServerError || rr.Header().Get("Content-Type") != problemContentType {
+		t.Errorf("expected a 500 problem, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+
+	records := logRecords(t, buf)
+	if len(records) != 2 || records[0]["panic"] != "boom" || records[0]["level"] != "ERROR" || records[1]["status"] != float64(500) {
+		t.Errorf("unexpected log records: %s", buf)
+	}
+}
@@ -1,51 +1,311 @@
 package main
 
+//go:generate go run . -write-openapi ../openapi
+
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/freshusername/news-api/cache"
+	"github.com/freshusername/news-api/config"
 	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/events"
+	"github.com/freshusername/news-api/health"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/metrics"
+	"github.com/freshusername/news-api/outbox"
+	"github.com/freshusername/news-api/tracing"
+	"github.com/freshusername/news-api/version"
+	"github.com/freshusername/news-api/webhooks"
 )
 
-const port = 3000
-
 type Application struct {
-	DSN string
-	DB  database.DatabaseRepo
+	// Database configures the connection and pool opened by connectToDB
+	Database    config.Database
+	DB          database.DatabaseRepo
+	DocsEnabled bool
+	Logger      *slog.Logger
+	// Health holds the readiness checks run by /readyz
+	Health *health.Registry
+	// Metrics is nil when /metrics is disabled
+	Metrics *metrics.Metrics
+	// ResponseValidation is one of the response validation modes: off, log or fail
+	ResponseValidation string
+	// Auth holds the bearer tokens; without any the API is open
+	Auth config.Auth
+	// CORS is applied to every route when it allows any origin
+	CORS config.CORS
+	// Cache sets the Cache-Control of reads, matching the cache wrapped around DB
+	Cache config.Cache
+	// Events streams post changes; nil without the change feed
+	Events    *events.Broker
+	Stream    config.Stream
+	WebSocket config.WebSocket
+	// Webhooks stores the webhooks and queues their deliveries
+	Webhooks webhooks.Store
+	// Relay publishes the post events of the outbox
+	Relay *outbox.Relay
+	// Tail follows the post events of the outbox to stream them
+	Tail *outbox.Tail
+
+	workers *workers
 }
 
 func main() {
+	// exit non-zero on a server failure, after the deferred cleanup ran
+	exitCode := 0
+	defer func() {
+		os.Exit(exitCode)
+	}()
+
+	// read the configuration: defaults, then the config file, the environment and the command line
+	openAPIDir := flag.String("write-openapi", "", "write the generated OpenAPI document into this directory and exit")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration, secrets redacted, and exit")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.LookupEnv)
+	if err != nil {
+		// flag.ExitOnError already reported bad flags
+		fatal(slog.Default(), "loading the configuration failed", err)
+	}
+
+	if *printConfig {
+		out, err := cfg.Redacted().YAML()
+		if err != nil {
+			fatal(slog.Default(), "printing the configuration failed", err)
+		}
+		os.Stdout.Write(out)
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+		}
+		return
+	}
+
+	// generating the document needs no database, so skip validating the rest
+	if *openAPIDir == "" {
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 1
+			return
+		}
+	}
+
 	// set Application config
-	var app Application
-
-	// read from command line
-	dbName := os.Getenv("DB_NAME")
-	flag.StringVar(&app.DSN, "dsn", fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable timezone=UTC connect_timeout=5",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		dbName,
-		os.Getenv("DB_PORT"),
-	), "Postgres connection string")
-	flag.Parse()
-
-	// connect to the database
-	conn, err := app.connectToDB()
+	app := Application{
+		Database:           cfg.Database,
+		DocsEnabled:        cfg.Features.Docs,
+		ResponseValidation: cfg.Features.ResponseValidation,
+		Auth:               cfg.Auth,
+		CORS:               cfg.CORS,
+		Cache:              cfg.Cache,
+		Stream:             cfg.Stream,
+		WebSocket:          cfg.WebSocket,
+	}
+
+	level, err := logging.ParseLevel(cfg.Log.Level)
 	if err != nil {
-		log.Fatal(err)
+		fatal(slog.Default(), "invalid log level", err)
 	}
-	app.DB = &database.PostgresDBRepo{DB: conn}
-	defer app.DB.Connection().Close()
+	app.Logger, err = logging.New(os.Stderr, cfg.Log.Format, level)
+	if err != nil {
+		fatal(slog.Default(), "invalid log format", err)
+	}
+	slog.SetDefault(app.Logger)
 
-	log.Println("Starting Application on port", port)
+	if *openAPIDir != "" {
+		if err := app.writeOpenAPI(*openAPIDir); err != nil {
+			fatal(app.Logger, "writing the OpenAPI document failed", err)
+		}
+		return
+	}
 
-	// start a web server
-	err = http.ListenAndServe(fmt.Sprintf(":%d", port), app.routes())
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:       cfg.Tracing.Exporter,
+		ServiceName:    "news-api",
+		ServiceVersion: version.Version,
+		Console:        os.Stdout,
+	})
 	if err != nil {
-		log.Fatal(err)
+		fatal(app.Logger, "setting up tracing failed", err)
 	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			app.Logger.Error("flushing traces failed", "error", err)
+		}
+	}()
+
+	// stop the workers before closing the sinks and the pool they use. serve
+	// stops them on shutdown; this covers failing after they started.
+	closeSinks := func() {}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := app.stopWorkers(ctx); err != nil {
+			app.Logger.Error("stopping the workers failed", "error", err)
+		}
+		closeSinks()
+		if app.DB == nil {
+			return
+		}
+		if err := app.DB.Close(); err != nil {
+			app.Logger.Error("closing the database pool failed", "error", err)
+		}
+	}()
+
+	// shut down on SIGINT or SIGTERM, which also aborts connecting; a second signal kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	app.Health = health.NewRegistry()
+	if cfg.Features.Metrics {
+		app.Metrics = metrics.New()
+	}
+
+	// connect to the database, retrying until it accepts connections
+	if cfg.Database.ConnectInBackground {
+		pending := &database.ConnectingRepo{}
+		app.DB = pending
+		app.goWorker("database-connect", func(ctx context.Context) {
+			repo, err := app.connectWithRetry(ctx, app.openRepository)
+			if err != nil {
+				app.Logger.Error("connecting to Postgres failed", "error", err)
+				return
+			}
+			if err := app.registerDB(repo, cfg.Database.Name); err != nil {
+				app.Logger.Error("registering database metrics failed", "error", err)
+			}
+			pending.Connected(repo)
+		})
+		app.Logger.Info("connecting to Postgres in the background, not ready until connected")
+	} else {
+		startupCtx, cancel := context.WithTimeout(ctx, cfg.Database.StartupTimeout)
+		repo, err := app.connectWithRetry(startupCtx, app.openRepository)
+		cancel()
+		if err != nil {
+			app.Logger.Error("connecting to Postgres failed", "error", err)
+			exitCode = 1
+			return
+		}
+		app.DB = repo
+		if err := app.registerDB(repo, cfg.Database.Name); err != nil {
+			app.Logger.Error("registering database metrics failed", "error", err)
+			exitCode = 1
+			return
+		}
+	}
+	app.Health.Register("database", 2*time.Second, app.DB.Healthcheck)
+
+	if app.Metrics != nil {
+		app.DB = app.Metrics.InstrumentRepo(app.DB)
+	}
+	// outermost, so only reads missing the cache are measured as queries
+	if cfg.Cache.Enabled {
+		app.DB = cache.NewRepo(app.DB, cache.Options{
+			MaxEntries:           cfg.Cache.MaxEntries,
+			TTL:                  cfg.Cache.TTL,
+			StaleWhileRevalidate: cfg.Cache.StaleWhileRevalidate,
+			Timeout:              cfg.Database.QueryTimeout,
+		})
+	}
+	// keep the cache consistent with writes made through other instances, and stream them
+	if cfg.Features.ChangeFeed {
+		app.Events = events.NewBroker(cfg.Stream.ReplayBuffer)
+		listener := &database.Listener{
+			ConnString: cfg.Database.ConnString(),
+			MinBackoff: cfg.Database.RetryInitialBackoff,
+			MaxBackoff: cfg.Database.RetryMaxBackoff,
+			Logger:     app.Logger,
+		}
+		app.goWorker("post-changes", func(ctx context.Context) {
+			listener.Run(ctx, app.handleChange)
+		})
+
+		// the stream follows the outbox, so a lost notification only delays an event
+		heartbeat := &health.Heartbeat{}
+		app.Health.Register("outbox-tail", 0, heartbeat.Check(heartbeatAge(cfg.Outbox.PollInterval, cfg.Database.QueryTimeout)))
+		app.Tail = outbox.NewTail(app.DB, outbox.TailOptions{
+			BatchSize:    cfg.Outbox.BatchSize,
+			PollInterval: cfg.Outbox.PollInterval,
+			Heartbeat:    heartbeat,
+			Logger:       app.Logger,
+		})
+		app.goWorker("post-stream", func(ctx context.Context) {
+			app.Tail.Run(ctx, app.streamEvent)
+		})
+	}
+	// the queue lives in the posts database; any instance may send what another queued
+	app.Webhooks = &webhooks.PostgresStore{DB: app.DB, Timeout: cfg.Database.QueryTimeout}
+	if cfg.Features.Webhooks {
+		heartbeat := &health.Heartbeat{}
+		app.Health.Register("webhooks", 0, heartbeat.Check(heartbeatAge(cfg.Webhooks.PollInterval, 2*cfg.Webhooks.Timeout)))
+		dispatcher := webhooks.NewDispatcher(app.Webhooks, webhooks.DispatcherOptions{
+			PollInterval:        cfg.Webhooks.PollInterval,
+			BatchSize:           cfg.Webhooks.BatchSize,
+			Timeout:             cfg.Webhooks.Timeout,
+			MaxAttempts:         cfg.Webhooks.MaxAttempts,
+			InitialBackoff:      cfg.Webhooks.RetryInitialBackoff,
+			MaxBackoff:          cfg.Webhooks.RetryMaxBackoff,
+			AllowPrivateTargets: cfg.Webhooks.AllowPrivateTargets,
+			Heartbeat:           heartbeat,
+			Logger:              app.Logger,
+		})
+		app.goWorker("webhooks", dispatcher.Run)
+	}
+
+	// publish the post events committed with each write
+	sinks, closeOutbox, err := app.outboxSinks(cfg.Outbox)
+	if err != nil {
+		app.Logger.Error("opening the outbox sinks failed", "error", err)
+		exitCode = 1
+		return
+	}
+	closeSinks = closeOutbox
+	heartbeat := &health.Heartbeat{}
+	app.Health.Register("outbox-relay", 0, heartbeat.Check(heartbeatAge(cfg.Outbox.PollInterval, cfg.Database.QueryTimeout)))
+	app.Relay = outbox.NewRelay(app.DB, sinks, outbox.RelayOptions{
+		BatchSize:    cfg.Outbox.BatchSize,
+		PollInterval: cfg.Outbox.PollInterval,
+		Retention:    cfg.Outbox.Retention,
+		Heartbeat:    heartbeat,
+		Logger:       app.Logger,
+	})
+	app.goWorker("outbox-relay", app.Relay.Run)
+
+	app.Logger.Info("starting application", "addr", cfg.Server.Addr, "version", version.Version)
+
+	ln, err := net.Listen("tcp", cfg.Server.Addr)
+	if err != nil {
+		app.Logger.Error("listening failed", "error", err)
+		exitCode = 1
+		return
+	}
+
+	if err := app.serve(ctx, ln, cfg.Server); err != nil {
+		app.Logger.Error("server failed", "error", err)
+		exitCode = 1
+	}
+}
+
+// heartbeatAge is how long a worker polling every interval, with cycles
+// normally done within work, may go without a heartbeat and stay ready
+func heartbeatAge(interval, work time.Duration) time.Duration {
+	return max(3*(interval+work), time.Minute)
+}
+
+// fatal logs msg with err and exits, for failures before anything needs
+// cleaning up
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// metricsPath is where Prometheus scrapes the metrics
+const metricsPath = "/metrics"
+
+// measureRequests counts and times every request by method, route pattern and status
+func (app *Application) measureRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		app.Metrics.ObserveRequest(r.Method, routePattern(r), status, time.Since(start))
+	})
+}
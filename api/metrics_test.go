@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/metrics"
+	"github.com/freshusername/news-api/models"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	m := metrics.New()
+	db := &MockDatabaseRepo{
+		GetPostFunc: func(ctx context.Context, id int32) (*models.Post, error) {
+			return &models.Post{ID: int(id), Title: "Title", Content: "Content"}, nil
+		},
+	}
+	app := &Application{DB: m.InstrumentRepo(db), Metrics: m, Logger: logging.Discard()}
+	handler := app.routes()
+
+	for _, path := range []string{"/posts/1", "/posts/2", "/no-such-page"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		`news_http_requests_total{method="GET",route="/posts/{id}",status="200"} 2`,
+		`news_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`news_db_query_duration_seconds_count{method="GetPost"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output lacks %q", want)
+		}
+	}
+}
+
+func TestMetricsDisabled(t *testing.T) {
+	app := &Application{DB: &MockDatabaseRepo{}, Logger: logging.Discard()}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without metrics, got %d", rr.Code)
+	}
+}
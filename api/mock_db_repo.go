@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/freshusername/news-api/models"
 )
 
 type MockDatabaseRepo struct {
-	HealthcheckFunc func() (*models.Post, error)
-	CreatePostFunc  func(post *models.Post) (*models.Post, error)
+	HealthcheckFunc  func(ctx context.Context) error
+	GetAllPostsFunc  func(ctx context.Context) ([]*models.Post, error)
+	GetPostsPageFunc func(ctx context.Context, limit, offset int) ([]*models.Post, error)
+	GetPostFunc      func(ctx context.Context, id int32) (*models.Post, error)
+	CreatePostFunc   func(ctx context.Context, post *models.Post) (*models.Post, error)
+	UpdatePostFunc   func(ctx context.Context, id int32, post *models.Post) (*models.Post, error)
+	DeletePostFunc   func(ctx context.Context, id int32) (int32, error)
 }
 
 func (m *MockDatabaseRepo) Connection() *sql.DB {
@@ -16,27 +22,56 @@ func (m *MockDatabaseRepo) Connection() *sql.DB {
 	return nil
 }
 
-func (m *MockDatabaseRepo) Healthcheck() (*models.Post, error) {
-	return m.HealthcheckFunc()
+func (m *MockDatabaseRepo) Close() error {
+	return nil
+}
+
+// Each method calls the matching Func field when a test sets one
+func (m *MockDatabaseRepo) Healthcheck(ctx context.Context) error {
+	if m.HealthcheckFunc != nil {
+		return m.HealthcheckFunc(ctx)
+	}
+	return nil
+}
+
+func (m *MockDatabaseRepo) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	if m.GetAllPostsFunc != nil {
+		return m.GetAllPostsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockDatabaseRepo) GetPostsPage(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	if m.GetPostsPageFunc != nil {
+		return m.GetPostsPageFunc(ctx, limit, offset)
+	}
+	return nil, nil
 }
 
-// You must add mock implementations for all other methods defined in the DatabaseRepo interface
-func (m *MockDatabaseRepo) GetAllPosts() ([]*models.Post, error) {
-	// Return a slice of posts or an error based on your test needs
+func (m *MockDatabaseRepo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	if m.GetPostFunc != nil {
+		return m.GetPostFunc(ctx, id)
+	}
 	return nil, nil
 }
 
-func (m *MockDatabaseRepo) CreatePost(post *models.Post) (*models.Post, error) {
-	// Return a new post or an error based on your test needs
+func (m *MockDatabaseRepo) CreatePost(ctx context.Context, post *models.Post) (*models.Post, error) {
+	if m.CreatePostFunc != nil {
+		return m.CreatePostFunc(ctx, post)
+	}
 	return nil, nil
 }
 
-func (m *MockDatabaseRepo) UpdatePost(id int32, item *models.Post) (*models.Post, error) {
-	// Return an updated post or an error based on your test needs
+func (m *MockDatabaseRepo) UpdatePost(ctx context.Context, id int32, item *models.Post) (*models.Post, error) {
+	if m.UpdatePostFunc != nil {
+		return m.UpdatePostFunc(ctx, id, item)
+	}
 	return nil, nil
 }
 
-func (m *MockDatabaseRepo) DeletePost(id int32) (int32, error) {
-	// Return the ID of the deleted post or an error based on your test needs
+func (m *MockDatabaseRepo) DeletePost(ctx context.Context, id int32) (int32, error) {
+	if m.DeletePostFunc != nil {
+		return m.DeletePostFunc(ctx, id)
+	}
 	return 0, nil
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/freshusername/news-api/webhooks"
+)
+
+type MockWebhookStore struct {
+	CreateWebhookFunc  func(ctx context.Context, hook *webhooks.Webhook) (*webhooks.Webhook, error)
+	ListWebhooksFunc   func(ctx context.Context) ([]*webhooks.Webhook, error)
+	GetWebhookFunc     func(ctx context.Context, id int32) (*webhooks.Webhook, error)
+	UpdateWebhookFunc  func(ctx context.Context, id int32, hook *webhooks.Webhook) (*webhooks.Webhook, error)
+	DeleteWebhookFunc  func(ctx context.Context, id int32) error
+	EnqueueFunc        func(ctx context.Context, payload webhooks.Payload) (int, error)
+	ListDeliveriesFunc func(ctx context.Context, webhookID int32, status string, limit int) ([]*webhooks.Delivery, error)
+	GetDeliveryFunc    func(ctx context.Context, webhookID int32, id int64) (*webhooks.Delivery, error)
+	RedeliverFunc      func(ctx context.Context, webhookID int32, id int64) (*webhooks.Delivery, error)
+}
+
+// Each method calls the matching Func field when a test sets one
+func (m *MockWebhookStore) CreateWebhook(ctx context.Context, hook *webhooks.Webhook) (*webhooks.Webhook, error) {
+	if m.CreateWebhookFunc != nil {
+		return m.CreateWebhookFunc(ctx, hook)
+	}
+	return hook, nil
+}
+
+func (m *MockWebhookStore) ListWebhooks(ctx context.Context) ([]*webhooks.Webhook, error) {
+	if m.ListWebhooksFunc != nil {
+		return m.ListWebhooksFunc(ctx)
+	}
+	return []*webhooks.Webhook{}, nil
+}
+
+func (m *MockWebhookStore) GetWebhook(ctx context.Context, id int32) (*webhooks.Webhook, error) {
+	if m.GetWebhookFunc != nil {
+		return m.GetWebhookFunc(ctx, id)
+	}
+	return nil, webhooks.ErrNotFound
+}
+
+func (m *MockWebhookStore) UpdateWebhook(ctx context.Context, id int32, hook *webhooks.Webhook) (*webhooks.Webhook, error) {
+	if m.UpdateWebhookFunc != nil {
+		return m.UpdateWebhookFunc(ctx, id, hook)
+	}
+	return nil, webhooks.ErrNotFound
+}
+
+func (m *MockWebhookStore) DeleteWebhook(ctx context.Context, id int32) error {
+	if m.DeleteWebhookFunc != nil {
+		return m.DeleteWebhookFunc(ctx, id)
+	}
+	return webhooks.ErrNotFound
+}
+
+func (m *MockWebhookStore) Enqueue(ctx context.Context, payload webhooks.Payload) (int, error) {
+	if m.EnqueueFunc != nil {
+		return m.EnqueueFunc(ctx, payload)
+	}
+	return 0, nil
+}
+
+func (m *MockWebhookStore) ListDeliveries(ctx context.Context, webhookID int32, status string, limit int) ([]*webhooks.Delivery, error) {
+	if m.ListDeliveriesFunc != nil {
+		return m.ListDeliveriesFunc(ctx, webhookID, status, limit)
+	}
+	return []*webhooks.Delivery{}, nil
+}
+
+func (m *MockWebhookStore) GetDelivery(ctx context.Context, webhookID int32, id int64) (*webhooks.Delivery, error) {
+	if m.GetDeliveryFunc != nil {
+		return m.GetDeliveryFunc(ctx, webhookID, id)
+	}
+	return nil, webhooks.ErrNotFound
+}
+
+func (m *MockWebhookStore) Redeliver(ctx context.Context, webhookID int32, id int64) (*webhooks.Delivery, error) {
+	if m.RedeliverFunc != nil {
+		return m.RedeliverFunc(ctx, webhookID, id)
+	}
+	return nil, webhooks.ErrNotFound
+}
+
+// Claim and Record serve the dispatcher, which handler tests do not run
+func (m *MockWebhookStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]*webhooks.Claim, error) {
+	return nil, nil
+}
+
+func (m *MockWebhookStore) Record(ctx context.Context, claim *webhooks.Claim, outcome webhooks.Outcome) error {
+	return nil
+}
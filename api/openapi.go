@@ -0,0 +1,577 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/freshusername/news-api/models"
+	"github.com/freshusername/news-api/openapi"
+	"github.com/freshusername/news-api/version"
+	"github.com/freshusername/news-api/webhooks"
+	"github.com/go-chi/chi/v5"
+)
+
+// apiVersion is the version of the API contract published in the OpenAPI document
+const apiVersion = "1.0.0"
+
+// routeOperation documents a single route registered in routes()
+type routeOperation struct {
+	operation *openapi.Operation
+	// request and responses name the Go types of bodies, resolved to schemas when the document is built
+	request   reflect.Type
+	responses map[int]responseSpec
+}
+
+type responseSpec struct {
+	description string
+	body        reflect.Type
+}
+
+var (
+	postType       = reflect.TypeOf(models.Post{})
+	postsType      = reflect.TypeOf([]*models.Post{})
+	problemType    = reflect.TypeOf(ProblemDetails{})
+	healthType     = reflect.TypeOf(healthStatus{})
+	probeType      = reflect.TypeOf(probeStatus{})
+	deletedIDType  = reflect.TypeOf(map[string]int32{})
+	webhookType    = reflect.TypeOf(webhooks.Webhook{})
+	webhooksType   = reflect.TypeOf([]*webhooks.Webhook{})
+	deliveryType   = reflect.TypeOf(webhooks.Delivery{})
+	deliveriesType = reflect.TypeOf([]*webhooks.Delivery{})
+	// eventStreamType documents a text/event-stream body
+	eventStreamType = reflect.TypeOf("")
+)
+
+const eventStreamContentType = "text/event-stream"
+
+// ifModifiedSinceParam lets clients revalidate a read they cached
+var ifModifiedSinceParam = &openapi.Parameter{
+	Name:        "If-Modified-Since",
+	In:          "header",
+	Description: "Answer 304 Not Modified when nothing changed since this HTTP date, the Last-Modified of a previous response",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+// bearerAuth names the security scheme of the configured bearer tokens
+const bearerAuth = "bearerAuth"
+
+var (
+	// writeSecurity requires a bearer token
+	writeSecurity = []openapi.SecurityRequirement{{bearerAuth: {}}}
+	// readSecurity accepts a bearer token, which is required only when reads are protected
+	readSecurity = []openapi.SecurityRequirement{{}, {bearerAuth: {}}}
+)
+
+// postIDParam is the {id} path parameter shared by single-post routes
+var postIDParam = &openapi.Parameter{
+	Name:        "id",
+	In:          "path",
+	Description: "ID of the post",
+	Required:    true,
+	Schema:      &openapi.Schema{Type: "integer", Format: "int32"},
+}
+
+// webhookIDParam is the {id} path parameter of webhook routes
+var webhookIDParam = &openapi.Parameter{
+	Name:        "id",
+	In:          "path",
+	Description: "ID of the webhook",
+	Required:    true,
+	Schema:      &openapi.Schema{Type: "integer", Format: "int32"},
+}
+
+// deliveryIDParam is the {deliveryID} path parameter of delivery routes
+var deliveryIDParam = &openapi.Parameter{
+	Name:        "deliveryID",
+	In:          "path",
+	Description: "ID of the delivery",
+	Required:    true,
+	Schema:      &openapi.Schema{Type: "integer", Format: "int64"},
+}
+
+// operations documents every route in routes(), keyed by "METHOD pattern".
+// TestOpenAPIMatchesRoutes fails when a route is missing here or the
+// embedded document is stale.
+var operations = map[string]routeOperation{
+	"GET /": {
+		operation: &openapi.Operation{
+			OperationID: "healthCheck",
+			Summary:     "Health check",
+			Description: "Report whether the API and its database are healthy.",
+			Tags:        []string{"health"},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                 {"The API is healthy", healthType},
+			http.StatusServiceUnavailable: {"The database is unavailable", problemType},
+		},
+	},
+	"GET /livez": {
+		operation: &openapi.Operation{
+			OperationID: "livez",
+			Summary:     "Liveness probe",
+			Description: "Report that the process is up, without checking dependencies, along with the running build.",
+			Tags:        []string{"health"},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK: {"The process is alive", probeType},
+		},
+	},
+	"GET /readyz": {
+		operation: &openapi.Operation{
+			OperationID: "readyz",
+			Summary:     "Readiness probe",
+			Description: "Run the dependency checks (database ping, schema migration version, background worker heartbeats) and report each result.",
+			Tags:        []string{"health"},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                 {"Every check passed", probeType},
+			http.StatusServiceUnavailable: {"At least one check failed", probeType},
+		},
+	},
+	"GET /posts": {
+		operation: &openapi.Operation{
+			OperationID: "listPosts",
+			Summary:     "List all posts",
+			Description: "Retrieve a list of all posts, newest first. Pass limit or offset to retrieve a single page.",
+			Tags:        []string{"posts"},
+			Security:    readSecurity,
+			Parameters: []*openapi.Parameter{
+				{
+					Name:        "limit",
+					In:          "query",
+					Description: fmt.Sprintf("Maximum number of posts to return, %d when only offset is given", defaultPageSize),
+					Schema:      &openapi.Schema{Type: "integer", Format: "int32", Minimum: floatp(1), Maximum: floatp(maxPageSize)},
+				},
+				{
+					Name:        "offset",
+					In:          "query",
+					Description: "Number of posts to skip",
+					Schema:      &openapi.Schema{Type: "integer", Format: "int32", Minimum: floatp(0)},
+				},
+				ifModifiedSinceParam,
+			},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                  {"A list of posts", postsType},
+			http.StatusNotModified:         {"The list did not change since If-Modified-Since", nil},
+			http.StatusBadRequest:          {"Invalid limit or offset", problemType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+	"GET /posts/stream": {
+		operation: &openapi.Operation{
+			OperationID: "streamPosts",
+			Summary:     "Stream post changes",
+			Description: "Stream created, updated and deleted posts as server-sent events, whichever instance made the change. " +
+				"Each event carries the post as JSON, as it was before a deletion. " +
+				"Send Last-Event-ID to resume after a disconnect; a resync event tells the client to reload the posts when the missed events are no longer available. " +
+				"Clients falling behind are disconnected and resume the same way.",
+			Tags:     []string{"posts"},
+			Security: readSecurity,
+			Parameters: []*openapi.Parameter{
+				{
+					Name:        "category",
+					In:          "query",
+					Description: "Only stream posts of these categories, comma separated",
+					Schema:      &openapi.Schema{Type: "string"},
+				},
+				{
+					Name:        "Last-Event-ID",
+					In:          "header",
+					Description: "ID of the last event received, to resume from",
+					Schema:      &openapi.Schema{Type: "string"},
+				},
+			},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                  {"An event stream of created, updated, deleted and resync events", eventStreamType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"Live updates are disabled", problemType},
+		},
+	},
+	"GET /posts/{id}": {
+		operation: &openapi.Operation{
+			OperationID: "getPost",
+			Summary:     "Get a post",
+			Description: "Retrieve a single post by ID.",
+			Tags:        []string{"posts"},
+			Security:    readSecurity,
+			Parameters:  []*openapi.Parameter{postIDParam, ifModifiedSinceParam},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                  {"The post", postType},
+			http.StatusNotModified:         {"The post did not change since If-Modified-Since", nil},
+			http.StatusBadRequest:          {"Invalid ID", problemType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:            {"Post not found", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+	"POST /posts": {
+		operation: &openapi.Operation{
+			OperationID: "createPost",
+			Summary:     "Create a post",
+			Description: "Create a new post based on the data provided in the request body.",
+			Tags:        []string{"posts"},
+			Security:    writeSecurity,
+		},
+		request: postType,
+		responses: map[int]responseSpec{
+			http.StatusCreated:              {"Post created successfully", postType},
+			http.StatusBadRequest:           {"Malformed body or validation error", problemType},
+			http.StatusUnauthorized:         {"Missing or invalid bearer token", problemType},
+			http.StatusUnsupportedMediaType: {"Request body is not JSON", problemType},
+			http.StatusInternalServerError:  {"Internal server error", problemType},
+			http.StatusServiceUnavailable:   {"The database is not connected yet", problemType},
+		},
+	},
+	"PUT /posts/{id}": {
+		operation: &openapi.Operation{
+			OperationID: "updatePost",
+			Summary:     "Update a post",
+			Description: "Update the details of an existing post by ID.",
+			Tags:        []string{"posts"},
+			Security:    writeSecurity,
+			Parameters:  []*openapi.Parameter{postIDParam},
+		},
+		request: postType,
+		responses: map[int]responseSpec{
+			http.StatusOK:                   {"Post updated successfully", postType},
+			http.StatusBadRequest:           {"Invalid ID, malformed body or validation error", problemType},
+			http.StatusUnauthorized:         {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:             {"Post not found", problemType},
+			http.StatusUnsupportedMediaType: {"Request body is not JSON", problemType},
+			http.StatusInternalServerError:  {"Internal server error", problemType},
+			http.StatusServiceUnavailable:   {"The database is not connected yet", problemType},
+		},
+	},
+	"DELETE /posts/{id}": {
+		operation: &openapi.Operation{
+			OperationID: "deletePost",
+			Summary:     "Delete a post",
+			Description: "Delete an existing post by ID.",
+			Tags:        []string{"posts"},
+			Security:    writeSecurity,
+			Parameters:  []*openapi.Parameter{postIDParam},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                  {"Post deleted successfully, returning its ID", deletedIDType},
+			http.StatusBadRequest:          {"Invalid ID", problemType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:            {"Post not found", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+	"GET /webhooks": {
+		operation: &openapi.Operation{
+			OperationID: "listWebhooks",
+			Summary:     "List webhooks",
+			Description: "Retrieve every registered webhook, without its secret.",
+			Tags:        []string{"webhooks"},
+			Security:    writeSecurity,
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                  {"A list of webhooks", webhooksType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+	"POST /webhooks": {
+		operation: &openapi.Operation{
+			OperationID: "createWebhook",
+			Summary:     "Register a webhook",
+			Description: "Register a URL to receive post events in signed POST requests. " +
+				"Every request carries the X-Webhook-Event, X-Webhook-Delivery and X-Webhook-Timestamp headers, and X-Webhook-Signature: " +
+				"sha256= and the hex HMAC-SHA256, keyed with the secret, of the timestamp, a dot and the body. " +
+				"A secret is generated unless given; this response is the only one carrying it.",
+			Tags:     []string{"webhooks"},
+			Security: writeSecurity,
+		},
+		request: webhookType,
+		responses: map[int]responseSpec{
+			http.StatusCreated:              {"Webhook registered, with its secret", webhookType},
+			http.StatusBadRequest:           {"Malformed body or validation error", problemType},
+			http.StatusUnauthorized:         {"Missing or invalid bearer token", problemType},
+			http.StatusUnsupportedMediaType: {"Request body is not JSON", problemType},
+			http.StatusInternalServerError:  {"Internal server error", problemType},
+			http.StatusServiceUnavailable:   {"The database is not connected yet", problemType},
+		},
+	},
+	"GET /webhooks/{id}": {
+		operation: &openapi.Operation{
+			OperationID: "getWebhook",
+			Summary:     "Get a webhook",
+			Description: "Retrieve a single webhook by ID, without its secret.",
+			Tags:        []string{"webhooks"},
+			Security:    writeSecurity,
+			Parameters:  []*openapi.Parameter{webhookIDParam},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                  {"The webhook", webhookType},
+			http.StatusBadRequest:          {"Invalid ID", problemType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:            {"Webhook not found", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+	"PUT /webhooks/{id}": {
+		operation: &openapi.Operation{
+			OperationID: "updateWebhook",
+			Summary:     "Update a webhook",
+			Description: "Replace a webhook by ID. The secret is kept unless a new one is given, which the response then carries. " +
+				"Pausing a webhook holds its pending deliveries and queues no new ones until it is resumed.",
+			Tags:       []string{"webhooks"},
+			Security:   writeSecurity,
+			Parameters: []*openapi.Parameter{webhookIDParam},
+		},
+		request: webhookType,
+		responses: map[int]responseSpec{
+			http.StatusOK:                   {"Webhook updated successfully", webhookType},
+			http.StatusBadRequest:           {"Invalid ID, malformed body or validation error", problemType},
+			http.StatusUnauthorized:         {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:             {"Webhook not found", problemType},
+			http.StatusUnsupportedMediaType: {"Request body is not JSON", problemType},
+			http.StatusInternalServerError:  {"Internal server error", problemType},
+			http.StatusServiceUnavailable:   {"The database is not connected yet", problemType},
+		},
+	},
+	"DELETE /webhooks/{id}": {
+		operation: &openapi.Operation{
+			OperationID: "deleteWebhook",
+			Summary:     "Delete a webhook",
+			Description: "Delete a webhook by ID, along with its deliveries.",
+			Tags:        []string{"webhooks"},
+			Security:    writeSecurity,
+			Parameters:  []*openapi.Parameter{webhookIDParam},
+		},
+		responses: map[int]responseSpec{
+			http.StatusNoContent:           {"Webhook deleted successfully", nil},
+			http.StatusBadRequest:          {"Invalid ID", problemType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:            {"Webhook not found", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+	"GET /webhooks/{id}/deliveries": {
+		operation: &openapi.Operation{
+			OperationID: "listDeliveries",
+			Summary:     "List webhook deliveries",
+			Description: "Retrieve the newest deliveries of a webhook. Failed deliveries are retried with exponential backoff; " +
+				"those failing every attempt are dead and wait for a redelivery.",
+			Tags:     []string{"webhooks"},
+			Security: writeSecurity,
+			Parameters: []*openapi.Parameter{
+				webhookIDParam,
+				{
+					Name:        "status",
+					In:          "query",
+					Description: "Only list deliveries with this status",
+					Schema:      &openapi.Schema{Type: "string", Enum: []string{webhooks.StatusPending, webhooks.StatusDelivered, webhooks.StatusDead}},
+				},
+				{
+					Name:        "limit",
+					In:          "query",
+					Description: fmt.Sprintf("Maximum number of deliveries to return, %d by default", defaultDeliveries),
+					Schema:      &openapi.Schema{Type: "integer", Format: "int32", Minimum: floatp(1), Maximum: floatp(maxDeliveries)},
+				},
+			},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                  {"A list of deliveries, newest first", deliveriesType},
+			http.StatusBadRequest:          {"Invalid ID, status or limit", problemType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:            {"Webhook not found", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+	"GET /webhooks/{id}/deliveries/{deliveryID}": {
+		operation: &openapi.Operation{
+			OperationID: "getDelivery",
+			Summary:     "Get a webhook delivery",
+			Description: "Retrieve a delivery with the log of its attempts.",
+			Tags:        []string{"webhooks"},
+			Security:    writeSecurity,
+			Parameters:  []*openapi.Parameter{webhookIDParam, deliveryIDParam},
+		},
+		responses: map[int]responseSpec{
+			http.StatusOK:                  {"The delivery and its attempts", deliveryType},
+			http.StatusBadRequest:          {"Invalid ID", problemType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:            {"Delivery not found", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+	"POST /webhooks/{id}/deliveries/{deliveryID}/redeliver": {
+		operation: &openapi.Operation{
+			OperationID: "redeliver",
+			Summary:     "Redeliver a webhook delivery",
+			Description: "Queue a delivery, whatever its status, for an immediate attempt with a fresh allowance of retries.",
+			Tags:        []string{"webhooks"},
+			Security:    writeSecurity,
+			Parameters:  []*openapi.Parameter{webhookIDParam, deliveryIDParam},
+		},
+		responses: map[int]responseSpec{
+			http.StatusAccepted:            {"The delivery is queued", deliveryType},
+			http.StatusBadRequest:          {"Invalid ID", problemType},
+			http.StatusUnauthorized:        {"Missing or invalid bearer token", problemType},
+			http.StatusNotFound:            {"Delivery not found", problemType},
+			http.StatusInternalServerError: {"Internal server error", problemType},
+			http.StatusServiceUnavailable:  {"The database is not connected yet", problemType},
+		},
+	},
+}
+
+// undocumentedRoutes are served but deliberately left out of the document
+var undocumentedRoutes = map[string]bool{
+	"GET /swagger":      true,
+	"GET /openapi.json": true,
+	"GET /openapi.yaml": true,
+	"GET /docs":         true,
+	"GET /docs/*":       true,
+	"GET /metrics":      true,
+	"GET /ws":           true,
+}
+
+// buildOpenAPI generates the OpenAPI document from the routes registered in
+// routes(), the operations table and the models' validation tags
+func (app *Application) buildOpenAPI() (*openapi.Document, error) {
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "News API",
+			Description: "News CRUD microservice",
+			Version:     apiVersion,
+		},
+		Servers: []openapi.Server{{URL: "/"}},
+		Paths:   make(map[string]*openapi.PathItem),
+	}
+
+	schemas := openapi.NewSchemas()
+	schemas.Named("Post", postType)
+	schemas.Named("Problem", problemType)
+	schemas.Named("HealthStatus", healthType)
+	schemas.Named("ProbeStatus", probeType)
+	schemas.Named("BuildInfo", reflect.TypeOf(version.Info{}))
+	// named before the delivery referencing them, which would claim the plain names
+	schemas.Named("WebhookPayload", reflect.TypeOf(webhooks.Payload{}))
+	schemas.Named("WebhookAttempt", reflect.TypeOf(webhooks.Attempt{}))
+	schemas.Named("Webhook", webhookType)
+	schemas.Named("WebhookDelivery", deliveryType)
+
+	var undocumented []string
+	err := chi.Walk(app.routes().(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := method + " " + route
+		if undocumentedRoutes[key] {
+			return nil
+		}
+
+		spec, ok := operations[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			return nil
+		}
+
+		if doc.Paths[route] == nil {
+			doc.Paths[route] = &openapi.PathItem{}
+		}
+		if !doc.Paths[route].SetOperation(method, spec.build(schemas)) {
+			return fmt.Errorf("openapi: unsupported method %s", key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		return nil, fmt.Errorf("openapi: routes missing from the operations table: %v", undocumented)
+	}
+
+	doc.Components.Schemas = schemas.Components()
+	doc.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		bearerAuth: {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "One of the tokens configured in auth.tokens. Writes need one unless no tokens are configured.",
+		},
+	}
+	return doc, nil
+}
+
+// build resolves the operation's body types into schemas
+func (spec routeOperation) build(schemas *openapi.Schemas) *openapi.Operation {
+	op := *spec.operation
+
+	if spec.request != nil {
+		op.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content: map[string]*openapi.MediaType{
+				"application/json": {Schema: schemas.For(spec.request)},
+			},
+		}
+	}
+
+	op.Responses = make(map[string]*openapi.Response, len(spec.responses))
+	for status, resp := range spec.responses {
+		if resp.body == nil {
+			op.Responses[fmt.Sprint(status)] = &openapi.Response{Description: resp.description}
+			continue
+		}
+
+		contentType := "application/json"
+		switch resp.body {
+		case problemType:
+			contentType = problemContentType
+		case eventStreamType:
+			contentType = eventStreamContentType
+		}
+
+		op.Responses[fmt.Sprint(status)] = &openapi.Response{
+			Description: resp.description,
+			Content: map[string]*openapi.MediaType{
+				contentType: {Schema: schemas.For(resp.body)},
+			},
+		}
+	}
+	return &op
+}
+
+func floatp(f float64) *float64 {
+	return &f
+}
+
+// writeOpenAPI writes the generated document as openapi.json and openapi.yaml into dir
+func (app *Application) writeOpenAPI(dir string) error {
+	doc, err := app.buildOpenAPI()
+	if err != nil {
+		return err
+	}
+
+	jsonSpec, err := doc.JSON()
+	if err != nil {
+		return err
+	}
+	yamlSpec, err := doc.YAML()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "openapi.json"), jsonSpec, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "openapi.yaml"), yamlSpec, 0o644)
+}
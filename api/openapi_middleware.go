@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/openapi"
+	"github.com/freshusername/news-api/validation"
+	"github.com/go-chi/chi/v5"
+)
+
+// Response validation modes, set with -validate-responses
+const (
+	// responseValidationOff skips response validation, the production default
+	responseValidationOff = "off"
+	// responseValidationLog logs responses that violate the OpenAPI document
+	responseValidationLog = "log"
+	// responseValidationFail replaces responses that violate the document with a 500 problem
+	responseValidationFail = "fail"
+)
+
+// contract loads the embedded OpenAPI document once for the validation middleware
+var contract = sync.OnceValues(func() (*openapi.Validator, error) {
+	doc, err := openapi.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading the OpenAPI document: %w", err)
+	}
+	return openapi.NewValidator(doc), nil
+})
+
+// validateContract checks the path parameters, query parameters and body of
+// requests to documented routes against the OpenAPI document before the
+// handler runs. Depending on app.ResponseValidation, it also checks the
+// handler's response. It must run after routing, e.g. in a chi Group.
+func (app *Application) validateContract(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		validator, err := contract()
+		if err != nil {
+			app.problemJSON(w, r, err, http.StatusInternalServerError)
+			return
+		}
+
+		op := documentedOperation(validator.Document(), r)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		errs := validateParams(validator, op, r)
+
+		if op.RequestBody != nil {
+			bodyErrs, status, err := app.validateRequestBody(validator, op.RequestBody, w, r)
+			if err != nil {
+				app.problemJSON(w, r, err, status)
+				return
+			}
+			errs = append(errs, bodyErrs...)
+		}
+
+		if len(errs) > 0 {
+			app.validationProblemJSON(w, r, errs)
+			return
+		}
+
+		// event streams never end, so they cannot be buffered for validation
+		if app.ResponseValidation == "" || app.ResponseValidation == responseValidationOff || streamsEvents(op) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := newBufferedResponse()
+		next.ServeHTTP(rec, r)
+
+		if violations := validateResponse(validator, op, rec); len(violations) > 0 {
+			logging.FromContext(r.Context()).WarnContext(r.Context(), "response violates the OpenAPI document",
+				"method", r.Method, "path", r.URL.Path, "violations", violations)
+			if app.ResponseValidation == responseValidationFail {
+				app.problemJSON(w, r, errors.New("response violates the OpenAPI document"), http.StatusInternalServerError)
+				return
+			}
+		}
+		rec.writeTo(w)
+	})
+}
+
+// documentedOperation returns the operation documented for the matched route, or nil
+func documentedOperation(doc *openapi.Document, r *http.Request) *openapi.Operation {
+	path, ok := doc.Paths[chi.RouteContext(r.Context()).RoutePattern()]
+	if !ok {
+		return nil
+	}
+	return path.Operation(r.Method)
+}
+
+// streamsEvents reports whether op answers with an event stream
+func streamsEvents(op *openapi.Operation) bool {
+	resp, ok := op.Responses[strconv.Itoa(http.StatusOK)]
+	return ok && resp.Content[eventStreamContentType] != nil
+}
+
+func validateParams(validator *openapi.Validator, op *openapi.Operation, r *http.Request) []*validation.ValidationError {
+	var errs []*validation.ValidationError
+	query := r.URL.Query()
+
+	for _, param := range op.Parameters {
+		var raw string
+		var present bool
+
+		switch param.In {
+		case "path":
+			raw = chi.URLParam(r, param.Name)
+			present = raw != ""
+		case "query":
+			raw = query.Get(param.Name)
+			present = query.Has(param.Name)
+		default:
+			continue
+		}
+
+		if !present {
+			if param.Required {
+				err := validation.NewError(validation.CodeRequired, nil)
+				err.Field = param.Name
+				errs = append(errs, err)
+			}
+			continue
+		}
+		errs = append(errs, validator.ValidateParam(param, raw)...)
+	}
+	return errs
+}
+
+// validateRequestBody checks the body against its schema and leaves it in r.Body for the handler.
+// It returns an error with its status code when the body cannot be checked at all.
+func (app *Application) validateRequestBody(validator *openapi.Validator, body *openapi.RequestBody, w http.ResponseWriter, r *http.Request) ([]*validation.ValidationError, int, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "" {
+		// clients such as curl often omit it; the handlers only speak JSON anyway
+		mediaType = "application/json"
+	}
+	content, ok := body.Content[mediaType]
+	if !ok {
+		return nil, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported Content-Type %q", mediaType)
+	}
+
+	maxBytes := 1024 * 1024 // one megabyte
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(maxBytes)))
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+
+	if len(bytes.TrimSpace(raw)) == 0 {
+		if body.Required {
+			return nil, http.StatusBadRequest, errors.New("request body must not be empty")
+		}
+		return nil, 0, nil
+	}
+
+	value, err := decodeJSON(raw)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return validator.Validate(content.Schema, value), 0, nil
+}
+
+// validateResponse checks a buffered response against the documented responses of op
+func validateResponse(validator *openapi.Validator, op *openapi.Operation, rec *bufferedResponse) []string {
+	resp, ok := op.Responses[strconv.Itoa(rec.status)]
+	if !ok {
+		return []string{fmt.Sprintf("undocumented status %d", rec.status)}
+	}
+	if len(resp.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(rec.header.Get("Content-Type"))
+	content, ok := resp.Content[mediaType]
+	if !ok {
+		return []string{fmt.Sprintf("undocumented Content-Type %q for status %d", mediaType, rec.status)}
+	}
+
+	value, err := decodeJSON(rec.body.Bytes())
+	if err != nil {
+		return []string{fmt.Sprintf("invalid JSON body: %v", err)}
+	}
+
+	var violations []string
+	for _, err := range validator.Validate(content.Schema, value) {
+		violations = append(violations, err.PrintError())
+	}
+	return violations
+}
+
+// decodeJSON decodes raw keeping numbers as json.Number, as openapi.Validator expects
+func decodeJSON(raw []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// bufferedResponse holds a handler's response until it has been validated
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+// writeTo sends the buffered response to w
+func (b *bufferedResponse) writeTo(w http.ResponseWriter) {
+	for key, value := range b.header {
+		w.Header()[key] = value
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
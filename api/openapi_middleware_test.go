@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freshusername/news-api/models"
+)
+
+func TestValidateContractRejectsInvalidRequests(t *testing.T) {
+	called := false
+	mockDB := &MockDatabaseRepo{
+		CreatePostFunc: func(ctx context.Context, post *models.Post) (*models.Post, error) {
+			called = true
+			return post, nil
+		},
+	}
+	app := &Application{DB: mockDB}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantErrors  string
+	}{
+		{"body violates schema", "POST", "/posts", "application/json", `{"title":"","content":5}`, http.StatusBadRequest, "content:type title:length"},
+		{"missing required fields", "POST", "/posts", "", `{}`, http.StatusBadRequest, "title:required content:required"},
+		{"malformed JSON", "POST", "/posts", "application/json", `{"title":`, http.StatusBadRequest, ""},
+		{"unsupported media type", "POST", "/posts", "text/plain", `title`, http.StatusUnsupportedMediaType, ""},
+		{"path parameter type", "DELETE", "/posts/abc", "", ``, http.StatusBadRequest, "id:type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rr := httptest.NewRecorder()
+
+			app.routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("wrong status code: got %v want %v (%s)", rr.Code, tt.wantStatus, rr.Body.String())
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != problemContentType {
+				t.Errorf("wrong Content-Type: got %v want %v", contentType, problemContentType)
+			}
+
+			var problem ProblemDetails
+			if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+				t.Fatalf("Could not decode response: %v", err)
+			}
+			var got []string
+			for _, err := range problem.Errors {
+				got = append(got, err.Field+":"+err.Code)
+			}
+			if strings.Join(got, " ") != tt.wantErrors {
+				t.Errorf("unexpected field errors: got %q want %q", strings.Join(got, " "), tt.wantErrors)
+			}
+		})
+	}
+
+	if called {
+		t.Error("handler ran for an invalid request")
+	}
+}
+
+func TestValidateContractPassesValidRequests(t *testing.T) {
+	mockDB := &MockDatabaseRepo{
+		CreatePostFunc: func(ctx context.Context, post *models.Post) (*models.Post, error) {
+			return &models.Post{ID: 1, Title: post.Title, Content: post.Content}, nil
+		},
+	}
+	app := &Application{DB: mockDB, ResponseValidation: responseValidationFail}
+
+	body := `{"title":"Valid title", "content":"Valid content"}`
+	req := httptest.NewRequest("POST", "/posts", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rr := httptest.NewRecorder()
+
+	app.routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("wrong status code: got %v want %v (%s)", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	var post models.Post
+	if err := json.NewDecoder(rr.Body).Decode(&post); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+	if post.Title != "Valid title" {
+		t.Errorf("handler did not receive the request body: got title %q", post.Title)
+	}
+}
+
+func TestValidateContractResponses(t *testing.T) {
+	mockDB := &MockDatabaseRepo{
+		GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+			// a title longer than the documented maxLength
+			return []*models.Post{{ID: 1, Title: strings.Repeat("a", 300), Content: "c"}}, nil
+		},
+	}
+
+	tests := map[string]int{
+		responseValidationOff:  http.StatusOK,
+		responseValidationLog:  http.StatusOK,
+		responseValidationFail: http.StatusInternalServerError,
+	}
+
+	for mode, wantStatus := range tests {
+		app := &Application{DB: mockDB, ResponseValidation: mode}
+
+		req := httptest.NewRequest("GET", "/posts", nil)
+		rr := httptest.NewRecorder()
+
+		app.routes().ServeHTTP(rr, req)
+
+		if rr.Code != wantStatus {
+			t.Errorf("%s: wrong status code: got %v want %v", mode, rr.Code, wantStatus)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freshusername/news-api/openapi"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	app := &Application{}
+
+	// Every documented operation must still be routed
+	routed := make(map[string]bool)
+	err := chi.Walk(app.routes().(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routed[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key := range operations {
+		if !routed[key] {
+			t.Errorf("operation %q is documented but not routed", key)
+		}
+	}
+
+	// Every route must be documented, and the embedded document must be current
+	doc, err := app.buildOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonSpec, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlSpec, err := doc.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(jsonSpec, openapi.SpecJSON) || !bytes.Equal(yamlSpec, openapi.SpecYAML) {
+		t.Error("embedded OpenAPI document is out of date, run `go generate ./api`")
+	}
+}
+
+func TestHandleSwagger(t *testing.T) {
+	app := &Application{}
+
+	tests := []struct {
+		path        string
+		contentType string
+		body        []byte
+	}{
+		{"/swagger", "application/json", openapi.SpecJSON},
+		{"/openapi.json", "application/json", openapi.SpecJSON},
+		{"/openapi.yaml", "application/yaml", openapi.SpecYAML},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		rr := httptest.NewRecorder()
+
+		app.routes().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: wrong status code: got %v want %v", tt.path, rr.Code, http.StatusOK)
+		}
+		if contentType := rr.Header().Get("Content-Type"); contentType != tt.contentType {
+			t.Errorf("%s: wrong Content-Type: got %v want %v", tt.path, contentType, tt.contentType)
+		}
+		if !bytes.Equal(rr.Body.Bytes(), tt.body) {
+			t.Errorf("%s: body does not match the embedded document", tt.path)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/outbox"
+)
+
+// outboxSinks opens the sinks of the outbox relay, in the configured order,
+// and returns a function closing them. The bus queues webhook deliveries.
+func (app *Application) outboxSinks(cfg config.Outbox) ([]outbox.Sink, func(), error) {
+	var sinks []outbox.Sink
+	var files []*outbox.WriterSink
+	closeFiles := func() {
+		for _, file := range files {
+			if err := file.Close(); err != nil {
+				app.Logger.Error("closing the outbox file failed", "error", err)
+			}
+		}
+	}
+
+	for _, name := range cfg.Sinks {
+		switch name {
+		case outbox.SinkBus:
+			bus := outbox.NewBus()
+			if app.Webhooks != nil {
+				bus.Subscribe("webhooks", app.enqueueWebhooks)
+			}
+			sinks = append(sinks, bus)
+		case outbox.SinkFile:
+			file, err := outbox.NewFileSink(cfg.File)
+			if err != nil {
+				closeFiles()
+				return nil, nil, err
+			}
+			files = append(files, file)
+			sinks = append(sinks, file)
+		case outbox.SinkStdout:
+			sinks = append(sinks, outbox.NewStdoutSink())
+		}
+	}
+	return sinks, closeFiles, nil
+}
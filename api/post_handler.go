@@ -13,131 +13,98 @@ import (
 )
 
 // HandleCreatePost handles the creation of a new post
-// swagger:operation POST /posts posts createPost
-// ---
-// summary: Creates a new post.
-// description: This will create a new post based on the data provided in the request body.
-// parameters:
-//   - name: post
-//     in: body
-//     description: The post to create.
-//     required: true
-//     schema:
-//     "$ref": "#/definitions/Post"
-//
-// responses:
-//
-//	"201":
-//	  description: "Post created successfully."
-//	  schema:
-//	    "$ref": "#/definitions/Post"
-//	"400":
-//	  description: "Validation error"
-//	"500":
-//	  description: "Internal server error."
 func (app *Application) HandleCreatePost(w http.ResponseWriter, r *http.Request) {
 	post := new(models.Post)
 
 	// Decode the request body into post
 	err := json.NewDecoder(r.Body).Decode(post)
 	if err != nil {
-		app.errorJSON(w, err, http.StatusBadRequest)
+		app.problemJSON(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	//validate
-	validator := validation.NewValidator()
-	validator.AddRule("Title", validation.Required())
-	validator.AddRule("Title", validation.Length(1, 255))
-	validator.AddRule("Content", validation.Required())
-	validator.AddRule("Content", validation.Length(1, 500))
-
-	errors := validator.Validate(post)
-
-	if len(errors) > 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		for _, err := range errors {
-			fmt.Fprintf(w, "%s\n", err.PrintError())
-		}
+	errs := validation.NewValidator().Validate(post)
+
+	if len(errs) > 0 {
+		app.validationProblemJSON(w, r, errs)
 		return
 	}
 
 	// Close the request body to prevent resource leaks
 	defer r.Body.Close()
 
-	createdItem, err := app.DB.CreatePost(post)
+	createdItem, err := app.DB.CreatePost(r.Context(), post)
 	if err != nil {
-		app.errorJSON(w, err, http.StatusInternalServerError)
+		app.dbErrorJSON(w, r, err)
 		return
 	}
 
 	app.writeJSON(w, http.StatusCreated, createdItem)
 }
 
-// HandleGetPosts retrieves all posts
-// swagger:operation GET /posts posts listPosts
-// ---
-// summary: List all posts
-// description: Retrieve a list of all posts.
-// responses:
-//
-//	"200":
-//	  description: "A list of posts"
-//	  schema:
-//	    type: array
-//	    items:
-//	      "$ref": "#/definitions/Post"
+// Paging bounds of HandleGetPosts
+const (
+	maxPageSize     = 100
+	defaultPageSize = 20
+)
+
+// HandleGetPosts retrieves all posts, or a single page of them when the
+// limit or offset query parameter is given
 func (app *Application) HandleGetPosts(w http.ResponseWriter, r *http.Request) {
-	posts, err := app.DB.GetAllPosts()
+	limit, offset, paged, err := pageParams(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	var posts []*models.Post
+	if paged {
+		posts, err = app.DB.GetPostsPage(r.Context(), limit, offset)
+	} else {
+		posts, err = app.DB.GetAllPosts(r.Context())
+	}
 	if err != nil {
-		app.errorJSON(w, err)
+		app.dbErrorJSON(w, r, err)
 		return
 	}
 
+	// an empty list is [] rather than null
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+
+	if app.notModified(w, r, app.listLastModified(posts)) {
+		return
+	}
 	_ = app.writeJSON(w, http.StatusOK, posts)
 }
 
-// HandleUpdatePost updates a post by ID
-// swagger:operation PUT /posts/{id} posts updatePost
-// ---
-// summary: Update a post
-// description: Update the details of an existing post by ID.
-// parameters:
-//   - name: id
-//     in: path
-//     description: ID of the post to update
-//     required: true
-//     type: integer
-//     format: int32
-//   - name: post
-//     in: body
-//     description: The post data to update
-//     required: true
-//     schema:
-//     "$ref": "#/definitions/Post"
-//
-// responses:
-//
-//	"200":
-//	  description: "Post updated successfully"
-//	  schema:
-//	    "$ref": "#/definitions/Post"
-//	"400":
-//	  description: "Validation error"
-//	"500":
-//	  description: "Internal server error"
-func (app *Application) HandleUpdatePost(w http.ResponseWriter, r *http.Request) {
-	// Extract the item ID from the URL using Chi's URLParam function
-	idString := chi.URLParam(r, "id")
-	if idString == "" {
-		app.errorJSON(w, errors.New("missing item id"), http.StatusBadRequest)
+// HandleGetPost retrieves a post by ID
+func (app *Application) HandleGetPost(w http.ResponseWriter, r *http.Request) {
+	id, err := postID(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	// Convert the ID from string to int32
-	id, err := strconv.ParseInt(idString, 10, 32)
+	post, err := app.DB.GetPost(r.Context(), id)
 	if err != nil {
-		app.errorJSON(w, errors.New("invalid item id format"), http.StatusBadRequest)
+		app.dbErrorJSON(w, r, err)
+		return
+	}
+
+	if app.notModified(w, r, post.UpdatedAt) {
+		return
+	}
+	_ = app.writeJSON(w, http.StatusOK, post)
+}
+
+// HandleUpdatePost updates a post by ID
+func (app *Application) HandleUpdatePost(w http.ResponseWriter, r *http.Request) {
+	id, err := postID(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
 		return
 	}
 
@@ -145,31 +112,22 @@ func (app *Application) HandleUpdatePost(w http.ResponseWriter, r *http.Request)
 	var post *models.Post
 	err = json.NewDecoder(r.Body).Decode(&post)
 	if err != nil {
-		app.errorJSON(w, err, http.StatusBadRequest)
+		app.problemJSON(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	//validate
-	validator := validation.NewValidator()
-	validator.AddRule("Title", validation.Required())
-	validator.AddRule("Title", validation.Length(1, 255))
-	validator.AddRule("Content", validation.Required())
-	validator.AddRule("Content", validation.Length(1, 500))
-
-	errors := validator.Validate(post)
-
-	if len(errors) > 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		for _, err := range errors {
-			fmt.Fprintf(w, "%s\n", err.PrintError())
-		}
+	errs := validation.NewValidator().Validate(post)
+
+	if len(errs) > 0 {
+		app.validationProblemJSON(w, r, errs)
 		return
 	}
 
 	// Update the post in the database
-	updatedPost, err := app.DB.UpdatePost(int32(id), post)
+	updatedPost, err := app.DB.UpdatePost(r.Context(), id, post)
 	if err != nil {
-		app.errorJSON(w, err, http.StatusInternalServerError)
+		app.dbErrorJSON(w, r, err)
 		return
 	}
 
@@ -178,44 +136,16 @@ func (app *Application) HandleUpdatePost(w http.ResponseWriter, r *http.Request)
 }
 
 // HandleDeletePost deletes a post by ID
-// swagger:operation DELETE /posts/{id} posts deletePost
-// ---
-// summary: Delete a post
-// description: Delete an existing post by ID.
-// parameters:
-//   - name: id
-//     in: path
-//     description: ID of the post to delete
-//     required: true
-//     type: integer
-//     format: int32
-//
-// responses:
-//
-//	"200":
-//	  description: "Post deleted successfully"
-//	"400":
-//	  description: "missing item id"
-//	"500":
-//	  description: "Internal server error"
 func (app *Application) HandleDeletePost(w http.ResponseWriter, r *http.Request) {
-	// Extract the item ID from the URL using Chi's URLParam function
-	idString := chi.URLParam(r, "id")
-	if idString == "" {
-		app.errorJSON(w, errors.New("missing item id"), http.StatusBadRequest)
-		return
-	}
-
-	// Convert the ID from string to int32
-	id, err := strconv.ParseInt(idString, 10, 32)
+	id, err := postID(r)
 	if err != nil {
-		app.errorJSON(w, errors.New("invalid item id format"), http.StatusBadRequest)
+		app.problemJSON(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	deletedID, err := app.DB.DeletePost(int32(id))
+	deletedID, err := app.DB.DeletePost(r.Context(), id)
 	if err != nil {
-		app.errorJSON(w, err, http.StatusInternalServerError)
+		app.dbErrorJSON(w, r, err)
 		return
 	}
 
@@ -224,3 +154,45 @@ func (app *Application) HandleDeletePost(w http.ResponseWriter, r *http.Request)
 
 	app.writeJSON(w, http.StatusOK, resp)
 }
+
+// postID extracts the post ID from the URL using Chi's URLParam function
+func postID(r *http.Request) (int32, error) {
+	idString := chi.URLParam(r, "id")
+	if idString == "" {
+		return 0, errors.New("missing item id")
+	}
+
+	// Convert the ID from string to int32
+	id, err := strconv.ParseInt(idString, 10, 32)
+	if err != nil {
+		return 0, errors.New("invalid item id format")
+	}
+
+	return int32(id), nil
+}
+
+// pageParams reads the limit and offset query parameters, reporting whether either was given
+func pageParams(r *http.Request) (limit, offset int, paged bool, err error) {
+	query := r.URL.Query()
+	if !query.Has("limit") && !query.Has("offset") {
+		return 0, 0, false, nil
+	}
+
+	limit, offset = defaultPageSize, 0
+
+	if query.Has("limit") {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, false, fmt.Errorf("limit must be an integer between 1 and %d", maxPageSize)
+		}
+	}
+
+	if query.Has("offset") {
+		offset, err = strconv.Atoi(query.Get("offset"))
+		if err != nil || offset < 0 {
+			return 0, 0, false, errors.New("offset must be a non-negative integer")
+		}
+	}
+
+	return limit, offset, true, nil
+}
@@ -23,17 +23,24 @@ import (
 )
 
 var (
-	db      *sql.DB
-	cleanup func()
+	db *sql.DB
+	// migratedDSN connects to the database of db, migrated to the newest version
+	migratedDSN string
+	cleanup     func()
 )
 
 func TestMain(m *testing.M) {
 	ctx := context.Background()
 	var err error
-	db, cleanup, err = setupPostgresContainer(ctx)
+	migratedDSN, cleanup, err = startPostgresContainer(ctx)
 	if err != nil {
 		log.Fatalf("Could not start postgres container: %s", err)
 	}
+	db, err = sql.Open("postgres", migratedDSN)
+	if err != nil {
+		cleanup()
+		log.Fatalf("Could not open the test database: %s", err)
+	}
 
 	// Run the tests
 	code := m.Run()
@@ -45,6 +52,20 @@ func TestMain(m *testing.M) {
 }
 
 func setupPostgresContainer(ctx context.Context) (*sql.DB, func(), error) {
+	dsn, cleanup, err := startPostgresContainer(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	return db, cleanup, nil
+}
+
+// startPostgresContainer starts a Postgres container, applies the migrations
+// and returns its DSN
+func startPostgresContainer(ctx context.Context) (string, func(), error) {
 	req := testcontainers.ContainerRequest{
 		Image:        "postgres:13",
 		ExposedPorts: []string{"5432/tcp"},
@@ -60,7 +81,7 @@ func setupPostgresContainer(ctx context.Context) (*sql.DB, func(), error) {
 		Started:          true,
 	})
 	if err != nil {
-		return nil, nil, err
+		return "", nil, err
 	}
 
 	// Closure to clean up the container
@@ -72,17 +93,12 @@ func setupPostgresContainer(ctx context.Context) (*sql.DB, func(), error) {
 	port, _ := postgresC.MappedPort(ctx, "5432")
 	dsn := fmt.Sprintf("host=localhost port=%s user=user password=password dbname=testdb sslmode=disable", port.Port())
 
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, cleanup, err
-	}
-
 	// Applying Goose migrations
 	if err := applyGooseMigrations(dsn); err != nil {
 		cleanup()
 		log.Fatalf("Failed to apply goose migrations: %v", err)
 	}
-	return db, cleanup, nil
+	return dsn, cleanup, nil
 }
 
 func TestHandleCreatePost_Integration(t *testing.T) {
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+)
+
+// A write answers with the time, in Unix milliseconds, until which the
+// client's reads go to the primary. Browsers send it back as a cookie, other
+// clients echo the header.
+const (
+	readYourWritesCookie = "news_primary_until"
+	readYourWritesHeader = "X-Primary-Until"
+)
+
+// readYourWrites lets a client read its own writes while they replicate:
+// after a write, its reads skip the replicas for the read-your-writes window
+func (app *Application) readYourWrites(next http.Handler) http.Handler {
+	window := app.Database.ReadYourWritesWindow
+	if len(app.Database.ReplicaDSNs) == 0 || window <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isRead(r.Method) {
+			until := strconv.FormatInt(time.Now().Add(window).UnixMilli(), 10)
+			w.Header().Set(readYourWritesHeader, until)
+			http.SetCookie(w, &http.Cookie{
+				Name:     readYourWritesCookie,
+				Value:    until,
+				Path:     "/",
+				MaxAge:   int(window.Round(time.Second).Seconds()) + 1,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		} else if primaryUntil(r).After(time.Now()) {
+			r = r.WithContext(database.WithPrimary(r.Context()))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// primaryUntil returns the read-your-writes deadline sent by the client, or the zero time
+func primaryUntil(r *http.Request) time.Time {
+	value := r.Header.Get(readYourWritesHeader)
+	if value == "" {
+		if cookie, err := r.Cookie(readYourWritesCookie); err == nil {
+			value = cookie.Value
+		}
+	}
+
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.ReplicaDSNs = []string{"postgres://replica/news"}
+
+	var fromPrimary bool
+	app := &Application{
+		Logger:   logging.Discard(),
+		Database: cfg,
+		DB: &MockDatabaseRepo{
+			GetPostFunc: func(ctx context.Context, id int32) (*models.Post, error) {
+				fromPrimary = database.UsesPrimary(ctx)
+				return &models.Post{ID: int(id), Title: "Title", Content: "Content"}, nil
+			},
+			DeletePostFunc: func(ctx context.Context, id int32) (int32, error) {
+				return id, nil
+			},
+		},
+	}
+	handler := app.routes()
+
+	get := func(prepare func(*http.Request)) bool {
+		req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+		prepare(req)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return fromPrimary
+	}
+
+	if get(func(*http.Request) {}) {
+		t.Error("expected a fresh client to read from the replicas")
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/posts/1", nil))
+	until := rr.Header().Get(readYourWritesHeader)
+	ms, err := strconv.ParseInt(until, 10, 64)
+	if err != nil || time.Until(time.UnixMilli(ms)) <= 0 || time.Until(time.UnixMilli(ms)) > cfg.ReadYourWritesWindow {
+		t.Fatalf("unexpected %s %q", readYourWritesHeader, until)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != readYourWritesCookie || cookies[0].Value != until {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+
+	if !get(func(r *http.Request) { r.Header.Set(readYourWritesHeader, until) }) {
+		t.Error("expected the header to send reads to the primary")
+	}
+	if !get(func(r *http.Request) { r.AddCookie(cookies[0]) }) {
+		t.Error("expected the cookie to send reads to the primary")
+	}
+	expired := strconv.FormatInt(time.Now().Add(-time.Second).UnixMilli(), 10)
+	if get(func(r *http.Request) { r.Header.Set(readYourWritesHeader, expired) }) {
+		t.Error("expected an expired deadline to read from the replicas")
+	}
+}
+
+func TestReadYourWritesWithoutReplicas(t *testing.T) {
+	app := &Application{Logger: logging.Discard(), Database: config.Default().Database, DB: &MockDatabaseRepo{}}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/posts/1", nil))
+	if rr.Header().Get(readYourWritesHeader) != "" || len(rr.Result().Cookies()) != 0 {
+		t.Error("expected no read-your-writes state without replicas")
+	}
+}
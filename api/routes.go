@@ -1,25 +1,115 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
 )
 
 func (app *Application) routes() http.Handler {
 	// create a router mux
 	mux := chi.NewRouter()
+	mux.NotFound(app.notFound)
+	mux.MethodNotAllowed(app.methodNotAllowed(mux))
 
-	mux.Use(middleware.Recoverer)
-	mux.Get("/", app.HealthCheck)
-	mux.Get("/posts", app.HandleGetPosts)
-	mux.Post("/posts", app.HandleCreatePost)
-	mux.Put("/posts/{id}", app.HandleUpdatePost)
-	mux.Delete("/posts/{id}", app.HandleDeletePost)
+	mux.Use(app.traceRequests)
+	mux.Use(app.requestID)
+	if app.Metrics != nil {
+		mux.Use(app.measureRequests)
+	}
+	mux.Use(app.accessLog)
+	mux.Use(app.recoverPanic)
+	if app.CORS.Enabled() {
+		mux.Use(cors.Handler(cors.Options{
+			AllowedOrigins:   app.CORS.AllowedOrigins,
+			AllowedMethods:   app.CORS.AllowedMethods,
+			AllowedHeaders:   app.CORS.AllowedHeaders,
+			ExposedHeaders:   app.CORS.ExposedHeaders,
+			AllowCredentials: app.CORS.AllowCredentials,
+			MaxAge:           int(app.CORS.MaxAge.Seconds()),
+		}))
+	}
+
+	// routes documented in the OpenAPI document, which is enforced as their contract
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.validateContract)
+
+		mux.Get("/", app.HealthCheck)
+		mux.Get("/livez", app.HandleLivez)
+		mux.Get("/readyz", app.HandleReadyz)
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(app.authenticate)
+			mux.Use(app.readYourWrites)
+
+			mux.Get("/posts", app.HandleGetPosts)
+			mux.Post("/posts", app.HandleCreatePost)
+			mux.Get("/posts/stream", app.HandleStreamPosts)
+			mux.Get("/posts/{id}", app.HandleGetPost)
+			mux.Put("/posts/{id}", app.HandleUpdatePost)
+			mux.Delete("/posts/{id}", app.HandleDeletePost)
+		})
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(app.requireToken)
+
+			mux.Get("/webhooks", app.HandleListWebhooks)
+			mux.Post("/webhooks", app.HandleCreateWebhook)
+			mux.Get("/webhooks/{id}", app.HandleGetWebhook)
+			mux.Put("/webhooks/{id}", app.HandleUpdateWebhook)
+			mux.Delete("/webhooks/{id}", app.HandleDeleteWebhook)
+			mux.Get("/webhooks/{id}/deliveries", app.HandleListDeliveries)
+			mux.Get("/webhooks/{id}/deliveries/{deliveryID}", app.HandleGetDelivery)
+			mux.Post("/webhooks/{id}/deliveries/{deliveryID}/redeliver", app.HandleRedeliver)
+		})
+	})
+
+	// WebSocket subscriptions, which OpenAPI cannot describe; the token is checked on connect
+	mux.Group(func(mux chi.Router) {
+		mux.Use(app.requireSocketToken)
+		mux.Get("/ws", app.HandleWebSocket)
+	})
 
 	//openapi specification
 	mux.Get("/swagger", app.HandleSwagger)
+	mux.Get("/openapi.json", app.HandleSwagger)
+	mux.Get("/openapi.yaml", app.HandleSwaggerYAML)
+
+	// Prometheus metrics
+	if app.Metrics != nil {
+		mux.Method(http.MethodGet, metricsPath, app.Metrics.Handler())
+	}
+
+	// interactive documentation, disabled in production
+	if app.DocsEnabled {
+		docs := app.docsHandler()
+		mux.Get(docsPath, docs.ServeHTTP)
+		mux.Get(docsPath+"/*", docs.ServeHTTP)
+	}
 
 	return mux
 }
+
+// routeMethods are the methods looked up for the Allow header of a 405
+var routeMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// notFound answers a request matching no route with a problem
+func (app *Application) notFound(w http.ResponseWriter, r *http.Request) {
+	app.problemJSON(w, r, fmt.Errorf("no route matches %s", r.URL.Path), http.StatusNotFound)
+}
+
+// methodNotAllowed answers a request for a route with another method with a
+// problem, listing the methods of the route in the Allow header. chi only
+// sets it in its own handler.
+func (app *Application) methodNotAllowed(mux *chi.Mux) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, method := range routeMethods {
+			if mux.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				w.Header().Add("Allow", method)
+			}
+		}
+		app.problemJSON(w, r, fmt.Errorf("method %s is not allowed for %s", r.Method, r.URL.Path), http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/freshusername/news-api/config"
+)
+
+// newServer builds the HTTP server for the application routes
+func (app *Application) newServer(cfg config.Server) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           app.routes(),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		ErrorLog:          slog.NewLogLogger(app.logger().Handler(), slog.LevelWarn),
+	}
+}
+
+// serve serves the application on ln until ctx is done, then shuts down
+// gracefully: readiness turns failing, and after cfg.ShutdownDelay in-flight
+// requests are drained within cfg.ShutdownTimeout, then background workers
+// are stopped within another. Connections still open at the first deadline
+// are closed.
+func (app *Application) serve(ctx context.Context, ln net.Listener, cfg config.Server) error {
+	srv := app.newServer(cfg)
+	if app.Events != nil {
+		// end the event streams, which would otherwise hold up draining
+		srv.RegisterOnShutdown(app.Events.Close)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(ln)
+	}()
+	app.logger().Info("serving HTTP", "addr", ln.Addr().String())
+
+	select {
+	case err := <-serveErr:
+		// the server failed on its own, e.g. the listener broke
+		app.stopWorkers(context.Background())
+		return err
+	case <-ctx.Done():
+	}
+
+	app.logger().Info("shutting down", "delay", cfg.ShutdownDelay, "timeout", cfg.ShutdownTimeout)
+	if app.Health != nil {
+		app.Health.Shutdown()
+	}
+	time.Sleep(cfg.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		app.logger().Warn("connections not drained in time, closing them")
+		err = srv.Close()
+	}
+	if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+
+	// the workers get a deadline of their own, which draining cannot use up
+	workersCtx, cancelWorkers := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelWorkers()
+	if stopErr := app.stopWorkers(workersCtx); err == nil {
+		err = stopErr
+	}
+
+	app.logger().Info("server stopped")
+	return err
+}
+
+// workers runs the application's background goroutines until they are stopped
+type workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// goWorker runs fn in the background until shutdown cancels its context. fn must return once ctx is done.
+func (app *Application) goWorker(name string, fn func(ctx context.Context)) {
+	if app.workers == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		app.workers = &workers{ctx: ctx, cancel: cancel}
+	}
+
+	app.workers.wg.Add(1)
+	go func() {
+		defer app.workers.wg.Done()
+		fn(app.workers.ctx)
+		app.logger().Info("worker stopped", "worker", name)
+	}()
+}
+
+// stopWorkers cancels the background workers and waits for them until ctx is done
+func (app *Application) stopWorkers(ctx context.Context) error {
+	if app.workers == nil {
+		return nil
+	}
+	app.workers.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		app.workers.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("background workers did not stop in time")
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/health"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+)
+
+// startServer serves app on a random port until the returned cancel is called
+func startServer(t *testing.T, app *Application, cfg config.Server) (baseURL string, cancel context.CancelFunc, done <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- app.serve(ctx, ln, cfg)
+	}()
+	t.Cleanup(cancel)
+
+	return "http://" + ln.Addr().String(), cancel, errc
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	app := &Application{
+		Logger: logging.Discard(),
+		Health: health.NewRegistry(),
+		DB: &MockDatabaseRepo{
+			GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+				close(entered)
+				<-release
+				return nil, nil
+			},
+		},
+	}
+
+	workerStopped := make(chan struct{})
+	app.goWorker("test", func(ctx context.Context) {
+		<-ctx.Done()
+		close(workerStopped)
+	})
+
+	cfg := config.Default().Server
+	cfg.ShutdownDelay = 200 * time.Millisecond
+	baseURL, cancel, done := startServer(t, app, cfg)
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	// a request in flight when the signal arrives
+	inFlight := make(chan int, 1)
+	go func() {
+		resp, err := client.Get(baseURL + "/posts")
+		if err != nil {
+			t.Error(err)
+			inFlight <- 0
+			return
+		}
+		resp.Body.Close()
+		inFlight <- resp.StatusCode
+	}()
+	<-entered
+
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+
+	// during the shutdown delay the instance reports itself not ready
+	resp, err := client.Get(baseURL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected /readyz to fail while shutting down, got %d", resp.StatusCode)
+	}
+
+	select {
+	case <-workerStopped:
+		t.Fatal("worker stopped before connections were drained")
+	default:
+	}
+
+	close(release)
+	if status := <-inFlight; status != http.StatusOK {
+		t.Errorf("in-flight request got status %d", status)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("serve returned %v", err)
+	}
+
+	select {
+	case <-workerStopped:
+	default:
+		t.Error("worker was not stopped")
+	}
+
+	if _, err := client.Get(baseURL + "/livez"); err == nil {
+		t.Error("server still accepts connections after shutdown")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	entered := make(chan struct{})
+	app := &Application{
+		Logger: logging.Discard(),
+		DB: &MockDatabaseRepo{
+			GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+				close(entered)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+	}
+
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = 100 * time.Millisecond
+	baseURL, cancel, done := startServer(t, app, cfg)
+
+	go func() {
+		if resp, err := http.Get(baseURL + "/posts"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	start := time.Now()
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("serve returned %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown took %s despite the timeout", elapsed)
+	}
+}
+
+func TestWorkersGetTheirOwnShutdownTimeout(t *testing.T) {
+	entered := make(chan struct{})
+	app := &Application{
+		Logger: logging.Discard(),
+		DB: &MockDatabaseRepo{
+			GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+				close(entered)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+	}
+	// a worker finishing its work for a while after being stopped
+	app.goWorker("test", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(50 * time.Millisecond)
+	})
+
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = 100 * time.Millisecond
+	baseURL, cancel, done := startServer(t, app, cfg)
+
+	go func() {
+		if resp, err := http.Get(baseURL + "/posts"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	// the request holds up draining until the deadline
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected the worker to stop in time, serve returned %v", err)
+	}
+}
+
+func TestNewServerTimeouts(t *testing.T) {
+	srv := (&Application{}).newServer(config.Default().Server)
+
+	if srv.ReadHeaderTimeout == 0 || srv.ReadTimeout == 0 || srv.WriteTimeout == 0 || srv.IdleTimeout == 0 || srv.MaxHeaderBytes == 0 {
+		t.Errorf("server without timeouts or header limit: %+v", srv)
+	}
+}
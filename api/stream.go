@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/events"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/outbox"
+)
+
+const (
+	// streamRetry is the reconnection delay suggested to event stream clients
+	streamRetry = 3 * time.Second
+	// streamWriteTimeout bounds writing one event to a client, replacing the
+	// server's write timeout, which would end every stream
+	streamWriteTimeout = 10 * time.Second
+)
+
+// streamEvents maps the event types of the outbox to those of the stream
+var streamEvents = map[string]string{
+	database.EventPostCreated: events.Created,
+	database.EventPostUpdated: events.Updated,
+	database.EventPostDeleted: events.Deleted,
+}
+
+// HandleStreamPosts streams post changes as server-sent events, optionally
+// only those of the categories in the category query parameter. A client
+// resuming with Last-Event-ID first receives the events it missed, or a
+// resync event when they are no longer buffered. A client too slow to keep
+// up is disconnected, to resume from its last event.
+func (app *Application) HandleStreamPosts(w http.ResponseWriter, r *http.Request) {
+	if app.Events == nil {
+		app.problemJSON(w, r, errors.New("live updates are disabled"), http.StatusServiceUnavailable)
+		return
+	}
+
+	var match func(events.Event) bool
+	if categories := categoryFilter(r); categories != nil {
+		match = func(e events.Event) bool {
+			return categories[e.Category()]
+		}
+	}
+	sub, replay, _ := app.Events.Subscribe(r.Header.Get("Last-Event-ID"), app.Stream.ClientBuffer, match)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// keep proxies such as nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(write func(io.Writer) error) bool {
+		// an unsupported deadline only means the server's write timeout applies
+		_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if err := write(w); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	ok := send(func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+		return err
+	})
+	for _, e := range replay {
+		if !ok {
+			break
+		}
+		ok = send(eventWriter(e))
+	}
+
+	heartbeat := time.NewTicker(app.Stream.Heartbeat)
+	defer heartbeat.Stop()
+
+	for ok {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			ok = send(func(w io.Writer) error {
+				_, err := io.WriteString(w, ": heartbeat\n\n")
+				return err
+			})
+		case e, open := <-sub.Events():
+			if !open {
+				if errors.Is(sub.Err(), events.ErrSlowConsumer) {
+					logging.FromContext(r.Context()).WarnContext(r.Context(), "disconnecting a slow event stream client")
+				}
+				return
+			}
+			ok = send(eventWriter(e))
+		}
+	}
+}
+
+// eventWriter writes e in the event stream format. Its data is the post as
+// JSON, or an empty object for resyncs.
+func eventWriter(e events.Event) func(io.Writer) error {
+	return func(w io.Writer) error {
+		data := []byte("{}")
+		if e.Post != nil {
+			var err error
+			if data, err = json.Marshal(e.Post); err != nil {
+				return err
+			}
+		}
+		if e.ID != "" {
+			if _, err := fmt.Fprintf(w, "id: %s\n", e.ID); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		return err
+	}
+}
+
+// categoryFilter returns the categories listed in the category query
+// parameters, comma separated or repeated, or nil for all
+func categoryFilter(r *http.Request) map[string]bool {
+	var categories map[string]bool
+	for _, param := range r.URL.Query()["category"] {
+		for _, category := range strings.Split(param, ",") {
+			if categories == nil {
+				categories = make(map[string]bool)
+			}
+			categories[strings.TrimSpace(category)] = true
+		}
+	}
+	return categories
+}
+
+// streamEvent streams a post event followed by the outbox tail
+func (app *Application) streamEvent(e outbox.Event) {
+	eventType, ok := streamEvents[e.Type]
+	if !ok {
+		return
+	}
+	app.Events.Publish(events.Event{Type: eventType, Post: e.Post, At: e.OccurredAt})
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/events"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+	"github.com/freshusername/news-api/outbox"
+)
+
+// sseEvent is an event read from a stream
+type sseEvent struct {
+	id, event, data string
+}
+
+// readEvents reads n events from an event stream, skipping comments and the retry hint
+func readEvents(t *testing.T, scanner *bufio.Scanner, n int) []sseEvent {
+	t.Helper()
+	var got []sseEvent
+	var e sseEvent
+	for len(got) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if e.event != "" {
+				got = append(got, e)
+			}
+			e = sseEvent{}
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if len(got) < n {
+		t.Fatalf("stream ended after %d of %d events: %v", len(got), n, scanner.Err())
+	}
+	return got
+}
+
+// openStream connects to the event stream of srv
+func openStream(t *testing.T, ctx context.Context, url, lastEventID string) *bufio.Scanner {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewScanner(resp.Body)
+}
+
+func TestStreamPosts(t *testing.T) {
+	app := &Application{
+		Logger: logging.Discard(),
+		DB:     &MockDatabaseRepo{},
+		Events: events.NewBroker(10),
+		Stream: config.Stream{Heartbeat: time.Hour, ClientBuffer: 10},
+		// streams must bypass response buffering
+		ResponseValidation: responseValidationFail,
+	}
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the handler subscribes before sending the response headers
+	sports := openStream(t, ctx, srv.URL+"/posts/stream?category=sports,tech", "")
+
+	app.streamEvent(outbox.Event{Type: database.EventPostCreated, Post: &models.Post{ID: 1, Title: "Goal", Category: "sports"}})
+	app.streamEvent(outbox.Event{Type: database.EventPostCreated, Post: &models.Post{ID: 2, Title: "Rates", Category: "finance"}})
+	app.streamEvent(outbox.Event{Type: database.EventPostDeleted, Post: &models.Post{ID: 1, Title: "Goal", Category: "sports"}})
+
+	got := readEvents(t, sports, 2)
+	if got[0].event != events.Created || !strings.Contains(got[0].data, `"title":"Goal"`) || got[0].id == "" {
+		t.Errorf("unexpected first event %+v", got[0])
+	}
+	if got[1].event != events.Deleted || !strings.Contains(got[1].data, `"id":1`) {
+		t.Errorf("expected the finance post to be filtered out, got %+v", got[1])
+	}
+
+	// resuming after the first event replays the two later ones
+	resumed := openStream(t, ctx, srv.URL+"/posts/stream", got[0].id)
+	replayed := readEvents(t, resumed, 2)
+	if replayed[0].event != events.Created || !strings.Contains(replayed[0].data, `"title":"Rates"`) || replayed[1].event != events.Deleted {
+		t.Errorf("unexpected replay %+v", replayed)
+	}
+
+	// an unknown ID asks the client to reload
+	stale := openStream(t, ctx, srv.URL+"/posts/stream", "unknown-1")
+	if e := readEvents(t, stale, 1)[0]; e.event != events.Resync || e.id != replayed[1].id || e.data != "{}" {
+		t.Errorf("expected a resync at the latest event, got %+v", e)
+	}
+}
+
+func TestStreamPostsHeartbeat(t *testing.T) {
+	app := &Application{
+		Logger: logging.Discard(),
+		Events: events.NewBroker(10),
+		Stream: config.Stream{Heartbeat: 10 * time.Millisecond, ClientBuffer: 10},
+	}
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := openStream(t, ctx, srv.URL+"/posts/stream", "")
+	for stream.Scan() {
+		if stream.Text() == ": heartbeat" {
+			return
+		}
+	}
+	t.Fatalf("no heartbeat received: %v", stream.Err())
+}
+
+func TestStreamPostsDisabled(t *testing.T) {
+	app := &Application{Logger: logging.Discard()}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/posts/stream", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without the change feed, got %d", rr.Code)
+	}
+}
+
+func TestStreamPostsEndsOnClose(t *testing.T) {
+	app := &Application{
+		Logger: logging.Discard(),
+		Events: events.NewBroker(10),
+		Stream: config.Stream{Heartbeat: time.Hour, ClientBuffer: 10},
+	}
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream := openStream(t, ctx, srv.URL+"/posts/stream", "")
+	app.Events.Close()
+	for stream.Scan() {
+	}
+	if ctx.Err() != nil {
+		t.Fatal("expected the stream to end when the broker closed")
+	}
+}
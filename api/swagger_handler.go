@@ -2,8 +2,18 @@ package main
 
 import (
 	"net/http"
+
+	"github.com/freshusername/news-api/openapi"
 )
 
+// HandleSwagger serves the OpenAPI document embedded in the binary as JSON
 func (app *Application) HandleSwagger(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, "./openapi/swagger.json")
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.SpecJSON)
+}
+
+// HandleSwaggerYAML serves the OpenAPI document embedded in the binary as YAML
+func (app *Application) HandleSwaggerYAML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(openapi.SpecYAML)
 }
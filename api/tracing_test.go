@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freshusername/news-api/client"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+	"github.com/freshusername/news-api/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useInMemoryTracing records the spans of a test and restores the global provider afterwards
+func useInMemoryTracing(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	provider, exporter := tracing.NewInMemory()
+
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return exporter
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTraceRequests(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.FormatText, slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := &Application{Logger: logger, DB: &MockDatabaseRepo{
+		GetPostFunc: func(ctx context.Context, id int32) (*models.Post, error) {
+			return &models.Post{ID: int(id), Title: "Title", Content: "Content"}, nil
+		},
+	}}
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/3", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	app.routes().ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans().Snapshots()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name() != "GET /posts/{id}" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("unexpected span %q of kind %v", span.Name(), span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("span does not continue the incoming trace: %v, parent %v", span.SpanContext().TraceID(), span.Parent().SpanID())
+	}
+	if spanAttr(span, "http.route").AsString() != "/posts/{id}" || spanAttr(span, "http.response.status_code").AsInt64() != 200 {
+		t.Errorf("unexpected attributes %v", span.Attributes())
+	}
+
+	// log records of the request carry the trace ID
+	if !bytes.Contains(buf.Bytes(), []byte("trace_id=4bf92f3577b34da6a3ce929d0e0e4736")) {
+		t.Errorf("access log lacks the trace ID: %s", buf.String())
+	}
+}
+
+func TestTraceRequestsServerError(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+	app := &Application{Logger: logging.Discard(), DB: &MockDatabaseRepo{
+		GetAllPostsFunc: func(ctx context.Context) ([]*models.Post, error) {
+			panic("boom")
+		},
+	}}
+
+	app.routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts", nil))
+
+	span := exporter.GetSpans().Snapshots()[0]
+	if span.Status().Code != codes.Error || spanAttr(span, "http.response.status_code").AsInt64() != 500 {
+		t.Errorf("expected a failed span, got status %v and attributes %v", span.Status(), span.Attributes())
+	}
+}
+
+func TestClientPropagatesTrace(t *testing.T) {
+	exporter := useInMemoryTracing(t)
+	app := &Application{Logger: logging.Discard(), DB: &MockDatabaseRepo{}}
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "caller")
+	if _, err := c.ListPosts(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans().Snapshots()
+	if len(spans) != 2 || spans[0].Name() != "GET /posts" || spans[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("server span is not a child of the caller's span: %v", spans)
+	}
+}
@@ -5,12 +5,29 @@ import (
 	"errors"
 	"io"
 	"net/http"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/validation"
 )
 
-type JSONResponse struct {
-	Error   bool        `json:"error"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+const (
+	problemContentType = "application/problem+json"
+
+	// problemTypeBlank is the RFC 9457 default type for problems that carry
+	// no semantics beyond the HTTP status code.
+	problemTypeBlank = "about:blank"
+	// problemTypeValidation identifies request bodies that failed validation.
+	problemTypeValidation = "/problems/validation-error"
+)
+
+// ProblemDetails is an RFC 9457 problem details object
+type ProblemDetails struct {
+	Type     string                        `json:"type"`
+	Title    string                        `json:"title"`
+	Status   int                           `json:"status"`
+	Detail   string                        `json:"detail,omitempty"`
+	Instance string                        `json:"instance,omitempty"`
+	Errors   []*validation.ValidationError `json:"errors,omitempty"`
 }
 
 func (app *Application) writeJSON(w http.ResponseWriter, status int, data interface{}, headers ...http.Header) error {
@@ -19,13 +36,14 @@ func (app *Application) writeJSON(w http.ResponseWriter, status int, data interf
 		return err
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+
 	if len(headers) > 0 {
 		for key, value := range headers[0] {
 			w.Header()[key] = value
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_, err = w.Write(out)
 	if err != nil {
@@ -56,16 +74,63 @@ func (app *Application) readJSON(w http.ResponseWriter, r *http.Request, data in
 	return nil
 }
 
-func (app *Application) errorJSON(w http.ResponseWriter, err error, status ...int) error {
+// writeProblem writes problem as an application/problem+json response
+func (app *Application) writeProblem(w http.ResponseWriter, r *http.Request, problem ProblemDetails) error {
+	if problem.Type == "" {
+		problem.Type = problemTypeBlank
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.Instance == "" && r != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	headers := http.Header{"Content-Type": []string{problemContentType}}
+
+	return app.writeJSON(w, problem.Status, problem, headers)
+}
+
+// problemJSON writes err as a problem details response, defaulting to 400 Bad Request
+func (app *Application) problemJSON(w http.ResponseWriter, r *http.Request, err error, status ...int) error {
 	statusCode := http.StatusBadRequest
 
 	if len(status) > 0 {
 		statusCode = status[0]
 	}
 
-	var payload JSONResponse
-	payload.Error = true
-	payload.Message = err.Error()
+	return app.writeProblem(w, r, ProblemDetails{
+		Status: statusCode,
+		Detail: err.Error(),
+	})
+}
+
+// dbErrorJSON writes a repository error as a problem, 404 Not Found for a
+// missing post, 503 Service Unavailable before the database is connected and 500 otherwise
+func (app *Application) dbErrorJSON(w http.ResponseWriter, r *http.Request, err error) error {
+	if errors.Is(err, database.ErrNotFound) {
+		return app.problemJSON(w, r, err, http.StatusNotFound)
+	}
+	if errors.Is(err, database.ErrUnavailable) {
+		w.Header().Set("Retry-After", "5")
+		return app.problemJSON(w, r, err, http.StatusServiceUnavailable)
+	}
+	return app.problemJSON(w, r, err, http.StatusInternalServerError)
+}
 
-	return app.writeJSON(w, statusCode, payload)
+// validationProblemJSON writes a 400 problem details response listing every
+// field violation, with messages in the language requested by Accept-Language
+func (app *Application) validationProblemJSON(w http.ResponseWriter, r *http.Request, errs []*validation.ValidationError) error {
+	locale := requestLocale(r)
+	validation.DefaultCatalog.Localize(locale, errs)
+	w.Header().Set("Content-Language", locale)
+	w.Header().Add("Vary", "Accept-Language")
+
+	return app.writeProblem(w, r, ProblemDetails{
+		Type:   problemTypeValidation,
+		Title:  "Your request did not pass validation",
+		Status: http.StatusBadRequest,
+		Detail: "One or more fields are invalid.",
+		Errors: errs,
+	})
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/freshusername/news-api/validation"
 )
 
 func TestWriteJSON(t *testing.T) {
@@ -75,29 +78,143 @@ func TestReadJSON(t *testing.T) {
 	}
 }
 
-func TestErrorJSON(t *testing.T) {
+func TestProblemJSON(t *testing.T) {
 	// Create an instance of your Application struct with necessary fields initialized.
 	app := &Application{}
 
 	// Create a ResponseRecorder to record the response.
 	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/posts/1", nil)
 
-	// Call errorJSON with a sample error.
+	// Call problemJSON with a sample error.
 	testError := errors.New("this is a test error")
-	err := app.errorJSON(rr, testError)
+	err := app.problemJSON(rr, req, testError)
 	if err != nil {
-		t.Errorf("errorJSON returned an error: %v", err)
+		t.Errorf("problemJSON returned an error: %v", err)
 	}
 
 	// Check the status code is what we expect.
 	expectedStatusCode := http.StatusBadRequest
 	if status := rr.Code; status != expectedStatusCode {
-		t.Errorf("errorJSON returned wrong status code: got %v want %v", status, expectedStatusCode)
+		t.Errorf("problemJSON returned wrong status code: got %v want %v", status, expectedStatusCode)
+	}
+
+	// Check the response content type is problem+json.
+	expectedContentType := "application/problem+json"
+	if contentType := rr.Header().Get("Content-Type"); contentType != expectedContentType {
+		t.Errorf("problemJSON returned wrong Content-Type header: got %v want %v", contentType, expectedContentType)
 	}
 
 	// Check the response body is what we expect.
-	expectedBody := `{"error":true,"message":"this is a test error"}`
+	expectedBody := `{"type":"about:blank","title":"Bad Request","status":400,"detail":"this is a test error","instance":"/posts/1"}`
 	if rr.Body.String() != expectedBody {
-		t.Errorf("errorJSON returned unexpected body: got %v want %v", rr.Body.String(), expectedBody)
+		t.Errorf("problemJSON returned unexpected body: got %v want %v", rr.Body.String(), expectedBody)
+	}
+}
+
+func TestUnknownRouteProblems(t *testing.T) {
+	app := &Application{DB: &MockDatabaseRepo{}}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	if rr.Code != http.StatusNotFound || rr.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("expected a 404 problem, got %d %q: %s", rr.Code, rr.Header().Get("Content-Type"), rr.Body)
+	}
+
+	rr = httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodPatch, "/posts/1", nil))
+	if rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Content-Type") != "application/problem+json" {
+		t.Errorf("expected a 405 problem, got %d %q: %s", rr.Code, rr.Header().Get("Content-Type"), rr.Body)
+	}
+	if allow := strings.Join(rr.Header().Values("Allow"), ", "); allow != "GET, PUT, DELETE" {
+		t.Errorf("expected the methods of /posts/{id} in Allow, got %q", allow)
+	}
+}
+
+func TestValidationProblemJSON(t *testing.T) {
+	app := &Application{}
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/posts", nil)
+
+	errs := []*validation.ValidationError{
+		{Field: "Title", Error: "is required"},
+		{Field: "Content", Error: "must be between 1 and 500 characters"},
+	}
+
+	err := app.validationProblemJSON(rr, req, errs)
+	if err != nil {
+		t.Fatalf("validationProblemJSON returned an error: %v", err)
+	}
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("validationProblemJSON returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	var problem ProblemDetails
+	if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+
+	if problem.Type != problemTypeValidation {
+		t.Errorf("unexpected problem type: got %v want %v", problem.Type, problemTypeValidation)
+	}
+	if len(problem.Errors) != len(errs) {
+		t.Fatalf("unexpected number of field errors: got %d want %d", len(problem.Errors), len(errs))
+	}
+	if problem.Errors[0].Field != "Title" || problem.Errors[0].Error != "is required" {
+		t.Errorf("unexpected first field error: got %+v", problem.Errors[0])
+	}
+}
+
+func TestValidationProblemJSONLocalized(t *testing.T) {
+	app := &Application{}
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/posts", nil)
+	req.Header.Set("Accept-Language", "de-DE, uk-UA;q=0.9, en;q=0.5")
+
+	errs := validation.NewValidator().Validate(struct {
+		Title string `json:"title" validate:"required,len=1..255"`
+	}{})
+
+	err := app.validationProblemJSON(rr, req, errs)
+	if err != nil {
+		t.Fatalf("validationProblemJSON returned an error: %v", err)
+	}
+
+	if lang := rr.Header().Get("Content-Language"); lang != "uk" {
+		t.Errorf("unexpected Content-Language: got %v want %v", lang, "uk")
+	}
+
+	var problem ProblemDetails
+	if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+		t.Fatalf("Could not decode response: %v", err)
+	}
+
+	if len(problem.Errors) != 1 {
+		t.Fatalf("unexpected number of field errors: got %d want 1", len(problem.Errors))
+	}
+	got := problem.Errors[0]
+	if got.Field != "title" || got.Code != validation.CodeRequired || got.Error != "є обов'язковим" {
+		t.Errorf("unexpected field error: %+v", got)
+	}
+}
+
+func TestRequestLocale(t *testing.T) {
+	tests := map[string]string{
+		"":                  "en",
+		"uk":                "uk",
+		"ru, uk;q=0.8":      "uk",
+		"en-GB,en;q=0.9":    "en",
+		"fr-FR":             "en",
+		"not a language!!!": "en",
+	}
+
+	for header, want := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Language", header)
+
+		if got := requestLocale(req); got != want {
+			t.Errorf("requestLocale(%q) = %v, want %v", header, got, want)
+		}
 	}
 }
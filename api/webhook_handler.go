@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/freshusername/news-api/outbox"
+	"github.com/freshusername/news-api/validation"
+	"github.com/freshusername/news-api/webhooks"
+	"github.com/go-chi/chi/v5"
+)
+
+// Paging bounds of HandleListDeliveries
+const (
+	maxDeliveries     = 100
+	defaultDeliveries = 20
+)
+
+// HandleListWebhooks lists the webhooks, without their secrets
+func (app *Application) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := app.Webhooks.ListWebhooks(r.Context())
+	if err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		hook.Secret = ""
+	}
+	_ = app.writeJSON(w, http.StatusOK, hooks)
+}
+
+// HandleCreateWebhook registers a webhook, generating its secret unless one
+// is given. The response is the only one carrying the secret.
+func (app *Application) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := app.readWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	if hook.Secret == "" {
+		secret, err := webhooks.NewSecret()
+		if err != nil {
+			app.problemJSON(w, r, err, http.StatusInternalServerError)
+			return
+		}
+		hook.Secret = secret
+	}
+
+	created, err := app.Webhooks.CreateWebhook(r.Context(), hook)
+	if err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+	_ = app.writeJSON(w, http.StatusCreated, created)
+}
+
+// HandleGetWebhook retrieves a webhook by ID, without its secret
+func (app *Application) HandleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookID(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	hook, err := app.Webhooks.GetWebhook(r.Context(), id)
+	if err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+	hook.Secret = ""
+	_ = app.writeJSON(w, http.StatusOK, hook)
+}
+
+// HandleUpdateWebhook replaces a webhook. Its secret is kept unless a new one
+// is given, which is then returned once.
+func (app *Application) HandleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookID(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+	hook, ok := app.readWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := app.Webhooks.UpdateWebhook(r.Context(), id, hook)
+	if err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+	if hook.Secret == "" {
+		updated.Secret = ""
+	}
+	_ = app.writeJSON(w, http.StatusOK, updated)
+}
+
+// HandleDeleteWebhook deletes a webhook along with its deliveries
+func (app *Application) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookID(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := app.Webhooks.DeleteWebhook(r.Context(), id); err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListDeliveries lists the newest deliveries of a webhook, optionally
+// only those with the status query parameter
+func (app *Application) HandleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := webhookID(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	status := query.Get("status")
+	switch status {
+	case "", webhooks.StatusPending, webhooks.StatusDelivered, webhooks.StatusDead:
+	default:
+		app.problemJSON(w, r, errors.New("status must be pending, delivered or dead"), http.StatusBadRequest)
+		return
+	}
+	limit := defaultDeliveries
+	if query.Has("limit") {
+		limit, err = strconv.Atoi(query.Get("limit"))
+		if err != nil || limit < 1 || limit > maxDeliveries {
+			app.problemJSON(w, r, fmt.Errorf("limit must be an integer between 1 and %d", maxDeliveries), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// an unknown webhook is a 404 rather than an empty list
+	if _, err := app.Webhooks.GetWebhook(r.Context(), id); err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+	deliveries, err := app.Webhooks.ListDeliveries(r.Context(), id, status, limit)
+	if err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+	_ = app.writeJSON(w, http.StatusOK, deliveries)
+}
+
+// HandleGetDelivery retrieves a delivery with the log of its attempts
+func (app *Application) HandleGetDelivery(w http.ResponseWriter, r *http.Request) {
+	hookID, id, err := deliveryID(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := app.Webhooks.GetDelivery(r.Context(), hookID, id)
+	if err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+	_ = app.writeJSON(w, http.StatusOK, delivery)
+}
+
+// HandleRedeliver queues a delivery, whatever its status, for an immediate
+// attempt with a fresh allowance of retries
+func (app *Application) HandleRedeliver(w http.ResponseWriter, r *http.Request) {
+	hookID, id, err := deliveryID(r)
+	if err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := app.Webhooks.Redeliver(r.Context(), hookID, id)
+	if err != nil {
+		app.webhookErrorJSON(w, r, err)
+		return
+	}
+	_ = app.writeJSON(w, http.StatusAccepted, delivery)
+}
+
+// readWebhook decodes and validates a webhook from the request body,
+// answering the request itself when it is invalid
+func (app *Application) readWebhook(w http.ResponseWriter, r *http.Request) (*webhooks.Webhook, bool) {
+	hook := new(webhooks.Webhook)
+	if err := app.readJSON(w, r, hook); err != nil {
+		app.problemJSON(w, r, err, http.StatusBadRequest)
+		return nil, false
+	}
+	if errs := validation.NewValidator().Validate(hook); len(errs) > 0 {
+		app.validationProblemJSON(w, r, errs)
+		return nil, false
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	return hook, true
+}
+
+// webhookErrorJSON writes a webhook store error as a problem, 404 Not Found
+// for a missing webhook or delivery
+func (app *Application) webhookErrorJSON(w http.ResponseWriter, r *http.Request, err error) error {
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return app.problemJSON(w, r, err, http.StatusNotFound)
+	}
+	return app.dbErrorJSON(w, r, err)
+}
+
+// webhookID extracts the webhook ID from the URL
+func webhookID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		return 0, errors.New("invalid webhook id format")
+	}
+	return int32(id), nil
+}
+
+// deliveryID extracts the webhook and delivery IDs from the URL
+func deliveryID(r *http.Request) (int32, int64, error) {
+	hookID, err := webhookID(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid delivery id format")
+	}
+	return hookID, id, nil
+}
+
+// enqueueWebhooks queues a post event relayed from the outbox for the
+// subscribed webhooks. An event relayed again is not queued twice.
+func (app *Application) enqueueWebhooks(ctx context.Context, e outbox.Event) error {
+	_, err := app.Webhooks.Enqueue(ctx, webhooks.Payload{
+		EventID:    e.ID,
+		Event:      e.Type,
+		OccurredAt: e.OccurredAt,
+		Post:       e.Post,
+	})
+	return err
+}
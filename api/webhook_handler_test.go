@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+	"github.com/freshusername/news-api/outbox"
+	"github.com/freshusername/news-api/webhooks"
+)
+
+func webhookApp(store *MockWebhookStore) *Application {
+	return &Application{
+		Logger:   logging.Discard(),
+		DB:       &MockDatabaseRepo{},
+		Webhooks: store,
+	}
+}
+
+func serveWebhooks(app *Application, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCreateWebhookGeneratesSecret(t *testing.T) {
+	var stored *webhooks.Webhook
+	app := webhookApp(&MockWebhookStore{
+		CreateWebhookFunc: func(ctx context.Context, hook *webhooks.Webhook) (*webhooks.Webhook, error) {
+			stored = hook
+			created := *hook
+			created.ID = 1
+			return &created, nil
+		},
+	})
+
+	rr := serveWebhooks(app, http.MethodPost, "/webhooks", `{"url":"https://search.example/hooks","events":["post.created"]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body)
+	}
+	var created webhooks.Webhook
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(stored.Secret, "whsec_") || created.Secret != stored.Secret {
+		t.Errorf("expected a generated secret returned once, stored %q, returned %q", stored.Secret, created.Secret)
+	}
+}
+
+func TestCreateWebhookValidates(t *testing.T) {
+	app := webhookApp(&MockWebhookStore{})
+	for name, body := range map[string]string{
+		"missing url":   `{"events":[]}`,
+		"ftp url":       `{"url":"ftp://search.example/hooks"}`,
+		"unknown event": `{"url":"https://search.example/hooks","events":["post.read"]}`,
+		"unknown field": `{"url":"https://search.example/hooks","filter":"sports"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if rr := serveWebhooks(app, http.MethodPost, "/webhooks", body); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", rr.Code, rr.Body)
+			}
+		})
+	}
+}
+
+func TestWebhookSecretsAreNotRead(t *testing.T) {
+	hook := func() *webhooks.Webhook {
+		return &webhooks.Webhook{ID: 1, URL: "https://search.example/hooks", Secret: "whsec_kept", Events: []string{}}
+	}
+	app := webhookApp(&MockWebhookStore{
+		ListWebhooksFunc: func(ctx context.Context) ([]*webhooks.Webhook, error) {
+			return []*webhooks.Webhook{hook()}, nil
+		},
+		GetWebhookFunc: func(ctx context.Context, id int32) (*webhooks.Webhook, error) {
+			return hook(), nil
+		},
+		UpdateWebhookFunc: func(ctx context.Context, id int32, update *webhooks.Webhook) (*webhooks.Webhook, error) {
+			return hook(), nil
+		},
+	})
+
+	for _, rr := range []*httptest.ResponseRecorder{
+		serveWebhooks(app, http.MethodGet, "/webhooks", ""),
+		serveWebhooks(app, http.MethodGet, "/webhooks/1", ""),
+		serveWebhooks(app, http.MethodPut, "/webhooks/1", `{"url":"https://search.example/hooks","paused":true}`),
+	} {
+		if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "whsec_") {
+			t.Errorf("expected 200 without the secret, got %d: %s", rr.Code, rr.Body)
+		}
+	}
+}
+
+func TestWebhookNotFound(t *testing.T) {
+	app := webhookApp(&MockWebhookStore{})
+	for _, path := range []string{"/webhooks/7", "/webhooks/7/deliveries", "/webhooks/7/deliveries/3"} {
+		if rr := serveWebhooks(app, http.MethodGet, path, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d: %s", path, rr.Code, rr.Body)
+		}
+	}
+	if rr := serveWebhooks(app, http.MethodDelete, "/webhooks/7", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting a missing webhook, got %d", rr.Code)
+	}
+}
+
+func TestWebhookStoreUnavailable(t *testing.T) {
+	app := webhookApp(&MockWebhookStore{
+		ListWebhooksFunc: func(ctx context.Context) ([]*webhooks.Webhook, error) {
+			return nil, database.ErrUnavailable
+		},
+	})
+	rr := serveWebhooks(app, http.MethodGet, "/webhooks", "")
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 503 with Retry-After, got %d %v", rr.Code, rr.Header())
+	}
+}
+
+func TestListDeliveries(t *testing.T) {
+	var gotStatus string
+	var gotLimit int
+	app := webhookApp(&MockWebhookStore{
+		GetWebhookFunc: func(ctx context.Context, id int32) (*webhooks.Webhook, error) {
+			return &webhooks.Webhook{ID: id}, nil
+		},
+		ListDeliveriesFunc: func(ctx context.Context, webhookID int32, status string, limit int) ([]*webhooks.Delivery, error) {
+			gotStatus, gotLimit = status, limit
+			return []*webhooks.Delivery{{ID: 3, WebhookID: webhookID, Status: status}}, nil
+		},
+	})
+
+	rr := serveWebhooks(app, http.MethodGet, "/webhooks/1/deliveries?status=dead&limit=5", "")
+	if rr.Code != http.StatusOK || gotStatus != webhooks.StatusDead || gotLimit != 5 {
+		t.Fatalf("expected dead deliveries limited to 5, got %d with %q and %d: %s", rr.Code, gotStatus, gotLimit, rr.Body)
+	}
+	rr = serveWebhooks(app, http.MethodGet, "/webhooks/1/deliveries", "")
+	if rr.Code != http.StatusOK || gotStatus != "" || gotLimit != defaultDeliveries {
+		t.Errorf("expected the default limit for every status, got %d with %q and %d", rr.Code, gotStatus, gotLimit)
+	}
+
+	for _, query := range []string{"status=failed", "limit=0", "limit=101"} {
+		if rr := serveWebhooks(app, http.MethodGet, "/webhooks/1/deliveries?"+query, ""); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	app := webhookApp(&MockWebhookStore{
+		RedeliverFunc: func(ctx context.Context, webhookID int32, id int64) (*webhooks.Delivery, error) {
+			next := time.Now()
+			return &webhooks.Delivery{ID: id, WebhookID: webhookID, Status: webhooks.StatusPending, NextAttemptAt: &next}, nil
+		},
+	})
+
+	rr := serveWebhooks(app, http.MethodPost, "/webhooks/1/deliveries/3/redeliver", "")
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body)
+	}
+	var delivery webhooks.Delivery
+	if err := json.Unmarshal(rr.Body.Bytes(), &delivery); err != nil {
+		t.Fatal(err)
+	}
+	if delivery.ID != 3 || delivery.Status != webhooks.StatusPending {
+		t.Errorf("expected delivery 3 pending again, got %+v", delivery)
+	}
+}
+
+func TestOutboxBusEnqueuesWebhooks(t *testing.T) {
+	var queued []webhooks.Payload
+	app := webhookApp(&MockWebhookStore{
+		EnqueueFunc: func(ctx context.Context, payload webhooks.Payload) (int, error) {
+			queued = append(queued, payload)
+			return 1, nil
+		},
+	})
+	sinks, closeSinks, err := app.outboxSinks(config.Outbox{Sinks: []string{outbox.SinkBus}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeSinks()
+
+	events := []outbox.Event{
+		{ID: 7, Type: webhooks.EventPostCreated, Post: &models.Post{ID: 1}, OccurredAt: time.Now()},
+		{ID: 8, Type: webhooks.EventPostDeleted, Post: &models.Post{ID: 1}, OccurredAt: time.Now()},
+	}
+	if n, err := outbox.Publish(context.Background(), sinks, events); n != 2 || err != nil {
+		t.Fatalf("expected both events published, got %d, %v", n, err)
+	}
+	if len(queued) != 2 {
+		t.Fatalf("expected 2 events queued, got %+v", queued)
+	}
+	for i, payload := range queued {
+		e := events[i]
+		if payload.EventID != e.ID || payload.Event != e.Type || payload.Post != e.Post {
+			t.Errorf("event %d: expected %s %d, got %+v", i, e.Type, e.ID, payload)
+		}
+	}
+}
+
+func TestEnqueueFailureIsRelayedAgain(t *testing.T) {
+	app := webhookApp(&MockWebhookStore{
+		EnqueueFunc: func(ctx context.Context, payload webhooks.Payload) (int, error) {
+			return 0, errors.New("queue down")
+		},
+	})
+	sinks, closeSinks, err := app.outboxSinks(config.Outbox{Sinks: []string{outbox.SinkBus}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeSinks()
+
+	// an unpublished event stays in the outbox, to be relayed again
+	events := []outbox.Event{{ID: 1, Type: webhooks.EventPostCreated, Post: &models.Post{ID: 1}}}
+	if n, err := outbox.Publish(context.Background(), sinks, events); n != 0 || err == nil {
+		t.Errorf("expected the event left unpublished, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freshusername/news-api/events"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/models"
+	"github.com/gorilla/websocket"
+)
+
+// Message types of the /ws protocol. Clients send subscribe, unsubscribe and
+// ping; the server answers with subscribed, unsubscribed, pong or error, and
+// pushes an event message for every change of a subscribed topic.
+const (
+	wsSubscribe    = "subscribe"
+	wsUnsubscribe  = "unsubscribe"
+	wsPing         = "ping"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsPong         = "pong"
+	wsEvent        = "event"
+	wsError        = "error"
+)
+
+// Topics of the /ws protocol
+const (
+	// topicPosts subscribes to every post
+	topicPosts = "posts"
+	// topicPostPrefix subscribes to one post, e.g. post:42
+	topicPostPrefix = "post:"
+	// topicCategoryPrefix subscribes to the posts of a category, e.g. category:sports
+	topicCategoryPrefix = "category:"
+)
+
+// categoryPattern matches the category names allowed by models.Post
+var categoryPattern = regexp.MustCompile(`^[a-z0-9-]{1,50}$`)
+
+// wsMessage is a message of the /ws protocol, in either direction
+type wsMessage struct {
+	Type string `json:"type"`
+	// ID is chosen by the client and echoed in the reply to its message
+	ID    string `json:"id,omitempty"`
+	Topic string `json:"topic,omitempty"`
+	// Event, EventID and Post describe a change pushed to the client
+	Event   string       `json:"event,omitempty"`
+	EventID string       `json:"event_id,omitempty"`
+	Post    *models.Post `json:"post,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// parseTopic returns the canonical form of a topic
+func parseTopic(topic string) (string, error) {
+	switch {
+	case topic == topicPosts:
+		return topic, nil
+	case strings.HasPrefix(topic, topicPostPrefix):
+		id, err := strconv.ParseInt(strings.TrimPrefix(topic, topicPostPrefix), 10, 32)
+		if err == nil && id > 0 {
+			return topicPostPrefix + strconv.FormatInt(id, 10), nil
+		}
+	case strings.HasPrefix(topic, topicCategoryPrefix):
+		if categoryPattern.MatchString(strings.TrimPrefix(topic, topicCategoryPrefix)) {
+			return topic, nil
+		}
+	}
+	return "", fmt.Errorf("unknown topic %q, expected posts, post:<id> or category:<name>", topic)
+}
+
+// wsTopics are the topics a connection subscribed to
+type wsTopics struct {
+	mu     sync.Mutex
+	topics map[string]bool
+}
+
+// matches reports whether e belongs to any subscribed topic
+func (t *wsTopics) matches(e events.Event) bool {
+	if e.Post == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.topics[topicPosts] ||
+		t.topics[topicPostPrefix+strconv.Itoa(e.Post.ID)] ||
+		(e.Post.Category != "" && t.topics[topicCategoryPrefix+e.Post.Category])
+}
+
+// add subscribes to topic, unless max topics are subscribed already
+func (t *wsTopics) add(topic string, max int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.topics[topic] && len(t.topics) >= max {
+		return fmt.Errorf("at most %d topics can be subscribed", max)
+	}
+	t.topics[topic] = true
+	return nil
+}
+
+func (t *wsTopics) remove(topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.topics, topic)
+}
+
+// HandleWebSocket upgrades to a WebSocket speaking the /ws protocol. Each
+// connection has its own topics, shares the change feed with the event
+// stream, and is closed when it falls behind the events, sends messages
+// faster than its replies are written, or stops answering pings.
+func (app *Application) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if app.Events == nil {
+		app.problemJSON(w, r, errors.New("live updates are disabled"), http.StatusServiceUnavailable)
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: app.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade answered with an error already
+		return
+	}
+	defer conn.Close()
+
+	cfg := app.WebSocket
+	logger := logging.FromContext(r.Context())
+	topics := &wsTopics{topics: make(map[string]bool)}
+	sub, _, _ := app.Events.Subscribe("", cfg.SendBuffer, topics.matches)
+	defer sub.Close()
+
+	replies := make(chan wsMessage, cfg.SendBuffer)
+	done := make(chan struct{})
+	var writer sync.WaitGroup
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+		app.writeWebSocket(conn, sub, replies, done)
+	}()
+	defer func() {
+		close(done)
+		writer.Wait()
+	}()
+
+	conn.SetReadLimit(int64(cfg.MaxMessageBytes))
+	extendDeadline := func() {
+		conn.SetReadDeadline(time.Now().Add(cfg.PingInterval + cfg.PongTimeout))
+	}
+	extendDeadline()
+	conn.SetPongHandler(func(string) error {
+		extendDeadline()
+		return nil
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				closeWebSocket(conn, websocket.CloseMessageTooBig, "message too big")
+			}
+			return
+		}
+		extendDeadline()
+
+		reply := app.handleWebSocketMessage(data, topics)
+		select {
+		case replies <- reply:
+		default:
+			logger.WarnContext(r.Context(), "closing a WebSocket sending faster than it reads")
+			closeWebSocket(conn, websocket.ClosePolicyViolation, "too many messages")
+			return
+		}
+	}
+}
+
+// handleWebSocketMessage applies a client message and returns the reply
+func (app *Application) handleWebSocketMessage(data []byte, topics *wsTopics) wsMessage {
+	var msg wsMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return wsMessage{Type: wsError, Error: "invalid JSON: " + err.Error()}
+	}
+	reply := wsMessage{ID: msg.ID, Topic: msg.Topic}
+
+	switch msg.Type {
+	case wsPing:
+		reply.Type = wsPong
+		return reply
+	case wsSubscribe, wsUnsubscribe:
+	default:
+		reply.Type = wsError
+		reply.Error = fmt.Sprintf("unknown message type %q, expected subscribe, unsubscribe or ping", msg.Type)
+		return reply
+	}
+
+	topic, err := parseTopic(msg.Topic)
+	if err != nil {
+		reply.Type = wsError
+		reply.Error = err.Error()
+		return reply
+	}
+	reply.Topic = topic
+
+	if msg.Type == wsUnsubscribe {
+		topics.remove(topic)
+		reply.Type = wsUnsubscribed
+		return reply
+	}
+	if err := topics.add(topic, app.WebSocket.MaxSubscriptions); err != nil {
+		reply.Type = wsError
+		reply.Error = err.Error()
+		return reply
+	}
+	reply.Type = wsSubscribed
+	return reply
+}
+
+// writeWebSocket is the only writer of conn besides close frames. It writes
+// replies and events and pings the client until done is closed, or closes
+// the connection when the events end or a write fails.
+func (app *Application) writeWebSocket(conn *websocket.Conn, sub *events.Subscription, replies <-chan wsMessage, done <-chan struct{}) {
+	ping := time.NewTicker(app.WebSocket.PingInterval)
+	defer ping.Stop()
+
+	write := func(msg wsMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteJSON(msg)
+	}
+
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case msg := <-replies:
+			err = write(msg)
+		case e, open := <-sub.Events():
+			if !open {
+				if errors.Is(sub.Err(), events.ErrSlowConsumer) {
+					closeWebSocket(conn, websocket.CloseTryAgainLater, "too slow, events dropped")
+				} else {
+					closeWebSocket(conn, websocket.CloseGoingAway, "shutting down")
+				}
+				conn.Close()
+				return
+			}
+			err = write(wsMessage{Type: wsEvent, Event: e.Type, EventID: e.ID, Post: e.Post})
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout))
+		}
+		if err != nil {
+			conn.Close()
+			return
+		}
+	}
+}
+
+// closeWebSocket sends a close frame, ignoring a connection already gone
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+}
+
+// checkOrigin accepts WebSocket handshakes without an Origin, as sent by
+// mobile apps, from the API's own origin, and from origins allowed by CORS
+func (app *Application) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range app.CORS.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/config"
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/events"
+	"github.com/freshusername/news-api/logging"
+	"github.com/freshusername/news-api/metrics"
+	"github.com/freshusername/news-api/models"
+	"github.com/freshusername/news-api/outbox"
+	"github.com/gorilla/websocket"
+)
+
+func newWebSocketApp() *Application {
+	return &Application{
+		Logger: logging.Discard(),
+		// the middleware wrapping the response must still allow hijacking it
+		Metrics: metrics.New(),
+		Events:  events.NewBroker(10),
+		WebSocket: config.WebSocket{
+			PingInterval:     time.Minute,
+			PongTimeout:      time.Minute,
+			MaxMessageBytes:  1024,
+			MaxSubscriptions: 2,
+			SendBuffer:       10,
+		},
+	}
+}
+
+// dialWebSocket connects to /ws of srv
+func dialWebSocket(t *testing.T, srv *httptest.Server, header http.Header) *websocket.Conn {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", header)
+	if err != nil {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		t.Fatalf("dialing failed with status %d: %v", status, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// roundTrip sends msg and returns the next message received
+func roundTrip(t *testing.T, conn *websocket.Conn, msg wsMessage) wsMessage {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+	var reply wsMessage
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	app := newWebSocketApp()
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+	conn := dialWebSocket(t, srv, nil)
+
+	if reply := roundTrip(t, conn, wsMessage{Type: wsPing, ID: "1"}); reply.Type != wsPong || reply.ID != "1" {
+		t.Errorf("expected a pong, got %+v", reply)
+	}
+	if reply := roundTrip(t, conn, wsMessage{Type: wsSubscribe, ID: "2", Topic: "post:007"}); reply.Type != wsSubscribed || reply.Topic != "post:7" {
+		t.Errorf("expected a subscription to post:7, got %+v", reply)
+	}
+	if reply := roundTrip(t, conn, wsMessage{Type: wsSubscribe, Topic: "category:sports"}); reply.Type != wsSubscribed {
+		t.Errorf("expected a subscription, got %+v", reply)
+	}
+	if reply := roundTrip(t, conn, wsMessage{Type: wsSubscribe, Topic: "posts"}); reply.Type != wsError || !strings.Contains(reply.Error, "at most 2") {
+		t.Errorf("expected the subscription limit, got %+v", reply)
+	}
+	for _, topic := range []string{"post:abc", "category:Sports", "everything"} {
+		if reply := roundTrip(t, conn, wsMessage{Type: wsSubscribe, Topic: topic}); reply.Type != wsError {
+			t.Errorf("expected an error for topic %q, got %+v", topic, reply)
+		}
+	}
+	if reply := roundTrip(t, conn, wsMessage{Type: "publish"}); reply.Type != wsError {
+		t.Errorf("expected an error for an unknown type, got %+v", reply)
+	}
+
+	app.streamEvent(outbox.Event{Type: database.EventPostCreated, Post: &models.Post{ID: 1, Title: "Rates", Category: "finance"}})
+	app.streamEvent(outbox.Event{Type: database.EventPostUpdated, Post: &models.Post{ID: 7, Title: "Seven"}})
+	app.streamEvent(outbox.Event{Type: database.EventPostCreated, Post: &models.Post{ID: 8, Title: "Goal", Category: "sports"}})
+
+	var got []wsMessage
+	for range 2 {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, msg)
+	}
+	if got[0].Type != wsEvent || got[0].Event != events.Updated || got[0].Post.ID != 7 || got[0].EventID == "" {
+		t.Errorf("expected the update of post 7, got %+v", got[0])
+	}
+	if got[1].Event != events.Created || got[1].Post.ID != 8 {
+		t.Errorf("expected the sports post, got %+v", got[1])
+	}
+
+	if reply := roundTrip(t, conn, wsMessage{Type: wsUnsubscribe, Topic: "post:7"}); reply.Type != wsUnsubscribed {
+		t.Errorf("expected to unsubscribe, got %+v", reply)
+	}
+	app.streamEvent(outbox.Event{Type: database.EventPostDeleted, Post: &models.Post{ID: 7, Title: "Seven"}})
+	if reply := roundTrip(t, conn, wsMessage{Type: wsPing}); reply.Type != wsPong {
+		t.Errorf("expected no event after unsubscribing, got %+v", reply)
+	}
+}
+
+func TestWebSocketSlowConsumer(t *testing.T) {
+	app := newWebSocketApp()
+	app.WebSocket.SendBuffer = 1
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+	conn := dialWebSocket(t, srv, nil)
+
+	if reply := roundTrip(t, conn, wsMessage{Type: wsSubscribe, Topic: "posts"}); reply.Type != wsSubscribed {
+		t.Fatalf("expected a subscription, got %+v", reply)
+	}
+	// publish faster than the writer can drain a queue of one
+	for i := 1; i <= 1000; i++ {
+		app.Events.Publish(events.Event{Type: events.Created, Post: &models.Post{ID: i}})
+	}
+
+	for {
+		var msg wsMessage
+		err := conn.ReadJSON(&msg)
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) {
+			if closeErr.Code != websocket.CloseTryAgainLater {
+				t.Errorf("expected close code %d, got %d", websocket.CloseTryAgainLater, closeErr.Code)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("expected a close frame, got %v", err)
+		}
+	}
+}
+
+func TestWebSocketMessageTooBig(t *testing.T) {
+	srv := httptest.NewServer(newWebSocketApp().routes())
+	defer srv.Close()
+	conn := dialWebSocket(t, srv, nil)
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping","id":"`+strings.Repeat("x", 2048)+`"}`))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("expected the connection to be closed as too big, got %v", err)
+	}
+}
+
+func TestWebSocketAuth(t *testing.T) {
+	app := newWebSocketApp()
+	// reads are open, but connecting still needs a token
+	app.Auth = config.Auth{Tokens: map[string]string{"mobile": "0123456789abcdef"}}
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	for _, target := range []string{url, url + "?access_token=wrong"} {
+		_, resp, err := websocket.DefaultDialer.Dial(target, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 connecting to %s, got %v", target, err)
+		}
+	}
+
+	conn := dialWebSocket(t, srv, http.Header{"Authorization": {"Bearer 0123456789abcdef"}})
+	if reply := roundTrip(t, conn, wsMessage{Type: wsPing}); reply.Type != wsPong {
+		t.Errorf("expected a pong, got %+v", reply)
+	}
+
+	// browsers pass the token in the query
+	conn, resp, err := websocket.DefaultDialer.Dial(url+"?access_token=0123456789abcdef", nil)
+	if err != nil {
+		t.Fatalf("expected the query token to be accepted, got %v", err)
+	}
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected 101, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	app := newWebSocketApp()
+	app.CORS = config.CORS{AllowedOrigins: []string{"https://news.example"}}
+	srv := httptest.NewServer(app.routes())
+	defer srv.Close()
+
+	dialWebSocket(t, srv, http.Header{"Origin": {"https://news.example"}})
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", http.Header{"Origin": {"https://evil.example"}})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for another origin, got %v", err)
+	}
+}
+
+func TestWebSocketDisabled(t *testing.T) {
+	app := &Application{Logger: logging.Discard()}
+
+	rr := httptest.NewRecorder()
+	app.routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without the change feed, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size-bounded cache whose entries expire. Past its TTL an entry is
+// stale: it is still returned, marked so, until the stale window passes too.
+// Once full, adding evicts the least recently used entry. It is safe for
+// concurrent use.
+type LRU[K comparable, V any] struct {
+	maxEntries int
+	ttl        time.Duration
+	stale      time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[K]*list.Element
+}
+
+type entry[K comparable, V any] struct {
+	key        K
+	value      V
+	stored     time.Time
+	freshUntil time.Time
+	staleUntil time.Time
+}
+
+// NewLRU returns a cache holding at most maxEntries entries, fresh for ttl
+// and served stale for another stale
+func NewLRU[K comparable, V any](maxEntries int, ttl, stale time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		stale:      stale,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[K]*list.Element),
+	}
+}
+
+// Get returns the value of key and when it was stored. fresh is false when
+// the entry is past its TTL but within the stale window; ok is false when
+// there is no entry or it expired for good.
+func (c *LRU[K, V]) Get(key K) (value V, stored time.Time, fresh, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		return value, stored, false, false
+	}
+	e := elem.Value.(*entry[K, V])
+
+	now := c.now()
+	if !now.Before(e.staleUntil) {
+		c.removeElement(elem)
+		return value, stored, false, false
+	}
+	c.order.MoveToFront(elem)
+	return e.value, e.stored, now.Before(e.freshUntil), true
+}
+
+// Add stores value under key, evicting the least recently used entry when full
+func (c *LRU[K, V]) Add(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	e := &entry[K, V]{key: key, value: value, stored: now, freshUntil: now.Add(c.ttl), staleUntil: now.Add(c.ttl + c.stale)}
+	if elem, found := c.entries[key]; found {
+		elem.Value = e
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(e)
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// Remove drops the entries whose key matches
+func (c *LRU[K, V]) Remove(match func(K) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.entries {
+		if match(key) {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// Len returns the number of entries, including stale ones
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*entry[K, V]).key)
+}
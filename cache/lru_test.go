@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	c := NewLRU[string, int](10, time.Second, 2*time.Second)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", 1)
+	if v, stored, fresh, ok := c.Get("a"); !ok || !fresh || v != 1 || !stored.Equal(now) {
+		t.Fatalf("expected a fresh entry, got %v %v %v %v", v, stored, fresh, ok)
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if v, _, fresh, ok := c.Get("a"); !ok || fresh || v != 1 {
+		t.Errorf("expected a stale entry, got %v %v %v", v, fresh, ok)
+	}
+
+	now = now.Add(2 * time.Second)
+	if _, _, _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("expected the entry to expire after the stale window")
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := NewLRU[string, int](2, time.Minute, 0)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Get("a") // b is now the least recently used
+	c.Add("c", 3)
+
+	if _, _, _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, _, _, ok := c.Get(k); !ok {
+			t.Errorf("expected %s to be kept", k)
+		}
+	}
+
+	c.Remove(func(k string) bool { return k == "a" })
+	if _, _, _, ok := c.Get("a"); ok || c.Len() != 1 {
+		t.Error("expected a to be removed")
+	}
+}
@@ -0,0 +1,228 @@
+// Package cache keeps recently read posts in memory in front of any
+// database.DatabaseRepo.
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/models"
+	"golang.org/x/sync/singleflight"
+)
+
+// Options bound the cache
+type Options struct {
+	// MaxEntries bounds the number of cached reads, the least recently used being evicted first
+	MaxEntries int
+	// TTL is how long a read is served from the cache without asking the repository
+	TTL time.Duration
+	// StaleWhileRevalidate is how long after TTL a read is still served from
+	// the cache while it is refreshed in the background
+	StaleWhileRevalidate time.Duration
+	// Timeout bounds a load, which is shared by concurrent callers and so
+	// outlives the one that started it. Zero leaves it to the repository.
+	Timeout time.Duration
+}
+
+// key identifies a cached read. Lists have no id; single posts no limit and offset.
+type key struct {
+	list          bool
+	limit, offset int
+	id            int32
+}
+
+// Repo caches post reads of the wrapped repository. Every write through it
+// invalidates the lists and the written post. Reads of a context marked
+// database.WithPrimary bypass the cache.
+type Repo struct {
+	repo    database.DatabaseRepo
+	posts   *LRU[key, []*models.Post]
+	timeout time.Duration
+	// loads collapses concurrent misses and refreshes of a key into one query
+	loads singleflight.Group
+
+	// generation counts writes, so a read started before a write is not cached after it
+	generation atomic.Uint64
+	lastWrite  atomic.Int64
+}
+
+// NewRepo wraps repo with a cache bounded by opts
+func NewRepo(repo database.DatabaseRepo, opts Options) *Repo {
+	r := &Repo{
+		repo:    repo,
+		posts:   NewLRU[key, []*models.Post](opts.MaxEntries, opts.TTL, opts.StaleWhileRevalidate),
+		timeout: opts.Timeout,
+	}
+	r.lastWrite.Store(time.Now().UnixNano())
+	return r
+}
+
+// LastWrite returns when the last write went through the cache, or when it
+// was created. Lists cannot have changed since, unless written elsewhere.
+func (r *Repo) LastWrite() time.Time {
+	return time.Unix(0, r.lastWrite.Load())
+}
+
+func (r *Repo) Connection() *sql.DB {
+	return r.repo.Connection()
+}
+
+func (r *Repo) Close() error {
+	return r.repo.Close()
+}
+
+func (r *Repo) Healthcheck(ctx context.Context) error {
+	return r.repo.Healthcheck(ctx)
+}
+
+func (r *Repo) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	return r.read(ctx, key{list: true, limit: -1}, func(ctx context.Context) ([]*models.Post, error) {
+		return r.repo.GetAllPosts(ctx)
+	})
+}
+
+func (r *Repo) GetPostsPage(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	return r.read(ctx, key{list: true, limit: limit, offset: offset}, func(ctx context.Context) ([]*models.Post, error) {
+		return r.repo.GetPostsPage(ctx, limit, offset)
+	})
+}
+
+func (r *Repo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	posts, err := r.read(ctx, key{id: id}, func(ctx context.Context) ([]*models.Post, error) {
+		post, err := r.repo.GetPost(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return []*models.Post{post}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return posts[0], nil
+}
+
+func (r *Repo) CreatePost(ctx context.Context, item *models.Post) (*models.Post, error) {
+	post, err := r.repo.CreatePost(ctx, item)
+	if err == nil {
+		r.invalidate(int32(post.ID))
+	}
+	return post, err
+}
+
+func (r *Repo) UpdatePost(ctx context.Context, id int32, item *models.Post) (*models.Post, error) {
+	post, err := r.repo.UpdatePost(ctx, id, item)
+	if err == nil {
+		r.invalidate(id)
+	}
+	return post, err
+}
+
+func (r *Repo) DeletePost(ctx context.Context, id int32) (int32, error) {
+	deletedID, err := r.repo.DeletePost(ctx, id)
+	if err == nil {
+		r.invalidate(id)
+	}
+	return deletedID, err
+}
+
+// Invalidate drops the lists and post id, for writes that bypassed the cache
+func (r *Repo) Invalidate(id int32) {
+	r.invalidate(id)
+}
+
+// InvalidateAll drops every cached read, for when writes may have been missed
+func (r *Repo) InvalidateAll() {
+	r.generation.Add(1)
+	r.lastWrite.Store(time.Now().UnixNano())
+	r.posts.Remove(func(key) bool {
+		return true
+	})
+}
+
+func (r *Repo) invalidate(id int32) {
+	r.generation.Add(1)
+	r.lastWrite.Store(time.Now().UnixNano())
+	r.posts.Remove(func(k key) bool {
+		return k.list || k.id == id
+	})
+}
+
+// read serves k from the cache, refreshing a stale entry in the background,
+// and loads it on a miss
+func (r *Repo) read(ctx context.Context, k key, load func(context.Context) ([]*models.Post, error)) ([]*models.Post, error) {
+	if database.UsesPrimary(ctx) {
+		return load(ctx)
+	}
+
+	if posts, _, fresh, ok := r.posts.Get(k); ok {
+		if !fresh {
+			// the refresh outlives the request, but keeps its logger and trace
+			go r.load(context.WithoutCancel(ctx), k, load)
+		}
+		return copyPosts(posts), nil
+	}
+
+	posts, err := r.load(ctx, k, load)
+	if err != nil {
+		return nil, err
+	}
+	return copyPosts(posts), nil
+}
+
+// load queries k once for all concurrent callers and caches the result,
+// unless a write happened meanwhile. Callers arriving after a write do not
+// join a load started before it, and a caller giving up leaves the load to
+// the others.
+func (r *Repo) load(ctx context.Context, k key, load func(context.Context) ([]*models.Post, error)) ([]*models.Post, error) {
+	generation := r.generation.Load()
+	loaded := r.loads.DoChan(fmt.Sprint(k, generation), func() (interface{}, error) {
+		ctx, cancel := r.loadContext(ctx)
+		defer cancel()
+
+		posts, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if r.generation.Load() == generation {
+			r.posts.Add(k, posts)
+		}
+		return posts, nil
+	})
+
+	select {
+	case res := <-loaded:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]*models.Post), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// loadContext returns the context of a load started by a caller with ctx,
+// keeping its values but not its cancellation
+func (r *Repo) loadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = context.WithoutCancel(ctx)
+	if r.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// copyPosts returns copies, so callers cannot change the cached posts
+func copyPosts(posts []*models.Post) []*models.Post {
+	if posts == nil {
+		return nil
+	}
+	out := make([]*models.Post, len(posts))
+	for i, post := range posts {
+		p := *post
+		out[i] = &p
+	}
+	return out
+}
@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/models"
+)
+
+// countingRepo counts the reads that reach it
+type countingRepo struct {
+	database.DatabaseRepo
+	reads atomic.Int32
+	title atomic.Value
+	// block, when set, holds reads until closed
+	block chan struct{}
+}
+
+func newCountingRepo() *countingRepo {
+	r := &countingRepo{}
+	r.title.Store("v1")
+	return r
+}
+
+func (r *countingRepo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	r.reads.Add(1)
+	// the post as it was when the query started
+	title := r.title.Load().(string)
+	if r.block != nil {
+		<-r.block
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if id == 404 {
+		return nil, database.ErrNotFound
+	}
+	return &models.Post{ID: int(id), Title: title}, nil
+}
+
+func (r *countingRepo) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	r.reads.Add(1)
+	return []*models.Post{{ID: 1, Title: r.title.Load().(string)}}, nil
+}
+
+func (r *countingRepo) UpdatePost(ctx context.Context, id int32, item *models.Post) (*models.Post, error) {
+	r.title.Store(item.Title)
+	return &models.Post{ID: int(id), Title: item.Title}, nil
+}
+
+func TestRepoCachesReads(t *testing.T) {
+	backend := newCountingRepo()
+	repo := NewRepo(backend, Options{MaxEntries: 10, TTL: time.Minute})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if post, err := repo.GetPost(ctx, 1); err != nil || post.Title != "v1" {
+			t.Fatalf("unexpected post %v, %v", post, err)
+		}
+		repo.GetAllPosts(ctx)
+	}
+	if got := backend.reads.Load(); got != 2 {
+		t.Errorf("expected 2 reads to reach the repository, got %d", got)
+	}
+
+	// callers get copies
+	post, _ := repo.GetPost(ctx, 1)
+	post.Title = "changed"
+	if post, _ := repo.GetPost(ctx, 1); post.Title != "v1" {
+		t.Errorf("a caller changed the cached post to %q", post.Title)
+	}
+
+	// errors are not cached
+	repo.GetPost(ctx, 404)
+	if _, err := repo.GetPost(ctx, 404); err != database.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if got := backend.reads.Load(); got != 4 {
+		t.Errorf("expected both missing post reads to reach the repository, got %d", got)
+	}
+
+	// reads marked for the primary bypass the cache
+	repo.GetPost(database.WithPrimary(ctx), 1)
+	if got := backend.reads.Load(); got != 5 {
+		t.Errorf("expected the primary read to reach the repository, got %d", got)
+	}
+}
+
+func TestRepoInvalidatesOnWrite(t *testing.T) {
+	backend := newCountingRepo()
+	repo := NewRepo(backend, Options{MaxEntries: 10, TTL: time.Minute})
+	ctx := context.Background()
+	before := repo.LastWrite()
+
+	repo.GetPost(ctx, 1)
+	repo.GetPost(ctx, 2)
+	repo.GetAllPosts(ctx)
+	if _, err := repo.UpdatePost(ctx, 1, &models.Post{Title: "v2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if post, _ := repo.GetPost(ctx, 1); post.Title != "v2" {
+		t.Errorf("expected the updated post, got %q", post.Title)
+	}
+	if posts, _ := repo.GetAllPosts(ctx); posts[0].Title != "v2" {
+		t.Errorf("expected the list to be invalidated, got %q", posts[0].Title)
+	}
+	// post 2 stays cached
+	if got := backend.reads.Load(); got != 5 {
+		t.Errorf("expected 5 reads to reach the repository, got %d", got)
+	}
+	if !repo.LastWrite().After(before) {
+		t.Error("expected LastWrite to advance")
+	}
+}
+
+func TestRepoInvalidateAll(t *testing.T) {
+	backend := newCountingRepo()
+	repo := NewRepo(backend, Options{MaxEntries: 10, TTL: time.Minute})
+	ctx := context.Background()
+
+	repo.GetPost(ctx, 1)
+	repo.GetAllPosts(ctx)
+	repo.InvalidateAll()
+	repo.GetPost(ctx, 1)
+	repo.GetAllPosts(ctx)
+
+	if got := backend.reads.Load(); got != 4 {
+		t.Errorf("expected every read to reach the repository again, got %d reads", got)
+	}
+}
+
+func TestRepoStaleWhileRevalidate(t *testing.T) {
+	backend := newCountingRepo()
+	repo := NewRepo(backend, Options{MaxEntries: 10, TTL: time.Minute, StaleWhileRevalidate: time.Hour})
+	now := time.Now()
+	repo.posts.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	repo.GetPost(ctx, 1)
+	backend.title.Store("v2")
+	now = now.Add(2 * time.Minute)
+
+	// the stale post is served at once while it is refreshed
+	if post, _ := repo.GetPost(ctx, 1); post.Title != "v1" {
+		t.Errorf("expected the stale post, got %q", post.Title)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, _, fresh, _ := repo.posts.Get(key{id: 1}); fresh {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the stale entry was not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if post, _ := repo.GetPost(ctx, 1); post.Title != "v2" {
+		t.Errorf("expected the refreshed post, got %q", post.Title)
+	}
+}
+
+func TestRepoCollapsesMisses(t *testing.T) {
+	backend := newCountingRepo()
+	backend.block = make(chan struct{})
+	repo := NewRepo(backend, Options{MaxEntries: 10, TTL: time.Minute})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo.GetPost(context.Background(), 1)
+		}()
+	}
+	// let the callers pile up on the first read
+	time.Sleep(20 * time.Millisecond)
+	close(backend.block)
+	wg.Wait()
+
+	if got := backend.reads.Load(); got != 1 {
+		t.Errorf("expected a single read for concurrent misses, got %d", got)
+	}
+}
+
+func TestRepoReadAfterWriteStartsItsOwnLoad(t *testing.T) {
+	backend := newCountingRepo()
+	backend.block = make(chan struct{})
+	repo := NewRepo(backend, Options{MaxEntries: 10, TTL: time.Minute})
+	ctx := context.Background()
+
+	before := make(chan *models.Post, 1)
+	go func() {
+		post, _ := repo.GetPost(ctx, 1)
+		before <- post
+	}()
+	time.Sleep(20 * time.Millisecond)
+	repo.UpdatePost(ctx, 1, &models.Post{Title: "v2"})
+
+	after := make(chan *models.Post, 1)
+	go func() {
+		post, _ := repo.GetPost(ctx, 1)
+		after <- post
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(backend.block)
+
+	if post := <-before; post == nil || post.Title != "v1" {
+		t.Errorf("expected the read started before the write to see v1, got %+v", post)
+	}
+	if post := <-after; post == nil || post.Title != "v2" {
+		t.Errorf("expected the read after the write to see v2, got %+v", post)
+	}
+}
+
+func TestRepoLoadOutlivesCaller(t *testing.T) {
+	backend := newCountingRepo()
+	backend.block = make(chan struct{})
+	repo := NewRepo(backend, Options{MaxEntries: 10, TTL: time.Minute, Timeout: time.Second})
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := repo.GetPost(first, 1)
+		firstErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	type result struct {
+		post *models.Post
+		err  error
+	}
+	joined := make(chan result, 1)
+	go func() {
+		post, err := repo.GetPost(context.Background(), 1)
+		joined <- result{post, err}
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// the first caller gives up without failing the load it started
+	cancel()
+	select {
+	case err := <-firstErr:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected the cancelled caller to return at once, got %v", err)
+		}
+	case <-time.After(time.Second):
+		close(backend.block)
+		t.Fatal("expected the cancelled caller not to wait for the load")
+	}
+	close(backend.block)
+	if res := <-joined; res.err != nil || res.post.Title != "v1" {
+		t.Errorf("expected the joined caller to get the post, got %+v, %v", res.post, res.err)
+	}
+	if got := backend.reads.Load(); got != 1 {
+		t.Errorf("expected a single read, got %d", got)
+	}
+}
@@ -0,0 +1,317 @@
+// Package client is a typed Go client for the news API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Client calls the news API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	userAgent  string
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	// primaryUntil is the read-your-writes deadline, in Unix milliseconds,
+	// returned by the last write; reads send it back until it passes
+	primaryUntil atomic.Int64
+}
+
+// primaryUntilHeader carries the read-your-writes deadline of a server with read replicas
+const primaryUntilHeader = "X-Primary-Until"
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient makes the Client send requests with hc instead of a client with a 30 second timeout
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken sends token as a bearer token in the Authorization header
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithUserAgent sets the User-Agent header of every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetries retries idempotent calls up to max times after network errors
+// and 429, 502, 503 and 504 responses, waiting an exponentially growing,
+// jittered delay between min and max backoff. Pass 0 to disable retries.
+func WithRetries(max int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = max
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// New creates a Client for the API served at baseURL, e.g. "http://localhost:3000"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL %q must be http or https", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		userAgent:  "news-api-go-client",
+		maxRetries: 3,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// ListOptions select a page of posts
+type ListOptions struct {
+	// Limit is the maximum number of posts to return, at most 100
+	Limit int
+	// Offset is the number of posts to skip
+	Offset int
+}
+
+// ListPosts returns posts, newest first. With nil opts it returns every post.
+func (c *Client) ListPosts(ctx context.Context, opts *ListOptions) ([]*models.Post, error) {
+	query := url.Values{}
+	if opts != nil {
+		if opts.Limit > 0 {
+			query.Set("limit", strconv.Itoa(opts.Limit))
+		}
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	var posts []*models.Post
+	if err := c.do(ctx, http.MethodGet, "/posts", query, nil, &posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// GetPost returns the post with id
+func (c *Client) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	post := new(models.Post)
+	if err := c.do(ctx, http.MethodGet, postPath(id), nil, nil, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// CreatePost creates a post from the title and content of post and returns it as stored.
+// It is never retried, since a retry could create the post twice.
+func (c *Client) CreatePost(ctx context.Context, post *models.Post) (*models.Post, error) {
+	created := new(models.Post)
+	if err := c.do(ctx, http.MethodPost, "/posts", nil, post, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdatePost replaces the title, content and category of the post with id and
+// returns it as stored. An empty category clears it.
+func (c *Client) UpdatePost(ctx context.Context, id int32, post *models.Post) (*models.Post, error) {
+	updated := new(models.Post)
+	if err := c.do(ctx, http.MethodPut, postPath(id), nil, post, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeletePost deletes the post with id
+func (c *Client) DeletePost(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodDelete, postPath(id), nil, nil, nil)
+}
+
+func postPath(id int32) string {
+	return "/posts/" + strconv.FormatInt(int64(id), 10)
+}
+
+// do sends a request, retrying idempotent methods, and decodes a successful JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("client: encoding request: %w", err)
+		}
+	}
+
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	retries := 0
+	if idempotent(method) {
+		retries = c.maxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u.String(), body)
+
+		if attempt < retries && retryable(ctx, resp, err) {
+			wait := c.backoff(attempt, resp)
+			if resp != nil {
+				drain(resp)
+			}
+			if err := sleep(ctx, wait); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+		return decodeResponse(resp, out)
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	// continue the caller's trace, if any, on the server
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	// read our own writes, even before they reached the replicas
+	if until := c.primaryUntil.Load(); until > time.Now().UnixMilli() {
+		req.Header.Set(primaryUntilHeader, strconv.FormatInt(until, 10))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err == nil {
+		if until, parseErr := strconv.ParseInt(resp.Header.Get(primaryUntilHeader), 10, 64); parseErr == nil {
+			c.primaryUntil.Store(until)
+		}
+	}
+	return resp, err
+}
+
+// backoff returns the delay before retry attempt+1, honoring a Retry-After header in seconds
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, c.maxBackoff)
+		}
+	}
+
+	delay := c.minBackoff << attempt
+	if delay <= 0 || delay > c.maxBackoff {
+		delay = c.maxBackoff
+	}
+	// full jitter over the upper half of the delay spreads out retrying clients
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a failed attempt is worth repeating
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// drain reads and closes the body so the connection can be reused
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+}
+
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer drain(resp)
+
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("client: empty response body")
+		}
+		return fmt.Errorf("client: decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL, WithRetries(3, time.Millisecond, 5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNewRejectsInvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"localhost:3000", "ftp://example.com", "://"} {
+		if _, err := New(baseURL); err == nil {
+			t.Errorf("New(%q) returned no error", baseURL)
+		}
+	}
+}
+
+func TestRetriesIdempotentCalls(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1,"title":"Title","content":"Content"}`))
+	})
+
+	post, err := c.GetPost(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetPost returned an error: %v", err)
+	}
+	if post.ID != 1 || post.Title != "Title" {
+		t.Errorf("GetPost returned %+v", post)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	err := c.DeletePost(context.Background(), 1)
+	if !hasStatus(err, http.StatusBadGateway) {
+		t.Errorf("expected a 502 error, got %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 attempts, got %d", calls)
+	}
+}
+
+func TestDoesNotRetryCreate(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	if _, err := c.CreatePost(context.Background(), nil); err == nil {
+		t.Error("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 attempt, got %d", calls)
+	}
+}
+
+func TestDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	if _, err := c.ListPosts(context.Background(), nil); err == nil {
+		t.Error("expected an error")
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 attempt, got %d", calls)
+	}
+}
+
+func TestContextCancelsBackoff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, WithRetries(5, time.Hour, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = c.GetPost(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestDecodesProblemDetails(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"type":"/problems/validation-error","title":"Validation failed","status":400,` +
+			`"instance":"/posts","errors":[{"field":"title","code":"required","message":"title is required"}]}`))
+	})
+
+	_, err := c.CreatePost(context.Background(), nil)
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %T: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Type != "/problems/validation-error" || apiErr.Instance != "/posts" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "title" || apiErr.Errors[0].Code != "required" {
+		t.Errorf("unexpected field errors: %+v", apiErr.Errors)
+	}
+	if !IsValidation(err) {
+		t.Error("IsValidation returned false")
+	}
+	if want := "news api: 400 Validation failed; title: title is required"; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestDecodesPlainTextErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	_, err := c.GetPost(context.Background(), 1)
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if want := "news api: 404 Not Found: 404 page not found"; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestSendsToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("got Authorization %q", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL, WithToken("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeletePost(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEchoesPrimaryUntil(t *testing.T) {
+	until := strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10)
+	var echoed atomic.Value
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			w.Header().Set("X-Primary-Until", until)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		echoed.Store(r.Header.Get("X-Primary-Until"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1,"title":"Title","content":"Content"}`))
+	})
+
+	if _, err := c.GetPost(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := echoed.Load(); got != "" {
+		t.Errorf("expected no header before a write, got %q", got)
+	}
+
+	if err := c.DeletePost(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetPost(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := echoed.Load(); got != until {
+		t.Errorf("expected the read to send %q, got %q", until, got)
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Error is an error response of the API, decoded from its RFC 9457 problem details
+type Error struct {
+	StatusCode int          `json:"status"`
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Detail     string       `json:"detail"`
+	Instance   string       `json:"instance"`
+	Errors     []FieldError `json:"errors"`
+}
+
+// FieldError is a single field violation of a validation error
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Code    string                 `json:"code"`
+	Params  map[string]interface{} `json:"params"`
+	Message string                 `json:"message"`
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "news api: %d %s", e.StatusCode, e.Title)
+	if e.Detail != "" {
+		fmt.Fprintf(&b, ": %s", e.Detail)
+	}
+	for _, fe := range e.Errors {
+		fmt.Fprintf(&b, "; %s: %s", fe.Field, fe.Message)
+	}
+	return b.String()
+}
+
+// IsNotFound reports whether err is an API error with status 404
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsValidation reports whether err is an API error listing field violations
+func IsValidation(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && len(apiErr.Errors) > 0
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// decodeError turns an unsuccessful response into an *Error, also for bodies that are not problem details
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode, Title: http.StatusText(resp.StatusCode)}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return apiErr
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/problem+json" || mediaType == "application/json" {
+		if json.Unmarshal(body, apiErr) == nil {
+			// the status line is authoritative, whatever the body says
+			apiErr.StatusCode = resp.StatusCode
+			return apiErr
+		}
+	}
+
+	apiErr.Detail = strings.TrimSpace(string(body))
+	return apiErr
+}
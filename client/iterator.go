@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+
+	"github.com/freshusername/news-api/models"
+)
+
+// PostIterator walks through all posts page by page, newest first:
+//
+//	it := c.Posts(50)
+//	for it.Next(ctx) {
+//		post := it.Post()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type PostIterator struct {
+	client   *Client
+	pageSize int
+	offset   int
+	page     []*models.Post
+	current  *models.Post
+	last     bool
+	err      error
+}
+
+// Posts returns an iterator fetching pageSize posts per request
+func (c *Client) Posts(pageSize int) *PostIterator {
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 100
+	}
+	return &PostIterator{client: c, pageSize: pageSize}
+}
+
+// Next advances to the next post, fetching the next page when needed. It
+// returns false when the posts are exhausted or an error occurred.
+func (it *PostIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.page) == 0 {
+		if it.last {
+			return false
+		}
+
+		page, err := it.client.ListPosts(ctx, &ListOptions{Limit: it.pageSize, Offset: it.offset})
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.page = page
+		it.offset += len(page)
+		it.last = len(page) < it.pageSize
+		if len(page) == 0 {
+			return false
+		}
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Post returns the current post
+func (it *PostIterator) Post() *models.Post {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *PostIterator) Err() error {
+	return it.err
+}
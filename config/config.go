@@ -0,0 +1,458 @@
+// Package config loads the API configuration from defaults, a YAML or TOML
+// file, environment variables and command-line flags, in that order of
+// precedence, and validates it.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/freshusername/news-api/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete API configuration. Each leaf field names its file
+// key (yaml, toml), environment variable (env) and flag (flag).
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server" json:"server"`
+	Database  Database  `yaml:"database" toml:"database" json:"database"`
+	Log       Log       `yaml:"log" toml:"log" json:"log"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing" json:"tracing"`
+	Auth      Auth      `yaml:"auth" toml:"auth" json:"auth"`
+	CORS      CORS      `yaml:"cors" toml:"cors" json:"cors"`
+	Cache     Cache     `yaml:"cache" toml:"cache" json:"cache"`
+	Stream    Stream    `yaml:"stream" toml:"stream" json:"stream"`
+	WebSocket WebSocket `yaml:"websocket" toml:"websocket" json:"websocket"`
+	Webhooks  Webhooks  `yaml:"webhooks" toml:"webhooks" json:"webhooks"`
+	Outbox    Outbox    `yaml:"outbox" toml:"outbox" json:"outbox"`
+	Features  Features  `yaml:"features" toml:"features" json:"features"`
+}
+
+// Server configures the HTTP server and its graceful shutdown
+type Server struct {
+	Addr              string        `yaml:"addr" toml:"addr" json:"addr" env:"ADDR" flag:"addr" usage:"address to listen on" validate:"required"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" json:"read_timeout" env:"SERVER_READ_TIMEOUT" flag:"read-timeout" usage:"maximum duration for reading a request, including the body" validate:"positive"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" json:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" flag:"read-header-timeout" usage:"maximum duration for reading request headers" validate:"positive"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout" env:"SERVER_WRITE_TIMEOUT" flag:"write-timeout" usage:"maximum duration for writing a response" validate:"positive"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" json:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" flag:"idle-timeout" usage:"how long idle keep-alive connections stay open" validate:"positive"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" toml:"max_header_bytes" json:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES" flag:"max-header-bytes" usage:"maximum size of request headers" validate:"min=1024"`
+	// ShutdownDelay is how long /readyz fails before connections are drained,
+	// so load balancers stop routing new requests to this instance first
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay" json:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY" flag:"shutdown-delay" usage:"how long /readyz fails before draining on shutdown" validate:"min=0"`
+	// ShutdownTimeout bounds draining connections, then stopping workers
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"deadline for draining connections, and again for stopping workers, on shutdown" validate:"positive"`
+}
+
+// Database drivers selectable with Database.Driver
+const (
+	// DriverSQL goes through database/sql with the pgx stdlib driver
+	DriverSQL = "sql"
+	// DriverPgxPool uses a native pgxpool connection pool
+	DriverPgxPool = "pgxpool"
+)
+
+// Database configures the Postgres connection and its pool. DSN, when set,
+// takes precedence over the individual connection fields.
+type Database struct {
+	Driver         string        `yaml:"driver" toml:"driver" json:"driver" env:"DB_DRIVER" flag:"db-driver" usage:"repository implementation: sql (database/sql) or pgxpool" validate:"oneof=sql|pgxpool"`
+	DSN            string        `yaml:"dsn" toml:"dsn" json:"dsn" env:"DATABASE_DSN" flag:"dsn" usage:"Postgres connection string, overrides the db-* connection flags" secret:"true"`
+	Host           string        `yaml:"host" toml:"host" json:"host" env:"DB_HOST" flag:"db-host" usage:"Postgres host"`
+	Port           int           `yaml:"port" toml:"port" json:"port" env:"DB_PORT" flag:"db-port" usage:"Postgres port" validate:"min=1,max=65535"`
+	User           string        `yaml:"user" toml:"user" json:"user" env:"DB_USER" flag:"db-user" usage:"Postgres user"`
+	Password       string        `yaml:"password" toml:"password" json:"password" env:"DB_PASSWORD" flag:"db-password" usage:"Postgres password" secret:"true"`
+	Name           string        `yaml:"name" toml:"name" json:"name" env:"DB_NAME" flag:"db-name" usage:"Postgres database name"`
+	SSLMode        string        `yaml:"sslmode" toml:"sslmode" json:"sslmode" env:"DB_SSLMODE" flag:"db-sslmode" usage:"Postgres sslmode" validate:"oneof=disable|allow|prefer|require|verify-ca|verify-full"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout" toml:"connect_timeout" json:"connect_timeout" env:"DB_CONNECT_TIMEOUT" flag:"db-connect-timeout" usage:"timeout for opening a connection" validate:"positive"`
+	// StartupTimeout bounds connecting on startup, retrying with exponential
+	// backoff between RetryInitialBackoff and RetryMaxBackoff
+	StartupTimeout      time.Duration `yaml:"startup_timeout" toml:"startup_timeout" json:"startup_timeout" env:"DB_STARTUP_TIMEOUT" flag:"db-startup-timeout" usage:"how long to keep retrying the connection on startup" validate:"positive"`
+	RetryInitialBackoff time.Duration `yaml:"retry_initial_backoff" toml:"retry_initial_backoff" json:"retry_initial_backoff" env:"DB_RETRY_INITIAL_BACKOFF" flag:"db-retry-initial-backoff" usage:"delay before the first connection retry, doubled after each failure" validate:"positive"`
+	RetryMaxBackoff     time.Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff" json:"retry_max_backoff" env:"DB_RETRY_MAX_BACKOFF" flag:"db-retry-max-backoff" usage:"maximum delay between connection retries" validate:"positive"`
+	// ConnectInBackground starts serving at once, not ready, and keeps
+	// connecting in the background without a deadline
+	ConnectInBackground bool          `yaml:"connect_in_background" toml:"connect_in_background" json:"connect_in_background" env:"DB_CONNECT_IN_BACKGROUND" flag:"db-connect-in-background" usage:"serve before Postgres is reachable, reporting not ready, and keep connecting in the background"`
+	QueryTimeout        time.Duration `yaml:"query_timeout" toml:"query_timeout" json:"query_timeout" env:"DB_QUERY_TIMEOUT" flag:"db-query-timeout" usage:"timeout for a single query" validate:"positive"`
+	MaxOpenConns        int           `yaml:"max_open_conns" toml:"max_open_conns" json:"max_open_conns" env:"DB_MAX_OPEN_CONNS" flag:"db-max-open-conns" usage:"maximum open connections, 0 for unlimited" validate:"min=0"`
+	MaxIdleConns        int           `yaml:"max_idle_conns" toml:"max_idle_conns" json:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" flag:"db-max-idle-conns" usage:"maximum idle connections kept in the pool" validate:"min=0"`
+	ConnMaxLifetime     time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" json:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" flag:"db-conn-max-lifetime" usage:"maximum lifetime of a connection, 0 for unlimited" validate:"min=0"`
+	ConnMaxIdleTime     time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" json:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" flag:"db-conn-max-idle-time" usage:"maximum idle time of a connection, 0 for unlimited" validate:"min=0"`
+	// ReplicaDSNs are read-only standbys serving reads, sql driver only
+	ReplicaDSNs []string `yaml:"replica_dsns" toml:"replica_dsns" json:"replica_dsns" env:"DB_REPLICA_DSNS" flag:"db-replica-dsns" usage:"connection strings of read replicas, comma separated" secret:"true"`
+	// ReplicaMaxLag is the replication lag beyond which a replica stops serving reads
+	ReplicaMaxLag        time.Duration `yaml:"replica_max_lag" toml:"replica_max_lag" json:"replica_max_lag" env:"DB_REPLICA_MAX_LAG" flag:"db-replica-max-lag" usage:"replication lag beyond which reads fall back to other replicas or the primary" validate:"min=0"`
+	ReplicaCheckInterval time.Duration `yaml:"replica_check_interval" toml:"replica_check_interval" json:"replica_check_interval" env:"DB_REPLICA_CHECK_INTERVAL" flag:"db-replica-check-interval" usage:"how often replica health and lag are checked" validate:"positive"`
+	// ReadYourWritesWindow is how long a client's reads go to the primary after it wrote
+	ReadYourWritesWindow time.Duration `yaml:"read_your_writes_window" toml:"read_your_writes_window" json:"read_your_writes_window" env:"DB_READ_YOUR_WRITES_WINDOW" flag:"db-read-your-writes-window" usage:"how long reads of a client go to the primary after it wrote" validate:"min=0"`
+	// MinConns, HealthCheckPeriod and the statement cache apply to the pgxpool driver only
+	MinConns          int           `yaml:"min_conns" toml:"min_conns" json:"min_conns" env:"DB_MIN_CONNS" flag:"db-min-conns" usage:"connections pgxpool keeps open even when idle" validate:"min=0"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" toml:"health_check_period" json:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD" flag:"db-health-check-period" usage:"how often pgxpool checks idle connections" validate:"positive"`
+	// StatementCacheMode is prepare (server-side prepared statements), describe
+	// (cached descriptions only, safe behind PgBouncer) or off
+	StatementCacheMode     string `yaml:"statement_cache_mode" toml:"statement_cache_mode" json:"statement_cache_mode" env:"DB_STATEMENT_CACHE_MODE" flag:"db-statement-cache-mode" usage:"pgxpool statement cache: prepare, describe or off" validate:"oneof=prepare|describe|off"`
+	StatementCacheCapacity int    `yaml:"statement_cache_capacity" toml:"statement_cache_capacity" json:"statement_cache_capacity" env:"DB_STATEMENT_CACHE_CAPACITY" flag:"db-statement-cache-capacity" usage:"statements cached per pgxpool connection" validate:"min=1"`
+}
+
+// Log configures the structured logger
+type Log struct {
+	Format string `yaml:"format" toml:"format" json:"format" env:"LOG_FORMAT" flag:"log-format" usage:"log format: json or text" validate:"oneof=json|text"`
+	Level  string `yaml:"level" toml:"level" json:"level" env:"LOG_LEVEL" flag:"log-level" usage:"minimum log level: debug, info, warn or error" validate:"oneof=debug|info|warn|error"`
+}
+
+// Tracing configures OpenTelemetry; the exporters read their own OTEL_* variables
+type Tracing struct {
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter" env:"OTEL_TRACES_EXPORTER" flag:"traces-exporter" usage:"export traces to: none, otlp or console" validate:"oneof=none|otlp|console"`
+}
+
+// Auth configures bearer token authentication. Without tokens every request is anonymous.
+type Auth struct {
+	// Tokens maps principal names to their bearer tokens
+	Tokens map[string]string `yaml:"tokens" toml:"tokens" json:"tokens" env:"AUTH_TOKENS" flag:"auth-tokens" usage:"bearer tokens as name:token pairs, comma separated" secret:"true" validate:"dive,len=16..512"`
+	// ProtectReads requires a token for reads too, not only for writes
+	ProtectReads bool `yaml:"protect_reads" toml:"protect_reads" json:"protect_reads" env:"AUTH_PROTECT_READS" flag:"auth-protect-reads" usage:"require a bearer token for reads as well as writes"`
+}
+
+// Enabled reports whether any token is configured
+func (a Auth) Enabled() bool {
+	return len(a.Tokens) > 0
+}
+
+// CORS configures cross-origin requests. It is disabled without allowed origins.
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" toml:"allowed_origins" json:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" flag:"cors-allowed-origins" usage:"origins allowed to call the API, comma separated, * for any" validate:"dive,origin"`
+	AllowedMethods   []string      `yaml:"allowed_methods" toml:"allowed_methods" json:"allowed_methods" env:"CORS_ALLOWED_METHODS" flag:"cors-allowed-methods" usage:"methods allowed in cross-origin requests, comma separated"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" toml:"allowed_headers" json:"allowed_headers" env:"CORS_ALLOWED_HEADERS" flag:"cors-allowed-headers" usage:"request headers allowed in cross-origin requests, comma separated"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" toml:"exposed_headers" json:"exposed_headers" env:"CORS_EXPOSED_HEADERS" flag:"cors-exposed-headers" usage:"response headers exposed to cross-origin callers, comma separated"`
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials" json:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" flag:"cors-allow-credentials" usage:"allow cookies and authorization headers in cross-origin requests"`
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age" json:"max_age" env:"CORS_MAX_AGE" flag:"cors-max-age" usage:"how long browsers may cache preflight responses" validate:"min=0"`
+}
+
+// Enabled reports whether any origin is allowed
+func (c CORS) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// Cache configures the in-process cache of post reads
+type Cache struct {
+	Enabled    bool          `yaml:"enabled" toml:"enabled" json:"enabled" env:"CACHE_ENABLED" flag:"cache" usage:"cache post reads in memory"`
+	MaxEntries int           `yaml:"max_entries" toml:"max_entries" json:"max_entries" env:"CACHE_MAX_ENTRIES" flag:"cache-max-entries" usage:"maximum number of cached reads" validate:"min=1"`
+	TTL        time.Duration `yaml:"ttl" toml:"ttl" json:"ttl" env:"CACHE_TTL" flag:"cache-ttl" usage:"how long a cached read is fresh, also sent as max-age" validate:"positive"`
+	// StaleWhileRevalidate is how long past its TTL a read is still served
+	// while it is refreshed in the background
+	StaleWhileRevalidate time.Duration `yaml:"stale_while_revalidate" toml:"stale_while_revalidate" json:"stale_while_revalidate" env:"CACHE_STALE_WHILE_REVALIDATE" flag:"cache-stale-while-revalidate" usage:"how long past its TTL a read is served while being refreshed" validate:"min=0"`
+}
+
+// Stream configures the server-sent event stream of post changes
+type Stream struct {
+	Heartbeat time.Duration `yaml:"heartbeat" toml:"heartbeat" json:"heartbeat" env:"STREAM_HEARTBEAT" flag:"stream-heartbeat" usage:"how often an idle event stream sends a comment to keep the connection open" validate:"positive"`
+	// ReplayBuffer is the number of recent events kept for clients resuming with Last-Event-ID
+	ReplayBuffer int `yaml:"replay_buffer" toml:"replay_buffer" json:"replay_buffer" env:"STREAM_REPLAY_BUFFER" flag:"stream-replay-buffer" usage:"recent events kept for clients resuming with Last-Event-ID" validate:"min=0"`
+	// ClientBuffer is the number of events queued per client before it is
+	// disconnected as too slow, to resume from its last event
+	ClientBuffer int `yaml:"client_buffer" toml:"client_buffer" json:"client_buffer" env:"STREAM_CLIENT_BUFFER" flag:"stream-client-buffer" usage:"events queued per client before a slow client is disconnected" validate:"min=1"`
+}
+
+// WebSocket configures the /ws subscription endpoint and its per-connection limits
+type WebSocket struct {
+	// PingInterval is how often the server pings a connection; one that has
+	// not answered within PongTimeout of a ping is closed
+	PingInterval time.Duration `yaml:"ping_interval" toml:"ping_interval" json:"ping_interval" env:"WS_PING_INTERVAL" flag:"ws-ping-interval" usage:"how often WebSocket connections are pinged" validate:"positive"`
+	PongTimeout  time.Duration `yaml:"pong_timeout" toml:"pong_timeout" json:"pong_timeout" env:"WS_PONG_TIMEOUT" flag:"ws-pong-timeout" usage:"how long a WebSocket connection may take to answer a ping" validate:"positive"`
+	// MaxMessageBytes bounds the size of a client message
+	MaxMessageBytes  int `yaml:"max_message_bytes" toml:"max_message_bytes" json:"max_message_bytes" env:"WS_MAX_MESSAGE_BYTES" flag:"ws-max-message-bytes" usage:"largest message accepted from a WebSocket client" validate:"min=128"`
+	MaxSubscriptions int `yaml:"max_subscriptions" toml:"max_subscriptions" json:"max_subscriptions" env:"WS_MAX_SUBSCRIPTIONS" flag:"ws-max-subscriptions" usage:"topics a WebSocket connection may subscribe to" validate:"min=1"`
+	// SendBuffer is the number of messages queued per connection before it
+	// is closed as too slow
+	SendBuffer int `yaml:"send_buffer" toml:"send_buffer" json:"send_buffer" env:"WS_SEND_BUFFER" flag:"ws-send-buffer" usage:"messages queued per WebSocket connection before a slow client is disconnected" validate:"min=1"`
+}
+
+// Webhooks configures the delivery of post events to webhooks
+type Webhooks struct {
+	// PollInterval is how often the delivery queue is checked while idle
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" json:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL" flag:"webhooks-poll-interval" usage:"how often the webhook delivery queue is checked while idle" validate:"positive"`
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size" json:"batch_size" env:"WEBHOOKS_BATCH_SIZE" flag:"webhooks-batch-size" usage:"webhook deliveries sent concurrently" validate:"min=1"`
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout" json:"timeout" env:"WEBHOOKS_TIMEOUT" flag:"webhooks-timeout" usage:"deadline of a webhook delivery attempt" validate:"positive"`
+	// MaxAttempts is the number of attempts before a delivery is dead-lettered
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts" json:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" flag:"webhooks-max-attempts" usage:"attempts before a webhook delivery is dead-lettered" validate:"min=1"`
+	// RetryInitialBackoff is the wait before the first retry, doubled for
+	// each following one up to RetryMaxBackoff
+	RetryInitialBackoff time.Duration `yaml:"retry_initial_backoff" toml:"retry_initial_backoff" json:"retry_initial_backoff" env:"WEBHOOKS_RETRY_INITIAL_BACKOFF" flag:"webhooks-retry-initial-backoff" usage:"wait before retrying a failed webhook delivery" validate:"positive"`
+	RetryMaxBackoff     time.Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff" json:"retry_max_backoff" env:"WEBHOOKS_RETRY_MAX_BACKOFF" flag:"webhooks-retry-max-backoff" usage:"longest wait between webhook delivery retries" validate:"positive"`
+	// AllowPrivateTargets permits deliveries to loopback and private
+	// addresses, which are refused by default so webhooks cannot probe the
+	// internal network
+	AllowPrivateTargets bool `yaml:"allow_private_targets" toml:"allow_private_targets" json:"allow_private_targets" env:"WEBHOOKS_ALLOW_PRIVATE_TARGETS" flag:"webhooks-allow-private-targets" usage:"allow webhook deliveries to loopback and private addresses"`
+}
+
+// Outbox configures the relay publishing the post events of the outbox table
+type Outbox struct {
+	// Sinks lists where events are published: bus, the in-process consumers
+	// such as webhooks, file and stdout, each as a line of JSON
+	Sinks []string `yaml:"sinks" toml:"sinks" json:"sinks" env:"OUTBOX_SINKS" flag:"outbox-sinks" usage:"where post events are published: bus, file, stdout" validate:"dive,oneof=bus|file|stdout"`
+	// File is appended to by the file sink
+	File         string        `yaml:"file" toml:"file" json:"file" env:"OUTBOX_FILE" flag:"outbox-file" usage:"file the file sink appends post events to"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" json:"poll_interval" env:"OUTBOX_POLL_INTERVAL" flag:"outbox-poll-interval" usage:"how often the outbox is checked while no post changes are notified" validate:"positive"`
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size" json:"batch_size" env:"OUTBOX_BATCH_SIZE" flag:"outbox-batch-size" usage:"outbox events published at once" validate:"min=1"`
+	// Retention is how long published events are kept, e.g. to investigate
+	Retention time.Duration `yaml:"retention" toml:"retention" json:"retention" env:"OUTBOX_RETENTION" flag:"outbox-retention" usage:"how long published outbox events are kept" validate:"positive"`
+}
+
+// Features toggles optional parts of the API
+type Features struct {
+	Docs    bool `yaml:"docs" toml:"docs" json:"docs" env:"DOCS_ENABLED" flag:"docs" usage:"serve interactive API docs at /docs"`
+	Metrics bool `yaml:"metrics" toml:"metrics" json:"metrics" env:"METRICS_ENABLED" flag:"metrics" usage:"serve Prometheus metrics at /metrics"`
+	// ChangeFeed listens for post changes notified by Postgres, from any
+	// instance, to invalidate the cache, and streams the events of the outbox
+	// at /posts/stream and /ws
+	ChangeFeed bool `yaml:"change_feed" toml:"change_feed" json:"change_feed" env:"CHANGE_FEED_ENABLED" flag:"change-feed" usage:"listen for post changes notified by Postgres, on a connection of its own"`
+	// Webhooks sends queued post events to the registered webhooks. The
+	// /webhooks routes are served either way.
+	Webhooks bool `yaml:"webhooks" toml:"webhooks" json:"webhooks" env:"WEBHOOKS_ENABLED" flag:"webhooks" usage:"deliver post events to the registered webhooks"`
+	// ResponseValidation is one of the response validation modes: off, log or fail
+	ResponseValidation string `yaml:"response_validation" toml:"response_validation" json:"response_validation" env:"VALIDATE_RESPONSES" flag:"validate-responses" usage:"check responses against the OpenAPI document: off, log or fail" validate:"oneof=off|log|fail"`
+}
+
+// Default returns the configuration used for everything not configured otherwise
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:              ":3000",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: Database{
+			Driver:         DriverSQL,
+			Port:           5432,
+			SSLMode:        "disable",
+			ConnectTimeout: 5 * time.Second,
+
+			StartupTimeout:      time.Minute,
+			RetryInitialBackoff: 500 * time.Millisecond,
+			RetryMaxBackoff:     10 * time.Second,
+
+			QueryTimeout:    3 * time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+
+			ReplicaMaxLag:        2 * time.Second,
+			ReplicaCheckInterval: 5 * time.Second,
+			ReadYourWritesWindow: 5 * time.Second,
+
+			HealthCheckPeriod:      time.Minute,
+			StatementCacheMode:     "prepare",
+			StatementCacheCapacity: 512,
+		},
+		Log: Log{
+			Format: "json",
+			Level:  "info",
+		},
+		Tracing: Tracing{
+			Exporter: "none",
+		},
+		CORS: CORS{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "Last-Event-ID", "X-Request-ID", "X-Primary-Until"},
+			ExposedHeaders: []string{"X-Request-ID", "X-Primary-Until"},
+			MaxAge:         5 * time.Minute,
+		},
+		Cache: Cache{
+			MaxEntries:           1000,
+			TTL:                  5 * time.Second,
+			StaleWhileRevalidate: 30 * time.Second,
+		},
+		Stream: Stream{
+			Heartbeat:    15 * time.Second,
+			ReplayBuffer: 1000,
+			ClientBuffer: 64,
+		},
+		WebSocket: WebSocket{
+			PingInterval:     30 * time.Second,
+			PongTimeout:      10 * time.Second,
+			MaxMessageBytes:  4096,
+			MaxSubscriptions: 100,
+			SendBuffer:       64,
+		},
+		Webhooks: Webhooks{
+			PollInterval:        time.Second,
+			BatchSize:           10,
+			Timeout:             10 * time.Second,
+			MaxAttempts:         8,
+			RetryInitialBackoff: 10 * time.Second,
+			RetryMaxBackoff:     time.Hour,
+		},
+		Outbox: Outbox{
+			Sinks:        []string{"bus"},
+			PollInterval: time.Second,
+			BatchSize:    100,
+			Retention:    24 * time.Hour,
+		},
+		Features: Features{
+			Docs:               true,
+			Metrics:            true,
+			ChangeFeed:         true,
+			Webhooks:           true,
+			ResponseValidation: "off",
+		},
+	}
+}
+
+// ReplicaConfig returns the configuration of the replica at index i, which
+// shares the pool settings of the primary
+func (d Database) ReplicaConfig(i int) Database {
+	replica := d
+	replica.DSN = d.ReplicaDSNs[i]
+	replica.ReplicaDSNs = nil
+	return replica
+}
+
+// ConnString returns DSN, or a connection string assembled from the individual fields
+func (d Database) ConnString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s timezone=UTC connect_timeout=%d",
+		d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode, int(d.ConnectTimeout.Seconds()))
+}
+
+// codePositive is the error code of the positive rule
+const codePositive = "positive"
+
+func init() {
+	validation.DefaultCatalog.Add("en", codePositive, "must be greater than zero")
+	validation.DefaultCatalog.Add("uk", codePositive, "має бути більше нуля")
+
+	validation.RegisterRule(codePositive, func(param string) (validation.Rule, error) {
+		if param != "" {
+			return nil, errors.New("positive takes no parameter")
+		}
+		return positive, nil
+	})
+	validation.RegisterRule("origin", func(param string) (validation.Rule, error) {
+		if param != "" {
+			return nil, errors.New("origin takes no parameter")
+		}
+		return origin, nil
+	})
+}
+
+// positive accepts integers, including durations, greater than zero
+func positive(value interface{}) *validation.ValidationError {
+	if v := reflect.ValueOf(value); v.CanInt() && v.Int() > 0 {
+		return nil
+	}
+	return validation.NewError(codePositive, nil)
+}
+
+// origin accepts * or a scheme://host[:port] origin without a path
+func origin(value interface{}) *validation.ValidationError {
+	str, _ := value.(string)
+	if str == "*" {
+		return nil
+	}
+	u, err := url.Parse(str)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+		return validation.NewError(validation.CodeURL, nil)
+	}
+	return nil
+}
+
+// Validate checks the configuration, reporting every invalid field
+func (c *Config) Validate() error {
+	var problems []string
+	for _, err := range validation.NewValidator().Validate(c) {
+		problems = append(problems, err.PrintError())
+	}
+
+	if c.Database.DSN == "" && (c.Database.Host == "" || c.Database.Name == "") {
+		problems = append(problems, "database: set either dsn or host and name")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		problems = append(problems, "database.max_idle_conns: must not exceed max_open_conns")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MinConns > c.Database.MaxOpenConns {
+		problems = append(problems, "database.min_conns: must not exceed max_open_conns")
+	}
+	if c.Database.RetryInitialBackoff > c.Database.RetryMaxBackoff {
+		problems = append(problems, "database.retry_initial_backoff: must not exceed retry_max_backoff")
+	}
+	if c.Database.Driver == DriverPgxPool && len(c.Database.ReplicaDSNs) > 0 {
+		problems = append(problems, "database.replica_dsns: supported by the sql driver only")
+	}
+	if c.Database.Driver == DriverPgxPool && c.Database.MaxOpenConns == 0 {
+		problems = append(problems, "database.max_open_conns: pgxpool needs a limit")
+	}
+	if c.Webhooks.RetryInitialBackoff > c.Webhooks.RetryMaxBackoff {
+		problems = append(problems, "webhooks.retry_initial_backoff: must not exceed retry_max_backoff")
+	}
+	if contains(c.Outbox.Sinks, "file") && c.Outbox.File == "" {
+		problems = append(problems, "outbox.file: required by the file sink")
+	}
+	if c.Features.Webhooks && !contains(c.Outbox.Sinks, "bus") {
+		problems = append(problems, "outbox.sinks: webhooks need the bus sink")
+	}
+	if c.CORS.AllowCredentials && contains(c.CORS.AllowedOrigins, "*") {
+		problems = append(problems, "cors.allow_credentials: cannot be combined with the * origin")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// redacted replaces the value of a configured secret
+const redacted = "<redacted>"
+
+// Redacted returns a copy with every field tagged secret masked, safe to print
+func (c *Config) Redacted() *Config {
+	out := *c
+	walk(reflect.ValueOf(&out).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") != "true" {
+			return
+		}
+		switch value.Kind() {
+		case reflect.String:
+			if value.Len() > 0 {
+				value.SetString(redacted)
+			}
+		case reflect.Slice:
+			// replace the slice, which the copy still shares with c
+			masked := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+			for i := 0; i < value.Len(); i++ {
+				masked.Index(i).SetString(redacted)
+			}
+			value.Set(masked)
+		case reflect.Map:
+			// replace the map, which the copy still shares with c
+			masked := reflect.MakeMapWithSize(value.Type(), value.Len())
+			for _, key := range value.MapKeys() {
+				masked.SetMapIndex(key, reflect.ValueOf(redacted))
+			}
+			value.Set(masked)
+		}
+	})
+	return &out
+}
+
+// YAML renders the configuration in the format of a config file
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv names the environment variable holding the config file path, like the -config flag
+const ConfigEnv = "NEWS_API_CONFIG"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load builds the configuration from the defaults, the file named by -config
+// or NEWS_API_CONFIG, the environment and the flags in args, each overriding
+// the previous. It registers -config and a flag per configuration field on
+// fs, so the caller may add its own flags before. lookupEnv is usually
+// os.LookupEnv. The result is not validated.
+func Load(fs *flag.FlagSet, args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	configPath := fs.String("config", "", "path of a YAML or TOML config file (env "+ConfigEnv+")")
+
+	// flags are applied last, after the file and the environment were read
+	var flagged []func()
+	walk(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("flag")
+		if name == "" {
+			return
+		}
+		usage := field.Tag.Get("usage")
+		if env := field.Tag.Get("env"); env != "" {
+			usage += " (env " + env + ")"
+		}
+		fs.Var(&deferredValue{target: value, pending: &flagged}, name, usage)
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	path := *configPath
+	if path == "" {
+		path, _ = lookupEnv(ConfigEnv)
+	}
+	if path != "" {
+		if err := loadFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(cfg, lookupEnv); err != nil {
+		return nil, err
+	}
+
+	for _, apply := range flagged {
+		apply()
+	}
+	return cfg, nil
+}
+
+// loadFile decodes a YAML or TOML file, chosen by extension, over cfg. Unknown keys are errors.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s: expected a .yaml, .yml or .toml extension", path)
+	}
+	return nil
+}
+
+// loadEnv sets every field whose environment variable is set and not empty
+func loadEnv(cfg *Config, lookupEnv func(string) (string, bool)) error {
+	var err error
+	walk(reflect.ValueOf(cfg).Elem(), func(field reflect.StructField, value reflect.Value) {
+		key := field.Tag.Get("env")
+		if key == "" || err != nil {
+			return
+		}
+		raw, ok := lookupEnv(key)
+		if !ok || raw == "" {
+			return
+		}
+		if setErr := setString(value, raw); setErr != nil {
+			err = fmt.Errorf("environment variable %s: %w", key, setErr)
+		}
+	})
+	return err
+}
+
+// walk calls fn for every leaf field of the struct v, in declaration order
+func walk(v reflect.Value, fn func(field reflect.StructField, value reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field, value := v.Type().Field(i), v.Field(i)
+		if value.Kind() == reflect.Struct {
+			walk(value, fn)
+			continue
+		}
+		fn(field, value)
+	}
+}
+
+// setString parses raw into the field: strings, bools, integers, durations,
+// comma separated lists and comma separated key:value maps
+func setString(value reflect.Value, raw string) error {
+	switch {
+	case value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+	case value.Kind() == reflect.String:
+		value.SetString(raw)
+	case value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(b)
+	case value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(int64(n))
+	case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.String:
+		value.Set(reflect.ValueOf(splitList(raw)))
+	case value.Kind() == reflect.Map && value.Type().Elem().Kind() == reflect.String:
+		m := make(map[string]string)
+		for _, pair := range splitList(raw) {
+			key, val, ok := strings.Cut(pair, ":")
+			if !ok || key == "" {
+				return fmt.Errorf("expected name:value pairs, got %q", pair)
+			}
+			m[key] = val
+		}
+		value.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported field type %s", value.Type())
+	}
+	return nil
+}
+
+// formatValue renders a field the way setString parses it
+func formatValue(value reflect.Value) string {
+	switch {
+	case value.Type() == durationType:
+		return time.Duration(value.Int()).String()
+	case value.Kind() == reflect.Slice:
+		return strings.Join(value.Interface().([]string), ",")
+	case value.Kind() == reflect.Map:
+		var pairs []string
+		for _, key := range value.MapKeys() {
+			pairs = append(pairs, key.String()+":"+value.MapIndex(key).String())
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// deferredValue is a flag.Value that checks its argument at parse time but
+// only assigns it once the file and environment were applied
+type deferredValue struct {
+	target  reflect.Value
+	pending *[]func()
+}
+
+func (d *deferredValue) String() string {
+	if !d.target.IsValid() {
+		// flag.PrintDefaults probes with a zero value
+		return ""
+	}
+	if d.target.Kind() == reflect.Map {
+		// never print secrets in -help
+		return ""
+	}
+	return formatValue(d.target)
+}
+
+func (d *deferredValue) Set(raw string) error {
+	parsed := reflect.New(d.target.Type()).Elem()
+	if err := setString(parsed, raw); err != nil {
+		return err
+	}
+	*d.pending = append(*d.pending, func() { d.target.Set(parsed) })
+	return nil
+}
+
+// IsBoolFlag lets boolean fields be set by a bare -flag
+func (d *deferredValue) IsBoolFlag() bool {
+	return d.target.IsValid() && d.target.Kind() == reflect.Bool
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/freshusername/news-api/models"
+	"github.com/jackc/pgx/v4/pgxpool"
+	_ "github.com/jackc/pgx/v4/stdlib"
+)
+
+// benchRepos opens both repository implementations on the migrated database
+// named by BENCH_DSN, seeded with a post, and skips the benchmark without it
+func benchRepos(b *testing.B) (repos map[string]DatabaseRepo, postID int32) {
+	dsn := os.Getenv("BENCH_DSN")
+	if dsn == "" {
+		b.Skip("set BENCH_DSN to a migrated Postgres database to run the repository benchmarks")
+	}
+	ctx := context.Background()
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(25)
+
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		b.Fatal(err)
+	}
+	poolConfig.MaxConns = 25
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	repos = map[string]DatabaseRepo{
+		"sql":     &PostgresDBRepo{DB: db},
+		"pgxpool": &PgxPoolRepo{Pool: pool},
+	}
+	b.Cleanup(func() {
+		for _, repo := range repos {
+			repo.Close()
+		}
+	})
+
+	post, err := repos["sql"].CreatePost(ctx, &models.Post{Title: "Benchmark", Content: "Seeded by the repository benchmarks"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		repos["sql"].DeletePost(ctx, int32(post.ID))
+	})
+	return repos, int32(post.ID)
+}
+
+func BenchmarkGetPost(b *testing.B) {
+	repos, id := benchRepos(b)
+
+	for _, name := range []string{"sql", "pgxpool"} {
+		repo := repos[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := repo.GetPost(context.Background(), id); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGetPostsPage(b *testing.B) {
+	repos, _ := benchRepos(b)
+
+	for _, name := range []string{"sql", "pgxpool"} {
+		repo := repos[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := repo.GetPostsPage(context.Background(), 20, 0); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
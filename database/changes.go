@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// PostChangesChannel is the channel the posts_notify_change trigger notifies
+const PostChangesChannel = "post_changes"
+
+// ChangeOp is the kind of a change
+type ChangeOp string
+
+const (
+	ChangeInsert ChangeOp = "insert"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+	// ChangeResync follows a reconnection of the listener. Changes may have
+	// been missed meanwhile, so anything derived from earlier ones is stale.
+	ChangeResync ChangeOp = "resync"
+)
+
+// Change is a committed write to public.posts, whichever API instance or
+// client made it
+type Change struct {
+	Op ChangeOp
+	ID int32
+	// At is when the writing transaction started
+	At time.Time
+	// Post is the row after an insert or update, and before a delete.
+	// Resyncs have none.
+	Post *models.Post
+}
+
+// changePayload is the JSON sent by the notify_post_change trigger
+type changePayload struct {
+	Op   ChangeOp  `json:"op"`
+	ID   int32     `json:"id"`
+	At   time.Time `json:"at"`
+	Post struct {
+		ID        int       `json:"id"`
+		Title     string    `json:"title"`
+		Content   string    `json:"content"`
+		Category  string    `json:"category"`
+		CreatedAt timestamp `json:"created_at"`
+		UpdatedAt timestamp `json:"updated_at"`
+	} `json:"post"`
+}
+
+// timestamp reads the JSON of a timestamp without time zone as UTC, the way
+// the driver scans the column
+type timestamp time.Time
+
+func (t *timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.Parse("2006-01-02T15:04:05.999999999", s)
+	if err != nil {
+		return err
+	}
+	*t = timestamp(parsed)
+	return nil
+}
+
+// ParseChange decodes the payload of a notification on PostChangesChannel
+func ParseChange(payload string) (Change, error) {
+	var p changePayload
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return Change{}, fmt.Errorf("decoding the post change: %w", err)
+	}
+	switch p.Op {
+	case ChangeInsert, ChangeUpdate, ChangeDelete:
+	default:
+		return Change{}, fmt.Errorf("unknown post change %q", p.Op)
+	}
+
+	return Change{
+		Op: p.Op,
+		ID: p.ID,
+		At: p.At,
+		Post: &models.Post{
+			ID:        p.Post.ID,
+			Title:     p.Post.Title,
+			Content:   p.Post.Content,
+			Category:  p.Post.Category,
+			CreatedAt: time.Time(p.Post.CreatedAt),
+			UpdatedAt: time.Time(p.Post.UpdatedAt),
+		},
+	}, nil
+}
+
+// Listener turns notifications on PostChangesChannel into Changes. It holds a
+// connection of its own, outside any pool, and reconnects when it is lost.
+type Listener struct {
+	// ConnString is the Postgres connection string. Listening needs a session,
+	// so it must not go through a transaction-mode pooler such as PgBouncer.
+	ConnString string
+	// MinBackoff and MaxBackoff bound the delay between reconnection
+	// attempts, doubled after each failure
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Logger     *slog.Logger
+}
+
+// Run passes every change to handle, in commit order, until ctx is done.
+// After reconnecting it passes a ChangeResync first. handle runs on the
+// listening connection, so it should return quickly.
+func (l *Listener) Run(ctx context.Context, handle func(Change)) {
+	delay := l.MinBackoff
+	for connected := false; ; {
+		err := l.listen(ctx, func() {
+			if connected {
+				handle(Change{Op: ChangeResync, At: time.Now()})
+			}
+			connected = true
+			delay = l.MinBackoff
+		}, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		l.logger().Warn("listening for post changes failed", "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, l.MaxBackoff)
+	}
+}
+
+// listen connects, calls listening once notifications are subscribed, and
+// passes them to handle until the connection fails or ctx is done
+func (l *Listener) listen(ctx context.Context, listening func(), handle func(Change)) error {
+	conn, err := pgx.Connect(ctx, l.ConnString)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+		defer cancel()
+		conn.Close(closeCtx)
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{PostChangesChannel}.Sanitize()); err != nil {
+		return err
+	}
+	l.logger().Info("listening for post changes", "channel", PostChangesChannel)
+	listening()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		change, err := ParseChange(n.Payload)
+		if err != nil {
+			l.logger().Error("skipping a post change", "error", err, "payload", truncate(n.Payload, 200))
+			continue
+		}
+		handle(change)
+	}
+}
+
+func (l *Listener) logger() *slog.Logger {
+	if l.Logger != nil {
+		return l.Logger
+	}
+	return slog.Default()
+}
+
+// truncate shortens s to at most n bytes for logging
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "") + "…"
+}
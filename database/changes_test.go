@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/logging"
+)
+
+func TestParseChange(t *testing.T) {
+	payload := `{"op" : "update", "id" : 7, "at" : "2024-03-01T10:00:00.123456+00:00", "post" : {"id":7,"title":"Title","content":"Content","created_at":"2024-02-14T16:09:12.5","updated_at":"2024-03-01T10:00:00.123456"}}`
+
+	change, err := ParseChange(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Op != ChangeUpdate || change.ID != 7 {
+		t.Errorf("unexpected change %s of post %d", change.Op, change.ID)
+	}
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 123456000, time.UTC); !change.At.Equal(want) {
+		t.Errorf("At = %v, want %v", change.At, want)
+	}
+	post := change.Post
+	if post == nil || post.ID != 7 || post.Title != "Title" || post.Content != "Content" {
+		t.Fatalf("unexpected post %+v", post)
+	}
+	if want := time.Date(2024, 2, 14, 16, 9, 12, 500000000, time.UTC); !post.CreatedAt.Equal(want) || post.CreatedAt.Location() != time.UTC {
+		t.Errorf("CreatedAt = %v, want %v", post.CreatedAt, want)
+	}
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 123456000, time.UTC); !post.UpdatedAt.Equal(want) {
+		t.Errorf("UpdatedAt = %v, want %v", post.UpdatedAt, want)
+	}
+}
+
+func TestParseChangeInvalid(t *testing.T) {
+	for _, payload := range []string{
+		`not json`,
+		`{"op":"truncate","id":1}`,
+		`{"op":"insert","id":1,"post":{"created_at":"yesterday"}}`,
+	} {
+		if _, err := ParseChange(payload); err == nil {
+			t.Errorf("expected an error for %s", payload)
+		}
+	}
+}
+
+func TestListenerStops(t *testing.T) {
+	// nothing listens on port 1, so every attempt fails and is retried
+	l := &Listener{
+		ConnString: "postgres://postgres@127.0.0.1:1/news?connect_timeout=1",
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		Logger:     logging.Discard(),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx, func(Change) {
+			t.Error("expected no change without a connection")
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the context was done")
+	}
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+
+	"github.com/freshusername/news-api/models"
+)
+
+// ErrUnavailable is returned while the database has not been connected yet
+var ErrUnavailable = errors.New("database unavailable")
+
+// ConnectingRepo stands in for a repository that is still connecting. Its
+// methods fail with ErrUnavailable until Connected is called, then delegate
+// to the connected repository. It lets the server start before Postgres is
+// reachable.
+type ConnectingRepo struct {
+	repo atomic.Pointer[DatabaseRepo]
+}
+
+// Connected makes every later call go to repo
+func (c *ConnectingRepo) Connected(repo DatabaseRepo) {
+	c.repo.Store(&repo)
+}
+
+// current returns the connected repository, or nil
+func (c *ConnectingRepo) current() DatabaseRepo {
+	if repo := c.repo.Load(); repo != nil {
+		return *repo
+	}
+	return nil
+}
+
+// Connection returns nil until the repository is connected
+func (c *ConnectingRepo) Connection() *sql.DB {
+	if repo := c.current(); repo != nil {
+		return repo.Connection()
+	}
+	return nil
+}
+
+// Close closes the connected repository, if any
+func (c *ConnectingRepo) Close() error {
+	if repo := c.current(); repo != nil {
+		return repo.Close()
+	}
+	return nil
+}
+
+func (c *ConnectingRepo) Healthcheck(ctx context.Context) error {
+	if repo := c.current(); repo != nil {
+		return repo.Healthcheck(ctx)
+	}
+	return ErrUnavailable
+}
+
+func (c *ConnectingRepo) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	if repo := c.current(); repo != nil {
+		return repo.GetAllPosts(ctx)
+	}
+	return nil, ErrUnavailable
+}
+
+func (c *ConnectingRepo) GetPostsPage(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	if repo := c.current(); repo != nil {
+		return repo.GetPostsPage(ctx, limit, offset)
+	}
+	return nil, ErrUnavailable
+}
+
+func (c *ConnectingRepo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	if repo := c.current(); repo != nil {
+		return repo.GetPost(ctx, id)
+	}
+	return nil, ErrUnavailable
+}
+
+func (c *ConnectingRepo) CreatePost(ctx context.Context, item *models.Post) (*models.Post, error) {
+	if repo := c.current(); repo != nil {
+		return repo.CreatePost(ctx, item)
+	}
+	return nil, ErrUnavailable
+}
+
+func (c *ConnectingRepo) UpdatePost(ctx context.Context, id int32, item *models.Post) (*models.Post, error) {
+	if repo := c.current(); repo != nil {
+		return repo.UpdatePost(ctx, id, item)
+	}
+	return nil, ErrUnavailable
+}
+
+func (c *ConnectingRepo) DeletePost(ctx context.Context, id int32) (int32, error) {
+	if repo := c.current(); repo != nil {
+		return repo.DeletePost(ctx, id)
+	}
+	return 0, ErrUnavailable
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/freshusername/news-api/models"
+)
+
+// postRepo finds every post
+type postRepo struct {
+	DatabaseRepo
+}
+
+func (postRepo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	return &models.Post{ID: int(id)}, nil
+}
+
+func (postRepo) Healthcheck(ctx context.Context) error {
+	return nil
+}
+
+func TestConnectingRepo(t *testing.T) {
+	repo := &ConnectingRepo{}
+
+	if _, err := repo.GetPost(context.Background(), 1); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable before connecting, got %v", err)
+	}
+	if err := repo.Healthcheck(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected a failing healthcheck before connecting, got %v", err)
+	}
+	if repo.Connection() != nil || repo.Close() != nil {
+		t.Error("expected no connection to close before connecting")
+	}
+
+	repo.Connected(postRepo{})
+
+	if post, err := repo.GetPost(context.Background(), 7); err != nil || post.ID != 7 {
+		t.Errorf("expected the connected repository to answer, got %v, %v", post, err)
+	}
+	if err := repo.Healthcheck(context.Background()); err != nil {
+		t.Errorf("expected a passing healthcheck, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Migrations are the goose migrations of the schema, embedded so the binary
+// knows which schema version it expects.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// LatestMigration returns the version of the newest embedded migration,
+// taken from its goose file name, e.g. 20240214160912_new_posts_table.sql
+func LatestMigration() (int64, error) {
+	names, err := fs.Glob(Migrations, "migrations/*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(path.Base(name), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s has no numeric version: %w", name, err)
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+// AppliedMigration returns the newest migration version goose applied to db
+func AppliedMigration(ctx context.Context, db *sql.DB) (int64, error) {
+	var version sql.NullInt64
+	err := db.QueryRowContext(ctx, `SELECT max(version_id) FROM goose_db_version WHERE is_applied`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("reading the applied migration: %w", err)
+	}
+	return version.Int64, nil
+}
+
+// CheckMigrations returns a readiness check failing until db is migrated to the embedded schema version
+func CheckMigrations(db *sql.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		want, err := LatestMigration()
+		if err != nil {
+			return err
+		}
+
+		applied, err := AppliedMigration(ctx, db)
+		if err != nil {
+			return err
+		}
+		if applied != want {
+			return fmt.Errorf("schema is at migration %d, expected %d", applied, want)
+		}
+		return nil
+	}
+}
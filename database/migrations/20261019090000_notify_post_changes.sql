@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- notify_post_change publishes every change of a post on the post_changes
+-- channel, delivered to listeners when the transaction commits. The payload
+-- carries the new row, or the old one for deletions, and stays well below the
+-- 8000 byte limit of pg_notify with the column sizes of public.posts.
+CREATE FUNCTION public.notify_post_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    post public.posts;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        post := OLD;
+    ELSE
+        post := NEW;
+    END IF;
+    PERFORM pg_notify('post_changes', json_build_object(
+        'op', lower(TG_OP),
+        'id', post.id,
+        'at', now(),
+        'post', row_to_json(post)
+    )::text);
+    RETURN NULL;
+END;
+$$;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TRIGGER posts_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON public.posts
+FOR EACH ROW EXECUTE FUNCTION public.notify_post_change();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER posts_notify_change ON public.posts;
+DROP FUNCTION public.notify_post_change();
+-- +goose StatementEnd
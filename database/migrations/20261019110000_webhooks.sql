@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+-- webhooks subscribe URLs to post events; an empty events array means all
+CREATE TABLE public.webhooks (
+    id integer GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    url character varying(2048) NOT NULL,
+    secret character varying(256) NOT NULL,
+    events text[] NOT NULL DEFAULT '{}',
+    paused boolean NOT NULL DEFAULT false,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    updated_at timestamp with time zone NOT NULL DEFAULT now()
+);
+-- webhook_deliveries is the durable delivery queue: pending deliveries are
+-- claimed once next_attempt_at passes, and end delivered or dead
+CREATE TABLE public.webhook_deliveries (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    webhook_id integer NOT NULL REFERENCES public.webhooks (id) ON DELETE CASCADE,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamp with time zone NOT NULL DEFAULT now(),
+    last_status_code integer,
+    last_error text,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    delivered_at timestamp with time zone
+);
+CREATE INDEX webhook_deliveries_due ON public.webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook ON public.webhook_deliveries (webhook_id, id DESC);
+-- webhook_attempts logs every delivery attempt
+CREATE TABLE public.webhook_attempts (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    delivery_id bigint NOT NULL REFERENCES public.webhook_deliveries (id) ON DELETE CASCADE,
+    attempt integer NOT NULL,
+    status_code integer,
+    error text,
+    duration_ms integer NOT NULL,
+    attempted_at timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX webhook_attempts_delivery ON public.webhook_attempts (delivery_id, attempt);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE public.webhook_attempts;
+DROP TABLE public.webhook_deliveries;
+DROP TABLE public.webhooks;
+-- +goose StatementEnd
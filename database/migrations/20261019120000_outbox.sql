@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- outbox holds the post events written in the same transaction as the post,
+-- until the relay has published them
+CREATE TABLE public.outbox (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    event text NOT NULL,
+    post_id integer NOT NULL,
+    payload jsonb NOT NULL,
+    occurred_at timestamp with time zone NOT NULL DEFAULT now(),
+    published_at timestamp with time zone
+);
+CREATE INDEX outbox_unpublished ON public.outbox (id) WHERE published_at IS NULL;
+CREATE INDEX outbox_published ON public.outbox (published_at) WHERE published_at IS NOT NULL;
+-- event_id is the outbox event a delivery was queued for, so an event
+-- published again is not queued twice for the same webhook
+ALTER TABLE public.webhook_deliveries ADD COLUMN event_id bigint;
+CREATE UNIQUE INDEX webhook_deliveries_event ON public.webhook_deliveries (webhook_id, event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX public.webhook_deliveries_event;
+ALTER TABLE public.webhook_deliveries DROP COLUMN event_id;
+DROP TABLE public.outbox;
+-- +goose StatementEnd
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/freshusername/news-api/models"
+	"github.com/jackc/pgx/v4"
+)
+
+// Post event types written to the outbox. Each write of a post inserts its
+// event in the same transaction, so an event exists if and only if the write
+// committed, and a relay can publish it even after a crash.
+const (
+	EventPostCreated = "post.created"
+	EventPostUpdated = "post.updated"
+	EventPostDeleted = "post.deleted"
+)
+
+// outboxWriteLockKey is the transaction-level advisory lock taken before an
+// event is inserted and held until its write commits. Events are numbered
+// under it, so their IDs follow the order of the commits: a relay never sees
+// an event while one with a lower ID may still commit.
+const outboxWriteLockKey = 0x6f7574626f782d77 // "outbox-w"
+
+const lockOutboxWrite = `SELECT pg_advisory_xact_lock($1::bigint)`
+
+const insertOutbox = `
+	INSERT INTO public.outbox (event, post_id, payload)
+	VALUES ($1, $2, $3::jsonb)
+`
+
+// outboxArgs returns the arguments of insertOutbox for an event of post
+func outboxArgs(event string, post *models.Post) ([]interface{}, error) {
+	payload, err := json.Marshal(post)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{event, post.ID, string(payload)}, nil
+}
+
+// writeOutbox inserts the event of post in the transaction of its write, as
+// its last statement since the outbox stays locked until the commit
+func writeOutbox(ctx context.Context, tx *sql.Tx, event string, post *models.Post) error {
+	args, err := outboxArgs(event, post)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, lockOutboxWrite, int64(outboxWriteLockKey)); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, insertOutbox, args...)
+	return err
+}
+
+// writeOutboxPgx is writeOutbox for a pgx transaction
+func writeOutboxPgx(ctx context.Context, tx pgx.Tx, event string, post *models.Post) error {
+	args, err := outboxArgs(event, post)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, lockOutboxWrite, int64(outboxWriteLockKey)); err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, insertOutbox, args...)
+	return err
+}
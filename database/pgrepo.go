@@ -3,35 +3,104 @@ package database
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/freshusername/news-api/logging"
 	"github.com/freshusername/news-api/models"
+	"github.com/freshusername/news-api/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PostgresDBRepo struct {
+	// DB is the primary, which takes every write
 	DB *sql.DB
+	// Replicas, when set, serve the reads unless ctx asks for the primary
+	Replicas *ReplicaSet
+	// Timeout bounds each query, defaultTimeout when zero
+	Timeout time.Duration
 }
 
-const dbTimeout = time.Second * 3
+const defaultTimeout = time.Second * 3
+
+// timeout returns the query timeout
+func (m *PostgresDBRepo) timeout() time.Duration {
+	if m.Timeout > 0 {
+		return m.Timeout
+	}
+	return defaultTimeout
+}
+
+// queryError logs a failed query with the request-scoped logger carried by
+// ctx, so the SQL error is correlated with its request, marks the query's
+// span as failed and returns err.
+func queryError(ctx context.Context, method string, err error) error {
+	logging.FromContext(ctx).ErrorContext(ctx, "database query failed", "method", method, "error", err)
+
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// startSpan starts a client span for a repository method running query. Only
+// the sanitized statement is recorded, never the query arguments.
+func startSpan(ctx context.Context, method, query string) (context.Context, trace.Span) {
+	statement := tracing.SanitizeSQL(query)
+	operation, _, _ := strings.Cut(statement, " ")
+
+	return tracing.Tracer().Start(ctx, "db."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(statement),
+		),
+	)
+}
 
 func (m *PostgresDBRepo) Connection() *sql.DB {
 	return m.DB
 }
 
-func (m *PostgresDBRepo) GetAllPosts() ([]*models.Post, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+// Close closes the connection pools of the primary and the replicas
+func (m *PostgresDBRepo) Close() error {
+	err := m.DB.Close()
+	if m.Replicas != nil {
+		if replicaErr := m.Replicas.Close(); err == nil {
+			err = replicaErr
+		}
+	}
+	return err
+}
+
+// reader returns the pool a read should use: a usable replica, or the
+// primary when there is none or ctx was marked WithPrimary
+func (m *PostgresDBRepo) reader(ctx context.Context) *sql.DB {
+	if UsesPrimary(ctx) {
+		return m.DB
+	}
+	if replica := m.Replicas.Pick(); replica != nil {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("db.replica", replica.Name))
+		return replica.DB
+	}
+	return m.DB
+}
+
+func (m *PostgresDBRepo) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
-	query := `
-		SELECT id, title, content, created_at, updated_at
-		FROM public.posts
-		ORDER BY created_at DESC
-	`
+	ctx, span := startSpan(ctx, "GetAllPosts", selectAllPosts)
+	defer span.End()
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.reader(ctx).QueryContext(ctx, selectAllPosts)
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, "GetAllPosts", err)
 	}
 	defer rows.Close()
 
@@ -40,101 +109,171 @@ func (m *PostgresDBRepo) GetAllPosts() ([]*models.Post, error) {
 	for rows.Next() {
 		var post models.Post
 
-		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt)
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.CreatedAt, &post.UpdatedAt)
 		if err != nil {
-			return nil, err
+			return nil, queryError(ctx, "GetAllPosts", err)
 		}
 
 		posts = append(posts, &post)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, "GetAllPosts", err)
+	}
 
 	return posts, nil
 }
 
-func (repo *PostgresDBRepo) CreatePost(post *models.Post) (*models.Post, error) {
-	query := `
-        INSERT INTO public.posts (title, content, created_at, updated_at) 
-        VALUES ($1, $2, NOW(), NOW())
-        RETURNING id, title, content, created_at, updated_at
-    `
+// GetPostsPage returns at most limit posts, newest first, skipping the first offset
+func (m *PostgresDBRepo) GetPostsPage(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
 
-	row := repo.DB.QueryRow(query, post.Title, post.Content)
+	ctx, span := startSpan(ctx, "GetPostsPage", selectPostsPage)
+	defer span.End()
 
-	newPost := &models.Post{}
-	err := row.Scan(&newPost.ID, &newPost.Title, &newPost.Content, &newPost.CreatedAt, &newPost.UpdatedAt)
+	rows, err := m.reader(ctx).QueryContext(ctx, selectPostsPage, limit, offset)
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, "GetPostsPage", err)
 	}
+	defer rows.Close()
 
-	return newPost, nil
+	posts := []*models.Post{}
+
+	for rows.Next() {
+		var post models.Post
+
+		err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.CreatedAt, &post.UpdatedAt)
+		if err != nil {
+			return nil, queryError(ctx, "GetPostsPage", err)
+		}
+
+		posts = append(posts, &post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, "GetPostsPage", err)
+	}
+
+	return posts, nil
 }
 
-func (m *PostgresDBRepo) Healthcheck() (*models.Post, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+func (m *PostgresDBRepo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
-	query := `
-		SELECT id, title, content, created_at, updated_at
-		FROM public.posts
-		ORDER BY created_at DESC
-		LIMIT 1
-	`
+	ctx, span := startSpan(ctx, "GetPost", selectPost)
+	defer span.End()
 
-	row := m.DB.QueryRowContext(ctx, query)
+	row := m.reader(ctx).QueryRowContext(ctx, selectPost, id)
 
 	post := &models.Post{}
-	err := row.Scan(&post.ID, &post.Title, &post.Content, &post.CreatedAt, &post.UpdatedAt)
+	err := row.Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.CreatedAt, &post.UpdatedAt)
 	if err != nil {
-		return nil, err
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, queryError(ctx, "GetPost", err)
 	}
 
 	return post, nil
 }
 
-func (m *PostgresDBRepo) UpdatePost(id int32, post *models.Post) (*models.Post, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+func (repo *PostgresDBRepo) CreatePost(ctx context.Context, post *models.Post) (*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, repo.timeout())
 	defer cancel()
 
-	query := `
-		UPDATE public.posts
-		SET title = $2, content = $3, updated_at = NOW()
-		WHERE id = $1
-		RETURNING id, title, content, created_at, updated_at
-    `
+	ctx, span := startSpan(ctx, "CreatePost", insertPost)
+	defer span.End()
 
-	row := m.DB.QueryRowContext(ctx, query, id, post.Title, post.Content)
+	newPost := &models.Post{}
+	err := repo.inTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, insertPost, post.Title, post.Content, post.Category)
+		if err := row.Scan(&newPost.ID, &newPost.Title, &newPost.Content, &newPost.Category, &newPost.CreatedAt, &newPost.UpdatedAt); err != nil {
+			return err
+		}
+		return writeOutbox(ctx, tx, EventPostCreated, newPost)
+	})
+	if err != nil {
+		return nil, queryError(ctx, "CreatePost", err)
+	}
 
-	updatedPost := &models.Post{}
+	return newPost, nil
+}
 
-	err := row.Scan(&updatedPost.ID, &updatedPost.Title, &updatedPost.Content, &updatedPost.CreatedAt, &updatedPost.UpdatedAt)
+// inTx runs fn in a transaction on the primary, committed when fn succeeds
+func (m *PostgresDBRepo) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("no rows were updated, post may not exist")
-		}
-		return nil, err
+		return err
 	}
+	defer tx.Rollback()
 
-	return updatedPost, nil
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (m *PostgresDBRepo) DeletePost(id int32) (int32, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+// Healthcheck pings the database reads go to, which works whether or not there are posts
+func (m *PostgresDBRepo) Healthcheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
 	defer cancel()
 
-	query := `DELETE FROM public.posts WHERE id = $1`
+	ctx, span := startSpan(ctx, "Healthcheck", "SELECT 1")
+	defer span.End()
 
-	result, err := m.DB.ExecContext(ctx, query, id)
-	if err != nil {
-		return 0, err
+	if err := m.reader(ctx).PingContext(ctx); err != nil {
+		return queryError(ctx, "Healthcheck", err)
 	}
+	return nil
+}
 
-	rowsAffected, err := result.RowsAffected()
+func (m *PostgresDBRepo) UpdatePost(ctx context.Context, id int32, post *models.Post) (*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "UpdatePost", updatePost)
+	defer span.End()
+
+	updatedPost := &models.Post{}
+	err := m.inTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, updatePost, id, post.Title, post.Content, post.Category)
+		if err := row.Scan(&updatedPost.ID, &updatedPost.Title, &updatedPost.Content, &updatedPost.Category, &updatedPost.CreatedAt, &updatedPost.UpdatedAt); err != nil {
+			return err
+		}
+		return writeOutbox(ctx, tx, EventPostUpdated, updatedPost)
+	})
 	if err != nil {
-		return 0, err
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no rows were updated, post may not exist: %w", ErrNotFound)
+		}
+		return nil, queryError(ctx, "UpdatePost", err)
 	}
 
-	if rowsAffected == 0 {
-		return 0, errors.New("no rows were deleted, post may not exist")
+	return updatedPost, nil
+}
+
+func (m *PostgresDBRepo) DeletePost(ctx context.Context, id int32) (int32, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "DeletePost", deletePost)
+	defer span.End()
+
+	// the event carries the post as it was before the deletion
+	deletedPost := &models.Post{}
+	err := m.inTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, deletePost, id)
+		if err := row.Scan(&deletedPost.ID, &deletedPost.Title, &deletedPost.Content, &deletedPost.Category, &deletedPost.CreatedAt, &deletedPost.UpdatedAt); err != nil {
+			return err
+		}
+		return writeOutbox(ctx, tx, EventPostDeleted, deletedPost)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("no rows were deleted, post may not exist: %w", ErrNotFound)
+		}
+		return 0, queryError(ctx, "DeletePost", err)
 	}
 
 	return id, nil
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/freshusername/news-api/tracing"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestQuerySpans(t *testing.T) {
+	provider, exporter := tracing.NewInMemory()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(prev)
+
+	// nothing listens on port 1, so the query fails without a database
+	db, err := sql.Open("pgx", "postgres://postgres@127.0.0.1:1/news?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := &PostgresDBRepo{DB: db}
+
+	if _, err := repo.GetPost(context.Background(), 42); err == nil {
+		t.Fatal("expected a connection error")
+	}
+
+	spans := exporter.GetSpans().Snapshots()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name() != "db.GetPost" || span.SpanKind() != trace.SpanKindClient || span.Status().Code != codes.Error {
+		t.Errorf("unexpected span %q of kind %v with status %v", span.Name(), span.SpanKind(), span.Status())
+	}
+
+	want := map[attribute.Key]string{
+		"db.system":         "postgresql",
+		"db.operation.name": "SELECT",
+		"db.query.text":     "SELECT id, title, content, category, created_at, updated_at FROM public.posts WHERE id = $1",
+	}
+	for _, kv := range span.Attributes() {
+		if value, ok := want[kv.Key]; ok {
+			if kv.Value.AsString() != value {
+				t.Errorf("%s = %q, want %q", kv.Key, kv.Value.AsString(), value)
+			}
+			delete(want, kv.Key)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("span lacks attributes %v", want)
+	}
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PgxPoolRepo stores posts through a native pgxpool pool, skipping the
+// database/sql layer and its extra allocations and locking
+type PgxPoolRepo struct {
+	Pool *pgxpool.Pool
+	// DB serves callers that need database/sql, such as the migrations check.
+	// It opens its own connections, separate from Pool.
+	DB *sql.DB
+	// Timeout bounds each query, defaultTimeout when zero
+	Timeout time.Duration
+}
+
+func (m *PgxPoolRepo) timeout() time.Duration {
+	if m.Timeout > 0 {
+		return m.Timeout
+	}
+	return defaultTimeout
+}
+
+func (m *PgxPoolRepo) Connection() *sql.DB {
+	return m.DB
+}
+
+// Close closes the pool and the database/sql handle
+func (m *PgxPoolRepo) Close() error {
+	m.Pool.Close()
+	if m.DB != nil {
+		return m.DB.Close()
+	}
+	return nil
+}
+
+// Healthcheck acquires a connection from the pool and pings it
+func (m *PgxPoolRepo) Healthcheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "Healthcheck", "SELECT 1")
+	defer span.End()
+
+	if err := m.Pool.Ping(ctx); err != nil {
+		return queryError(ctx, "Healthcheck", err)
+	}
+	return nil
+}
+
+func (m *PgxPoolRepo) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "GetAllPosts", selectAllPosts)
+	defer span.End()
+
+	posts, err := m.queryPosts(ctx, selectAllPosts)
+	if err != nil {
+		return nil, queryError(ctx, "GetAllPosts", err)
+	}
+	return posts, nil
+}
+
+// GetPostsPage returns at most limit posts, newest first, skipping the first offset
+func (m *PgxPoolRepo) GetPostsPage(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "GetPostsPage", selectPostsPage)
+	defer span.End()
+
+	posts, err := m.queryPosts(ctx, selectPostsPage, limit, offset)
+	if err != nil {
+		return nil, queryError(ctx, "GetPostsPage", err)
+	}
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+	return posts, nil
+}
+
+// queryPosts runs a query returning whole posts
+func (m *PgxPoolRepo) queryPosts(ctx context.Context, query string, args ...interface{}) ([]*models.Post, error) {
+	rows, err := m.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		var post models.Post
+		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.CreatedAt, &post.UpdatedAt); err != nil {
+			return nil, err
+		}
+		posts = append(posts, &post)
+	}
+	return posts, rows.Err()
+}
+
+func (m *PgxPoolRepo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "GetPost", selectPost)
+	defer span.End()
+
+	post := &models.Post{}
+	err := m.Pool.QueryRow(ctx, selectPost, id).Scan(&post.ID, &post.Title, &post.Content, &post.Category, &post.CreatedAt, &post.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, queryError(ctx, "GetPost", err)
+	}
+
+	return post, nil
+}
+
+func (m *PgxPoolRepo) CreatePost(ctx context.Context, post *models.Post) (*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "CreatePost", insertPost)
+	defer span.End()
+
+	newPost := &models.Post{}
+	err := m.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, insertPost, post.Title, post.Content, post.Category).Scan(&newPost.ID, &newPost.Title, &newPost.Content, &newPost.Category, &newPost.CreatedAt, &newPost.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return writeOutboxPgx(ctx, tx, EventPostCreated, newPost)
+	})
+	if err != nil {
+		return nil, queryError(ctx, "CreatePost", err)
+	}
+
+	return newPost, nil
+}
+
+func (m *PgxPoolRepo) UpdatePost(ctx context.Context, id int32, post *models.Post) (*models.Post, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "UpdatePost", updatePost)
+	defer span.End()
+
+	updatedPost := &models.Post{}
+	err := m.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, updatePost, id, post.Title, post.Content, post.Category).Scan(&updatedPost.ID, &updatedPost.Title, &updatedPost.Content, &updatedPost.Category, &updatedPost.CreatedAt, &updatedPost.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return writeOutboxPgx(ctx, tx, EventPostUpdated, updatedPost)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("no rows were updated, post may not exist: %w", ErrNotFound)
+		}
+		return nil, queryError(ctx, "UpdatePost", err)
+	}
+
+	return updatedPost, nil
+}
+
+func (m *PgxPoolRepo) DeletePost(ctx context.Context, id int32) (int32, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout())
+	defer cancel()
+
+	ctx, span := startSpan(ctx, "DeletePost", deletePost)
+	defer span.End()
+
+	// the event carries the post as it was before the deletion
+	deletedPost := &models.Post{}
+	err := m.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, deletePost, id).Scan(&deletedPost.ID, &deletedPost.Title, &deletedPost.Content, &deletedPost.Category, &deletedPost.CreatedAt, &deletedPost.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return writeOutboxPgx(ctx, tx, EventPostDeleted, deletedPost)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, fmt.Errorf("no rows were deleted, post may not exist: %w", ErrNotFound)
+		}
+		return 0, queryError(ctx, "DeletePost", err)
+	}
+
+	return id, nil
+}
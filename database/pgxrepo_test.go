@@ -0,0 +1,42 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/freshusername/news-api/tracing"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestPgxPoolRepoQuerySpans(t *testing.T) {
+	provider, exporter := tracing.NewInMemory()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(prev)
+
+	// nothing listens on port 1; a lazy pool only connects on the first query
+	config, err := pgxpool.ParseConfig("postgres://postgres@127.0.0.1:1/news?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.LazyConnect = true
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &PgxPoolRepo{Pool: pool}
+	defer repo.Close()
+
+	_, err = repo.GetPost(context.Background(), 42)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a connection error, got %v", err)
+	}
+
+	spans := exporter.GetSpans().Snapshots()
+	if len(spans) != 1 || spans[0].Name() != "db.GetPost" || spans[0].Status().Code != codes.Error {
+		t.Fatalf("expected a single failed db.GetPost span, got %v", spans)
+	}
+}
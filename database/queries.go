@@ -0,0 +1,37 @@
+package database
+
+// The statements shared by the database/sql and pgxpool repositories
+const (
+	selectAllPosts = `
+		SELECT id, title, content, category, created_at, updated_at
+		FROM public.posts
+		ORDER BY created_at DESC
+	`
+	selectPostsPage = `
+		SELECT id, title, content, category, created_at, updated_at
+		FROM public.posts
+		ORDER BY created_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`
+	selectPost = `
+		SELECT id, title, content, category, created_at, updated_at
+		FROM public.posts
+		WHERE id = $1
+	`
+	insertPost = `
+		INSERT INTO public.posts (title, content, category, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING id, title, content, category, created_at, updated_at
+	`
+	updatePost = `
+		UPDATE public.posts
+		SET title = $2, content = $3, category = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, title, content, category, created_at, updated_at
+	`
+	deletePost = `
+		DELETE FROM public.posts
+		WHERE id = $1
+		RETURNING id, title, content, category, created_at, updated_at
+	`
+)
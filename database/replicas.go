@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// replicaLagQuery measures how far a standby is behind its primary. A standby
+// that replayed everything it received is not lagging, however long ago the
+// last transaction was; a server not in recovery reports NULL, read as 0.
+const replicaLagQuery = `
+	SELECT COALESCE(
+		CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END,
+	0)
+`
+
+// Replica is a read-only standby of the primary
+type Replica struct {
+	Name string
+	DB   *sql.DB
+
+	healthy atomic.Bool
+	lag     atomic.Int64
+}
+
+// Healthy reports whether the last check reached the replica
+func (r *Replica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+// Lag returns the replication lag measured by the last check
+func (r *Replica) Lag() time.Duration {
+	return time.Duration(r.lag.Load())
+}
+
+// ReplicaSet spreads reads over replicas in round-robin order, skipping those
+// that failed their last check or lag more than MaxLag behind the primary.
+// Replicas count as unhealthy until checked.
+type ReplicaSet struct {
+	Replicas []*Replica
+	MaxLag   time.Duration
+	Logger   *slog.Logger
+
+	next atomic.Uint64
+}
+
+// Pick returns the next usable replica, or nil when none is
+func (s *ReplicaSet) Pick() *Replica {
+	if s == nil || len(s.Replicas) == 0 {
+		return nil
+	}
+
+	start := s.next.Add(1)
+	for i := range s.Replicas {
+		r := s.Replicas[(start+uint64(i))%uint64(len(s.Replicas))]
+		if r.Healthy() && r.Lag() <= s.MaxLag {
+			return r
+		}
+	}
+	return nil
+}
+
+// Check pings every replica and measures its lag, logging changes of state
+func (s *ReplicaSet) Check(ctx context.Context, timeout time.Duration) {
+	for _, r := range s.Replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		var lagSeconds float64
+		err := r.DB.QueryRowContext(checkCtx, replicaLagQuery).Scan(&lagSeconds)
+		cancel()
+
+		lag := time.Duration(lagSeconds * float64(time.Second))
+		wasUsable := r.Healthy() && r.Lag() <= s.MaxLag
+		r.healthy.Store(err == nil)
+		if err == nil {
+			r.lag.Store(int64(lag))
+		}
+
+		switch usable := err == nil && lag <= s.MaxLag; {
+		case wasUsable && err != nil:
+			s.logger().Warn("replica unreachable, reading from the others", "replica", r.Name, "error", err)
+		case wasUsable && !usable:
+			s.logger().Warn("replica lagging, reading from the others", "replica", r.Name, "lag", lag, "max_lag", s.MaxLag)
+		case !wasUsable && usable:
+			s.logger().Info("replica usable", "replica", r.Name, "lag", lag)
+		}
+	}
+}
+
+// Monitor checks the replicas every interval until ctx is done
+func (s *ReplicaSet) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Check(ctx, interval)
+		}
+	}
+}
+
+// Close closes the connections of every replica
+func (s *ReplicaSet) Close() error {
+	var first error
+	for _, r := range s.Replicas {
+		if err := r.DB.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (s *ReplicaSet) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a context whose reads go to the primary, so a client
+// reads its own writes before they reach the replicas
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsesPrimary reports whether ctx was marked with WithPrimary
+func UsesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/logging"
+)
+
+func usableReplica(name string, lag time.Duration) *Replica {
+	r := &Replica{Name: name}
+	r.healthy.Store(true)
+	r.lag.Store(int64(lag))
+	return r
+}
+
+func TestReplicaSetPick(t *testing.T) {
+	down := &Replica{Name: "down"}
+	set := &ReplicaSet{
+		Replicas: []*Replica{usableReplica("a", 0), down, usableReplica("lagging", time.Minute), usableReplica("b", time.Second)},
+		MaxLag:   2 * time.Second,
+	}
+
+	seen := map[string]int{}
+	for i := 0; i < 8; i++ {
+		seen[set.Pick().Name]++
+	}
+	if len(seen) != 2 || seen["a"] == 0 || seen["b"] == 0 {
+		t.Errorf("expected reads spread over a and b only, got %v", seen)
+	}
+
+	empty := &ReplicaSet{Replicas: []*Replica{down}}
+	if r := empty.Pick(); r != nil {
+		t.Errorf("expected no replica, got %s", r.Name)
+	}
+	if r := (*ReplicaSet)(nil).Pick(); r != nil {
+		t.Error("expected a nil set to pick nothing")
+	}
+}
+
+func TestReplicaSetCheck(t *testing.T) {
+	// nothing listens on port 1
+	db, err := sql.Open("pgx", "postgres://postgres@127.0.0.1:1/news?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica := usableReplica("unreachable", 0)
+	replica.DB = db
+	set := &ReplicaSet{Replicas: []*Replica{replica}, Logger: logging.Discard()}
+	defer set.Close()
+
+	set.Check(context.Background(), time.Second)
+	if replica.Healthy() {
+		t.Error("expected the unreachable replica to be marked unhealthy")
+	}
+}
+
+func TestReaderRouting(t *testing.T) {
+	primary, replica := &sql.DB{}, &sql.DB{}
+	r := usableReplica("replica", 0)
+	r.DB = replica
+	repo := &PostgresDBRepo{DB: primary, Replicas: &ReplicaSet{Replicas: []*Replica{r}, MaxLag: time.Second}}
+
+	if repo.reader(context.Background()) != replica {
+		t.Error("expected reads to go to the replica")
+	}
+	if repo.reader(WithPrimary(context.Background())) != primary {
+		t.Error("expected WithPrimary reads to go to the primary")
+	}
+
+	r.lag.Store(int64(time.Minute))
+	if repo.reader(context.Background()) != primary {
+		t.Error("expected reads to fall back to the primary when the replica lags")
+	}
+	if (&PostgresDBRepo{DB: primary}).reader(context.Background()) != primary {
+		t.Error("expected reads to go to the primary without replicas")
+	}
+}
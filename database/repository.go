@@ -1,16 +1,32 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/freshusername/news-api/models"
 )
 
-type DatabaseRepo interface {
+// ErrNotFound is returned, possibly wrapped, when the requested post does not exist
+var ErrNotFound = errors.New("post not found")
+
+// Connector returns the database connection, nil until it is connected
+type Connector interface {
 	Connection() *sql.DB
-	Healthcheck() (*models.Post, error)
-	GetAllPosts() ([]*models.Post, error)
-	CreatePost(item *models.Post) (*models.Post, error)
-	UpdatePost(id int32, item *models.Post) (*models.Post, error)
-	DeletePost(id int32) (int32, error)
+}
+
+// DatabaseRepo stores posts. Every method takes the context of the request it
+// serves, which bounds the query and carries the request-scoped logger.
+type DatabaseRepo interface {
+	Connector
+	// Close releases every connection of the repository
+	Close() error
+	Healthcheck(ctx context.Context) error
+	GetAllPosts(ctx context.Context) ([]*models.Post, error)
+	GetPostsPage(ctx context.Context, limit, offset int) ([]*models.Post, error)
+	GetPost(ctx context.Context, id int32) (*models.Post, error)
+	CreatePost(ctx context.Context, item *models.Post) (*models.Post, error)
+	UpdatePost(ctx context.Context, id int32, item *models.Post) (*models.Post, error)
+	DeletePost(ctx context.Context, id int32) (int32, error)
 }
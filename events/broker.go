@@ -0,0 +1,225 @@
+// Package events fans post changes out to live subscribers, such as the
+// server-sent event stream, keeping the latest ones for clients resuming
+// after a disconnect.
+package events
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+)
+
+// Event types
+const (
+	Created = "created"
+	Updated = "updated"
+	Deleted = "deleted"
+	// Resync tells subscribers that changes were missed, so anything they
+	// derived from earlier events must be reloaded
+	Resync = "resync"
+)
+
+var (
+	// ErrSlowConsumer ends a subscription that fell too far behind the events
+	ErrSlowConsumer = errors.New("subscriber too slow, events dropped")
+	// ErrClosed ends every subscription when the broker is closed
+	ErrClosed = errors.New("broker closed")
+)
+
+// Event is a change of a post
+type Event struct {
+	// ID orders the events of a broker. It is unique across restarts, so a
+	// client cannot resume from another instance's events by mistake.
+	ID   string
+	Type string
+	// Post is the post after it was created or updated, and before it was
+	// deleted. Resyncs have none.
+	Post *models.Post
+	At   time.Time
+
+	seq uint64
+}
+
+// Category returns the category of the event's post, empty for resyncs
+func (e Event) Category() string {
+	if e.Post == nil {
+		return ""
+	}
+	return e.Post.Category
+}
+
+// Broker publishes events to its subscribers, keeping the last ones for
+// replay. Publishing never blocks: a subscriber whose queue is full is
+// dropped with ErrSlowConsumer and has to resume from its last event.
+type Broker struct {
+	// epoch prefixes the event IDs of this broker
+	epoch      string
+	replaySize int
+
+	mu     sync.Mutex
+	seq    uint64
+	replay []Event
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroker returns a broker keeping the last replay events
+func NewBroker(replay int) *Broker {
+	return &Broker{
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		replaySize: replay,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns e the next ID and sends it to every subscriber it matches
+func (b *Broker) Publish(e Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.seq = b.seq
+	e.ID = b.id(b.seq)
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	if b.replaySize > 0 {
+		if len(b.replay) == b.replaySize {
+			copy(b.replay, b.replay[1:])
+			b.replay = b.replay[:len(b.replay)-1]
+		}
+		b.replay = append(b.replay, e)
+	}
+
+	for sub := range b.subs {
+		if !sub.matches(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			b.drop(sub, ErrSlowConsumer)
+		}
+	}
+	return e
+}
+
+// Subscribe subscribes to the events match accepts; nil accepts all.
+// Resyncs are always delivered. Up to queue events wait for the subscriber
+// before it is dropped as too slow. With a lastID, the buffered events after it
+// are returned for replay. missed reports that they cannot be, as some are no
+// longer buffered or lastID is unknown, e.g. from before a restart; the
+// replay is then a single Resync with the ID of the latest event.
+func (b *Broker) Subscribe(lastID string, queue int, match func(Event) bool) (sub *Subscription, replay []Event, missed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{
+		broker: b,
+		match:  match,
+		events: make(chan Event, queue),
+	}
+	if b.closed {
+		sub.err = ErrClosed
+		close(sub.events)
+		return sub, nil, false
+	}
+	b.subs[sub] = struct{}{}
+
+	if lastID == "" {
+		return sub, nil, false
+	}
+	seq, ok := b.parseID(lastID)
+	// the oldest buffered event must directly follow lastID
+	oldest := b.seq + 1
+	if len(b.replay) > 0 {
+		oldest = b.replay[0].seq
+	}
+	if !ok || seq > b.seq || seq+1 < oldest {
+		resync := Event{Type: Resync, At: time.Now(), seq: b.seq}
+		if b.seq > 0 {
+			resync.ID = b.id(b.seq)
+		}
+		return sub, []Event{resync}, true
+	}
+	for _, e := range b.replay {
+		if e.seq > seq && sub.matches(e) {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay, missed
+}
+
+// Close ends every subscription with ErrClosed, and those made later
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.drop(sub, ErrClosed)
+	}
+}
+
+func (b *Broker) id(seq uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// parseID returns the sequence number of an ID of this broker
+func (b *Broker) parseID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// drop ends sub with err. b.mu must be held.
+func (b *Broker) drop(sub *Subscription, err error) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	sub.err = err
+	close(sub.events)
+}
+
+// Subscription receives the events of a broker
+type Subscription struct {
+	broker *Broker
+	match  func(Event) bool
+	events chan Event
+	// err is set, under the broker's lock, before events is closed
+	err error
+}
+
+// Events returns the events in publication order. It is closed when the
+// subscription ends; Err then tells why.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns why the subscription ended: ErrSlowConsumer, ErrClosed, or nil
+// when it was closed by its subscriber or is still active
+func (s *Subscription) Err() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.err
+}
+
+// Close unsubscribes
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.drop(s, nil)
+}
+
+func (s *Subscription) matches(e Event) bool {
+	return e.Type == Resync || s.match == nil || s.match(e)
+}
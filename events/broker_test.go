@@ -0,0 +1,112 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/freshusername/news-api/models"
+)
+
+func post(id int, category string) *models.Post {
+	return &models.Post{ID: id, Title: "Title", Content: "Content", Category: category}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	b := NewBroker(10)
+	sports, _, _ := b.Subscribe("", 10, func(e Event) bool {
+		return e.Category() == "sports"
+	})
+	all, _, _ := b.Subscribe("", 10, nil)
+
+	first := b.Publish(Event{Type: Created, Post: post(1, "sports")})
+	b.Publish(Event{Type: Created, Post: post(2, "news")})
+	b.Publish(Event{Type: Resync})
+
+	if first.ID == "" || first.At.IsZero() {
+		t.Errorf("expected an ID and time to be assigned, got %+v", first)
+	}
+	if got := len(all.Events()); got != 3 {
+		t.Errorf("expected 3 events for the unfiltered subscriber, got %d", got)
+	}
+	if got := len(sports.Events()); got != 2 {
+		t.Fatalf("expected the sports post and the resync, got %d events", got)
+	}
+	if e := <-sports.Events(); e.ID != first.ID || e.Post.ID != 1 {
+		t.Errorf("unexpected first event %+v", e)
+	}
+	if e := <-sports.Events(); e.Type != Resync {
+		t.Errorf("expected a resync, got %+v", e)
+	}
+}
+
+func TestBrokerReplay(t *testing.T) {
+	b := NewBroker(3)
+	var ids []string
+	for i := 1; i <= 5; i++ {
+		ids = append(ids, b.Publish(Event{Type: Created, Post: post(i, "")}).ID)
+	}
+
+	_, replay, missed := b.Subscribe(ids[2], 10, nil)
+	if missed || len(replay) != 2 || replay[0].ID != ids[3] || replay[1].ID != ids[4] {
+		t.Errorf("expected to replay the last 2 events, got %v (missed %v)", replay, missed)
+	}
+
+	_, replay, missed = b.Subscribe(ids[4], 10, nil)
+	if missed || len(replay) != 0 {
+		t.Errorf("expected nothing to replay when up to date, got %v (missed %v)", replay, missed)
+	}
+
+	// the event after ids[0] is no longer buffered
+	_, replay, missed = b.Subscribe(ids[0], 10, nil)
+	if !missed || len(replay) != 1 || replay[0].Type != Resync || replay[0].ID != ids[4] {
+		t.Errorf("expected a resync at the latest event, got %v (missed %v)", replay, missed)
+	}
+	// IDs of another broker, e.g. before a restart, are unknown
+	other := NewBroker(3)
+	other.epoch = "other"
+	if _, _, missed = b.Subscribe(other.Publish(Event{Type: Created}).ID, 10, nil); !missed {
+		t.Error("expected an unknown ID to count as missed")
+	}
+}
+
+func TestBrokerSlowConsumer(t *testing.T) {
+	b := NewBroker(10)
+	slow, _, _ := b.Subscribe("", 2, nil)
+	fast, _, _ := b.Subscribe("", 2, nil)
+
+	for i := 1; i <= 2; i++ {
+		b.Publish(Event{Type: Created, Post: post(i, "")})
+		<-fast.Events()
+	}
+	b.Publish(Event{Type: Created, Post: post(3, "")})
+
+	n := 0
+	for range slow.Events() {
+		n++
+	}
+	if n != 2 || !errors.Is(slow.Err(), ErrSlowConsumer) {
+		t.Errorf("expected the slow subscriber to be dropped after 2 events, got %d and %v", n, slow.Err())
+	}
+	if e := <-fast.Events(); e.Post.ID != 3 || fast.Err() != nil {
+		t.Errorf("expected the fast subscriber to keep up, got %+v and %v", e, fast.Err())
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker(10)
+	sub, _, _ := b.Subscribe("", 10, nil)
+	done, _, _ := b.Subscribe("", 10, nil)
+	done.Close()
+	b.Close()
+
+	if _, ok := <-sub.Events(); ok || !errors.Is(sub.Err(), ErrClosed) {
+		t.Errorf("expected the subscription to end with ErrClosed, got %v", sub.Err())
+	}
+	if done.Err() != nil {
+		t.Errorf("expected no error after unsubscribing, got %v", done.Err())
+	}
+	late, _, _ := b.Subscribe("", 10, nil)
+	if _, ok := <-late.Events(); ok || !errors.Is(late.Err(), ErrClosed) {
+		t.Errorf("expected subscribing to a closed broker to fail, got %v", late.Err())
+	}
+}
@@ -0,0 +1,153 @@
+// Package health runs the readiness checks of the API's dependencies.
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// DefaultTimeout bounds a check registered without a timeout
+const DefaultTimeout = 2 * time.Second
+
+// ShutdownCheck is the name of the failing check reported once Shutdown was called
+const ShutdownCheck = "shutdown"
+
+// CheckFunc reports a dependency as unhealthy by returning an error. It must return once ctx is done.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Registry holds named checks. It is safe for concurrent use.
+type Registry struct {
+	mu           sync.RWMutex
+	checks       []check
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a check run with at most timeout, replacing a check of the same name
+func (r *Registry) Register(name string, timeout time.Duration, fn CheckFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.checks {
+		if r.checks[i].name == name {
+			r.checks[i] = check{name, timeout, fn}
+			return
+		}
+	}
+	r.checks = append(r.checks, check{name, timeout, fn})
+}
+
+// Shutdown makes every following Run fail, so load balancers stop sending
+// traffic while the server drains its connections.
+func (r *Registry) Shutdown() {
+	r.shuttingDown.Store(true)
+}
+
+// Report is the outcome of running every check
+type Report struct {
+	// Status is ok when every check passed
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Healthy reports whether every check passed
+func (rep Report) Healthy() bool {
+	return rep.Status == StatusOK
+}
+
+// Failed returns the names of the failed checks, sorted
+func (rep Report) Failed() []string {
+	var failed []string
+	for name, result := range rep.Checks {
+		if result.Status != StatusOK {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// Run runs all checks concurrently, each bounded by its timeout
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = c.run(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	if r.shuttingDown.Load() {
+		report.Status = StatusFail
+		report.Checks[ShutdownCheck] = CheckResult{Status: StatusFail, Error: "shutting down"}
+	}
+	return report
+}
+
+func (c check) run(ctx context.Context) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.fn(ctx)
+	if err == nil && ctx.Err() != nil {
+		// a check that ignored its deadline still failed to answer in time
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: StatusOK, DurationMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = errors.New("timed out after " + c.timeout.String())
+		}
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
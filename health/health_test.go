@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistryRun(t *testing.T) {
+	r := NewRegistry()
+	r.Register("database", time.Second, func(ctx context.Context) error { return nil })
+	r.Register("cache", time.Second, func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := r.Run(context.Background())
+	if report.Healthy() || report.Status != StatusFail {
+		t.Errorf("expected a failing report, got %+v", report)
+	}
+	if got := report.Checks["database"]; got.Status != StatusOK || got.Error != "" {
+		t.Errorf("unexpected database result %+v", got)
+	}
+	if got := report.Checks["cache"]; got.Status != StatusFail || got.Error != "connection refused" {
+		t.Errorf("unexpected cache result %+v", got)
+	}
+	if failed := report.Failed(); len(failed) != 1 || failed[0] != "cache" {
+		t.Errorf("unexpected failed checks %v", failed)
+	}
+
+	// registering an existing name replaces the check
+	r.Register("cache", time.Second, func(ctx context.Context) error { return nil })
+	if report := r.Run(context.Background()); !report.Healthy() || len(report.Checks) != 2 {
+		t.Errorf("expected a healthy report with 2 checks, got %+v", report)
+	}
+}
+
+func TestRegistryTimeout(t *testing.T) {
+	r := NewRegistry()
+	r.Register("slow", 10*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	r.Register("stubborn", 10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+
+	report := r.Run(context.Background())
+	for _, name := range []string{"slow", "stubborn"} {
+		if got := report.Checks[name]; got.Status != StatusFail || got.Error != "timed out after 10ms" {
+			t.Errorf("%s: unexpected result %+v", name, got)
+		}
+	}
+}
+
+func TestEmptyRegistryIsHealthy(t *testing.T) {
+	if report := NewRegistry().Run(context.Background()); !report.Healthy() {
+		t.Errorf("expected a healthy report, got %+v", report)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	var h Heartbeat
+	check := h.Check(time.Minute)
+
+	if err := check(context.Background()); err == nil {
+		t.Error("expected an error before the first beat")
+	}
+
+	h.Beat()
+	if err := check(context.Background()); err != nil {
+		t.Errorf("unexpected error after a beat: %v", err)
+	}
+
+	h.last.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	if err := check(context.Background()); err == nil {
+		t.Error("expected an error for a stale heartbeat")
+	}
+}
+
+func TestRegistryShutdown(t *testing.T) {
+	r := NewRegistry()
+	r.Register("database", time.Second, func(ctx context.Context) error { return nil })
+	r.Shutdown()
+
+	report := r.Run(context.Background())
+	if report.Healthy() || report.Checks[ShutdownCheck].Error != "shutting down" || report.Checks["database"].Status != StatusOK {
+		t.Errorf("expected a failing report during shutdown, got %+v", report)
+	}
+}
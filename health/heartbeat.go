@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Heartbeat is beaten by a background worker on every cycle, and turns
+// readiness failing when the worker stalls.
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+// Beat records that the worker is alive
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Last returns the time of the last beat, or the zero time
+func (h *Heartbeat) Last() time.Time {
+	nanos := h.last.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// Check fails when the last beat is older than maxAge, or there was none
+func (h *Heartbeat) Check(maxAge time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		last := h.Last()
+		if last.IsZero() {
+			return errors.New("no heartbeat yet")
+		}
+		if age := time.Since(last); age > maxAge {
+			return fmt.Errorf("last heartbeat %s ago, more than %s", age.Round(time.Second), maxAge)
+		}
+		return nil
+	}
+}
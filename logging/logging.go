@@ -0,0 +1,102 @@
+// Package logging sets up structured log/slog loggers and carries the
+// request-scoped logger, request ID and principal through a context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats accepted by New
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New creates a logger writing records of at least level to w in the given format
+func New(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q, expected json or text", format)
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
+// Discard is a logger that drops every record, for tests
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.Level(127)}))
+}
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+	principalKey
+)
+
+// WithLogger returns a context carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default()
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithRequestID returns a context carrying the ID of the request it serves
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID carried by ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// principal is a slot filled by authentication deep in the handler chain
+// and read by the access log wrapped around it.
+type principal struct {
+	name string
+}
+
+// WithPrincipalSlot returns a context in which SetPrincipal can record who made the request
+func WithPrincipalSlot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, principalKey, &principal{})
+}
+
+// SetPrincipal records the authenticated caller of the request served with ctx
+func SetPrincipal(ctx context.Context, name string) {
+	if p, ok := ctx.Value(principalKey).(*principal); ok {
+		p.name = name
+	}
+}
+
+// Principal returns the caller recorded with SetPrincipal, or ""
+func Principal(ctx context.Context) string {
+	if p, ok := ctx.Value(principalKey).(*principal); ok {
+		return p.name
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, FormatJSON, slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Debug("hidden")
+	logger.Info("shown", "request_id", "abc")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "shown" || record["request_id"] != "abc" {
+		t.Errorf("unexpected record %v", record)
+	}
+
+	buf.Reset()
+	logger, err = New(&buf, "TEXT", slog.LevelDebug)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("shown")
+	if !strings.Contains(buf.String(), "level=DEBUG msg=shown") {
+		t.Errorf("unexpected text record %q", buf.String())
+	}
+
+	if _, err := New(&buf, "xml", slog.LevelInfo); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("warn")
+	if err != nil || level != slog.LevelWarn {
+		t.Errorf("got %v, %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if FromContext(ctx) != slog.Default() {
+		t.Error("expected the default logger without one in the context")
+	}
+
+	logger := Discard()
+	ctx = WithRequestID(WithLogger(ctx, logger), "req-1")
+	if FromContext(ctx) != logger || RequestID(ctx) != "req-1" {
+		t.Error("context lost the logger or request ID")
+	}
+
+	// the principal is only recorded where a slot exists
+	SetPrincipal(ctx, "alice")
+	if Principal(ctx) != "" {
+		t.Error("principal recorded without a slot")
+	}
+	ctx = WithPrincipalSlot(ctx)
+	SetPrincipal(context.WithValue(ctx, contextKey(99), "nested"), "alice")
+	if Principal(ctx) != "alice" {
+		t.Errorf("got principal %q", Principal(ctx))
+	}
+}
@@ -0,0 +1,120 @@
+// Package metrics collects Prometheus metrics of the API: HTTP traffic,
+// database pool and query latency, and business counters.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "news"
+
+// Post actions counted by PostsChanged
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// dbBuckets suit queries, which mostly take single milliseconds
+var dbBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+
+// Metrics owns a registry with the API's collectors
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	dbDuration   *prometheus.HistogramVec
+	dbErrors     *prometheus.CounterVec
+	posts        *prometheus.CounterVec
+}
+
+// New creates Metrics with the Go runtime and process collectors registered
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests served, by method, chi route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Time to serve HTTP requests, by method, chi route pattern and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Time spent in repository methods, by method.",
+			Buckets:   dbBuckets,
+		}, []string{"method"}),
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_errors_total",
+			Help:      "Repository method calls that failed, not counting missing posts, by method.",
+		}, []string{"method"}),
+		posts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "posts_total",
+			Help:      "Posts created, updated and deleted, by action.",
+		}, []string{"action"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbDuration,
+		m.dbErrors,
+		m.posts,
+	)
+
+	// start every action at zero so rates work from the first scrape
+	for _, action := range []string{ActionCreated, ActionUpdated, ActionDeleted} {
+		m.posts.WithLabelValues(action)
+	}
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Registry returns the registry, to register further collectors
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// RegisterDB exports the connection pool stats of db, labeled with dbName
+func (m *Metrics) RegisterDB(db *sql.DB, dbName string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// ObserveRequest records a served HTTP request. route must be a route
+// pattern, not the raw path, to keep the number of series bounded.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// PostsChanged counts a post created, updated or deleted
+func (m *Metrics) PostsChanged(action string) {
+	m.posts.WithLabelValues(action).Inc()
+}
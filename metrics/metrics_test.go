@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/models"
+	"github.com/jackc/pgx/v4/pgxpool"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// stubRepo fails GetPost with err and succeeds otherwise
+type stubRepo struct {
+	database.DatabaseRepo
+	err error
+}
+
+func (s *stubRepo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	return nil, s.err
+}
+
+func (s *stubRepo) CreatePost(ctx context.Context, item *models.Post) (*models.Post, error) {
+	return item, nil
+}
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+	m.ObserveRequest("GET", "/posts/{id}", 200, 20*time.Millisecond)
+	m.ObserveRequest("GET", "/posts/{id}", 200, 30*time.Millisecond)
+	m.ObserveRequest("GET", "/posts/{id}", 404, time.Millisecond)
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/posts/{id}", "200")); got != 2 {
+		t.Errorf("expected 2 requests, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.httpDuration); got != 2 {
+		t.Errorf("expected 2 duration series, got %d", got)
+	}
+}
+
+func TestInstrumentRepo(t *testing.T) {
+	m := New()
+	stub := &stubRepo{}
+	repo := m.InstrumentRepo(stub)
+	ctx := context.Background()
+
+	repo.CreatePost(ctx, &models.Post{})
+	stub.err = database.ErrNotFound
+	repo.GetPost(ctx, 1)
+	stub.err = fmt.Errorf("query: %w", errors.New("connection refused"))
+	repo.GetPost(ctx, 1)
+
+	if got := testutil.ToFloat64(m.posts.WithLabelValues(ActionCreated)); got != 1 {
+		t.Errorf("expected 1 created post, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.posts.WithLabelValues(ActionDeleted)); got != 0 {
+		t.Errorf("expected 0 deleted posts, got %v", got)
+	}
+	// a missing post is not a query error
+	if got := testutil.ToFloat64(m.dbErrors.WithLabelValues("GetPost")); got != 1 {
+		t.Errorf("expected 1 GetPost error, got %v", got)
+	}
+
+	expected := `
+		# HELP news_db_query_errors_total Repository method calls that failed, not counting missing posts, by method.
+		# TYPE news_db_query_errors_total counter
+		news_db_query_errors_total{method="GetPost"} 1
+	`
+	if err := testutil.CollectAndCompare(m.dbErrors, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.CollectAndCount(m.dbDuration); got != 2 {
+		t.Errorf("expected latency series for 2 methods, got %d", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	// opening does not connect, which is enough for pool stats
+	db, err := sql.Open("pgx", "postgres://127.0.0.1:1/news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := m.RegisterDB(db, "news"); err != nil {
+		t.Fatal(err)
+	}
+	m.PostsChanged(ActionUpdated)
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("got status %d", rr.Code)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{`news_posts_total{action="updated"} 1`, `go_sql_open_connections{db_name="news"} 0`, "go_goroutines"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output lacks %q", want)
+		}
+	}
+}
+
+func TestRegisterPool(t *testing.T) {
+	m := New()
+	// a lazy pool does not connect, which is enough for pool stats
+	config, err := pgxpool.ParseConfig("postgres://127.0.0.1:1/news?pool_max_conns=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.LazyConnect = true
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if err := m.RegisterPool(pool, "news"); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rr.Body.String()
+	for _, want := range []string{`pgxpool_max_conns{db_name="news"} 7`, `pgxpool_total_conns{db_name="news"} 0`, `pgxpool_acquires_total{db_name="news"} 0`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output lacks %q", want)
+		}
+	}
+}
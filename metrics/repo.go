@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/models"
+)
+
+// instrumentedRepo measures every call to the wrapped repository
+type instrumentedRepo struct {
+	repo    database.DatabaseRepo
+	metrics *Metrics
+}
+
+// InstrumentRepo wraps repo so the latency and failures of each method are
+// recorded, and successful writes are counted as post changes.
+func (m *Metrics) InstrumentRepo(repo database.DatabaseRepo) database.DatabaseRepo {
+	return &instrumentedRepo{repo: repo, metrics: m}
+}
+
+// observe records a call of method that started at start and returned err
+func (r *instrumentedRepo) observe(method string, start time.Time, err error) {
+	r.metrics.dbDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, database.ErrNotFound) {
+		r.metrics.dbErrors.WithLabelValues(method).Inc()
+	}
+}
+
+func (r *instrumentedRepo) Connection() *sql.DB {
+	return r.repo.Connection()
+}
+
+func (r *instrumentedRepo) Close() error {
+	return r.repo.Close()
+}
+
+func (r *instrumentedRepo) Healthcheck(ctx context.Context) error {
+	start := time.Now()
+	err := r.repo.Healthcheck(ctx)
+	r.observe("Healthcheck", start, err)
+	return err
+}
+
+func (r *instrumentedRepo) GetAllPosts(ctx context.Context) ([]*models.Post, error) {
+	start := time.Now()
+	posts, err := r.repo.GetAllPosts(ctx)
+	r.observe("GetAllPosts", start, err)
+	return posts, err
+}
+
+func (r *instrumentedRepo) GetPostsPage(ctx context.Context, limit, offset int) ([]*models.Post, error) {
+	start := time.Now()
+	posts, err := r.repo.GetPostsPage(ctx, limit, offset)
+	r.observe("GetPostsPage", start, err)
+	return posts, err
+}
+
+func (r *instrumentedRepo) GetPost(ctx context.Context, id int32) (*models.Post, error) {
+	start := time.Now()
+	post, err := r.repo.GetPost(ctx, id)
+	r.observe("GetPost", start, err)
+	return post, err
+}
+
+func (r *instrumentedRepo) CreatePost(ctx context.Context, item *models.Post) (*models.Post, error) {
+	start := time.Now()
+	post, err := r.repo.CreatePost(ctx, item)
+	r.observe("CreatePost", start, err)
+	if err == nil {
+		r.metrics.PostsChanged(ActionCreated)
+	}
+	return post, err
+}
+
+func (r *instrumentedRepo) UpdatePost(ctx context.Context, id int32, item *models.Post) (*models.Post, error) {
+	start := time.Now()
+	post, err := r.repo.UpdatePost(ctx, id, item)
+	r.observe("UpdatePost", start, err)
+	if err == nil {
+		r.metrics.PostsChanged(ActionUpdated)
+	}
+	return post, err
+}
+
+func (r *instrumentedRepo) DeletePost(ctx context.Context, id int32) (int32, error) {
+	start := time.Now()
+	deletedID, err := r.repo.DeletePost(ctx, id)
+	r.observe("DeletePost", start, err)
+	if err == nil {
+		r.metrics.PostsChanged(ActionDeleted)
+	}
+	return deletedID, err
+}
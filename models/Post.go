@@ -5,14 +5,16 @@ import (
 )
 
 // Post model
-// swagger:model Post
 type Post struct {
 	// example: 1
 	ID int `json:"id"`
 	// example: My First Post
-	Title string `json:"title"`
+	Title string `json:"title" validate:"required,notblank,len=1..255"`
 	// example: This is the content of my first post.
-	Content string `json:"content"`
+	Content string `json:"content" validate:"required,notblank,len=1..500"`
+	// Category groups posts, e.g. for filtering live updates. Empty when uncategorized.
+	// example: sports
+	Category string `json:"category,omitempty" validate:"len=0..50,match=^[a-z0-9-]*$"`
 	// example: 2024-02-015T00:00:00Z
 	CreatedAt time.Time `json:"created_at"`
 	// example: 2024-02-015T00:00:00Z
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultServer = "http://localhost:3000"
+
+// Config is the profiles file, by default ~/.config/newsctl/config.yaml:
+//
+//	current-profile: prod
+//	profiles:
+//	  local:
+//	    server: http://localhost:3000
+//	  prod:
+//	    server: https://news.example.com
+//	    token: secret
+type Config struct {
+	CurrentProfile string              `yaml:"current-profile,omitempty"`
+	Profiles       map[string]*Profile `yaml:"profiles,omitempty"`
+}
+
+// Profile is a server and the credentials used for it
+type Profile struct {
+	Server string `yaml:"server,omitempty"`
+	Token  string `yaml:"token,omitempty"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "newsctl.yaml"
+	}
+	return filepath.Join(dir, "newsctl", "config.yaml")
+}
+
+// loadConfig reads the profiles file; a missing file is an empty config
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// save writes the config readable only by its owner, since it holds tokens
+func (cfg *Config) save(path string) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// resolve picks the profile named by -profile or the current profile, with -server and -token applied on top
+func (c *cli) resolve() (Profile, error) {
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	var profile Profile
+	name := c.profile
+	if name == "" {
+		name = cfg.CurrentProfile
+	}
+	if name != "" {
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			return Profile{}, fmt.Errorf("profile %q not found in %s", name, c.configPath)
+		}
+		profile = *p
+	}
+
+	if c.server != "" {
+		profile.Server = c.server
+	}
+	if c.token != "" {
+		profile.Token = c.token
+	}
+	if profile.Server == "" {
+		profile.Server = defaultServer
+	}
+	return profile, nil
+}
+
+func (c *cli) config(command string, args []string) error {
+	switch command {
+	case "list":
+		return c.configList(args)
+	case "set":
+		return c.configSet(args)
+	case "use":
+		return c.configUse(args)
+	}
+	return fmt.Errorf("unknown command %q, expected list, set or use", "config "+command)
+}
+
+func (c *cli) configList(args []string) error {
+	fs := c.subcommand("config list", "")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CURRENT\tNAME\tSERVER\tTOKEN")
+	for _, name := range names {
+		p := cfg.Profiles[name]
+		current, token := "", ""
+		if name == cfg.CurrentProfile {
+			current = "*"
+		}
+		if p.Token != "" {
+			token = "<redacted>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", current, name, p.Server, token)
+	}
+	return tw.Flush()
+}
+
+func (c *cli) configSet(args []string) error {
+	fs := c.subcommand("config set", "<profile> [-server url] [-token token]")
+	server := fs.String("server", "", "API base URL")
+	token := fs.String("token", "", "API bearer token")
+	rest, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]*Profile)
+	}
+
+	name := rest[0]
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		p = &Profile{}
+		cfg.Profiles[name] = p
+	}
+	if *server != "" {
+		p.Server = *server
+	}
+	if *token != "" {
+		p.Token = *token
+	}
+	if cfg.CurrentProfile == "" {
+		cfg.CurrentProfile = name
+	}
+
+	return cfg.save(c.configPath)
+}
+
+func (c *cli) configUse(args []string) error {
+	fs := c.subcommand("config use", "<profile>")
+	rest, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[rest[0]]; !ok {
+		return fmt.Errorf("profile %q not found in %s", rest[0], c.configPath)
+	}
+
+	cfg.CurrentProfile = rest[0]
+	return cfg.save(c.configPath)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/freshusername/news-api/models"
+)
+
+// editMarker starts the help appended to the edited file. It and every line
+// after it are ignored, while other lines starting with '#' are content.
+const editMarker = "# ------------------------ >8 ------------------------"
+
+const editHelp = editMarker + `
+# Everything from the line above is ignored.
+# The first line is the title, the content follows after a blank line.
+# Save an empty file to cancel.
+`
+
+// errEditCancelled is returned when the edited file is empty or unchanged
+var errEditCancelled = errors.New("edit cancelled, no changes made")
+
+// editorCommand returns $VISUAL or $EDITOR split into arguments, falling back to vi
+func editorCommand() []string {
+	for _, key := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(key)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{"vi"}
+}
+
+// editPost opens post in the user's editor and returns the edited title and content
+func (c *cli) editPost(post *models.Post) (*models.Post, error) {
+	f, err := os.CreateTemp("", "newsctl-post-*.txt")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	original := formatEditable(post)
+	if _, err := f.WriteString(original); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	editor := editorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, c.stdout, c.stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running editor %s: %w", editor[0], err)
+	}
+
+	edited, err := os.ReadFile(f.Name())
+	if err != nil {
+		return nil, err
+	}
+	if string(edited) == original {
+		return nil, errEditCancelled
+	}
+	return parseEditable(edited)
+}
+
+func formatEditable(post *models.Post) string {
+	return post.Title + "\n\n" + post.Content + "\n\n" + editHelp
+}
+
+// parseEditable reads back a file written by formatEditable
+func parseEditable(data []byte) (*models.Post, error) {
+	lines := strings.Split(string(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.TrimSpace(lines[i]) == editMarker {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		return nil, errEditCancelled
+	}
+
+	title, content, _ := strings.Cut(text, "\n")
+	return &models.Post{
+		Title:   strings.TrimSpace(title),
+		Content: strings.TrimSpace(content),
+	}, nil
+}
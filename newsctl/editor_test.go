@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/freshusername/news-api/models"
+)
+
+func TestParseEditable(t *testing.T) {
+	post := &models.Post{Title: "Title", Content: "First line\n\nSecond paragraph"}
+
+	got, err := parseEditable([]byte(formatEditable(post)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != post.Title || got.Content != post.Content {
+		t.Errorf("round trip changed the post: %+v", got)
+	}
+
+	// lines starting with '#' are content, up to the help
+	post = &models.Post{Title: "#news", Content: "# Breaking\n\n#elections are today"}
+	got, err = parseEditable([]byte(formatEditable(post)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Title != post.Title || got.Content != post.Content {
+		t.Errorf("round trip dropped lines starting with '#': %+v", got)
+	}
+
+	if _, err := parseEditable([]byte("\n\n" + editHelp)); err != errEditCancelled {
+		t.Errorf("expected errEditCancelled, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Command newsctl manages posts of the news API from the command line.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const usage = `newsctl manages posts of the news API.
+
+Usage:
+  newsctl [global flags] posts list [-since 24h] [-limit n]
+  newsctl [global flags] posts get <id>
+  newsctl [global flags] posts create -title <title> (-content <text> | -content-file <file|->)
+  newsctl [global flags] posts edit <id>
+  newsctl [global flags] posts delete <id>
+  newsctl [global flags] config list
+  newsctl [global flags] config set <profile> [-server url] [-token token]
+  newsctl [global flags] config use <profile>
+
+Global flags:
+`
+
+// errUsage is returned for invalid command lines, after the usage was printed
+var errUsage = errors.New("invalid usage")
+
+// cli holds the global flags and the streams of one newsctl run
+type cli struct {
+	configPath string
+	profile    string
+	server     string
+	token      string
+	output     string
+
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	if err := c.run(os.Args[1:]); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "newsctl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func (c *cli) run(args []string) error {
+	fs := flag.NewFlagSet("newsctl", flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.configPath, "config", envOr("NEWSCTL_CONFIG", defaultConfigPath()), "path of the profiles file")
+	fs.StringVar(&c.profile, "profile", os.Getenv("NEWSCTL_PROFILE"), "profile to use instead of the current one")
+	fs.StringVar(&c.server, "server", os.Getenv("NEWSCTL_SERVER"), "API base URL, overrides the profile")
+	fs.StringVar(&c.token, "token", os.Getenv("NEWSCTL_TOKEN"), "API bearer token, overrides the profile")
+	fs.StringVar(&c.output, "o", formatTable, "output format: table, json or yaml")
+	fs.Usage = func() {
+		fmt.Fprint(c.stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+
+	if !validFormat(c.output) {
+		return fmt.Errorf("unknown output format %q, expected table, json or yaml", c.output)
+	}
+
+	rest := fs.Args()
+	if len(rest) < 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	switch rest[0] {
+	case "posts":
+		return c.posts(rest[1], rest[2:])
+	case "config":
+		return c.config(rest[1], rest[2:])
+	}
+
+	fs.Usage()
+	return errUsage
+}
+
+// parseInterspersed parses flags that may appear before, between or after positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// subcommand creates the flag set of a subcommand, printing its synopsis on errors
+func (c *cli) subcommand(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: newsctl %s %s\n", name, synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses a subcommand's arguments and checks the number of positional ones
+func (c *cli) parse(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, errUsage
+	}
+	if len(rest) != positional {
+		fs.Usage()
+		return nil, errUsage
+	}
+	return rest, nil
+}
+
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && strings.TrimSpace(value) != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+)
+
+// fakeAPI serves /posts from posts, newest first, and records the last created or updated post
+type fakeAPI struct {
+	posts []*models.Post
+	saved *models.Post
+	token string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.token = r.Header.Get("Authorization")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/posts" && r.Method == http.MethodGet {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		end := min(offset+limit, len(f.posts))
+		if offset > end {
+			offset = end
+		}
+		json.NewEncoder(w).Encode(f.posts[offset:end])
+		return
+	}
+
+	if r.Method == http.MethodPost || r.Method == http.MethodPut {
+		f.saved = &models.Post{}
+		json.NewDecoder(r.Body).Decode(f.saved)
+		f.saved.ID = 1
+		json.NewEncoder(w).Encode(f.saved)
+		return
+	}
+
+	id, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/posts/"))
+	for _, post := range f.posts {
+		if post.ID == id {
+			json.NewEncoder(w).Encode(post)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte(`{"type":"about:blank","title":"Not Found","status":404,"detail":"post not found"}`))
+}
+
+// runCLI runs newsctl against api with an empty profiles file and returns its stdout
+func runCLI(t *testing.T, api http.Handler, stdin string, args ...string) (string, error) {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	var stdout, stderr bytes.Buffer
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	args = append([]string{"-config", filepath.Join(t.TempDir(), "config.yaml"), "-server", srv.URL}, args...)
+	err := c.run(args)
+	return stdout.String(), err
+}
+
+func TestPostsListSince(t *testing.T) {
+	now := time.Now()
+	api := &fakeAPI{}
+	for i := 1; i <= 5; i++ {
+		api.posts = append(api.posts, &models.Post{ID: i, Title: "Post " + strconv.Itoa(i), CreatedAt: now.Add(-time.Duration(i) * 10 * time.Hour)})
+	}
+
+	out, err := runCLI(t, api, "", "-o", "json", "posts", "list", "-since", "24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var posts []*models.Post
+	if err := json.Unmarshal([]byte(out), &posts); err != nil {
+		t.Fatalf("invalid JSON output %q: %v", out, err)
+	}
+	if len(posts) != 2 || posts[0].ID != 1 || posts[1].ID != 2 {
+		t.Errorf("expected posts 1 and 2, got %s", out)
+	}
+}
+
+func TestPostsListTable(t *testing.T) {
+	api := &fakeAPI{posts: []*models.Post{{ID: 7, Title: "Hello"}}}
+
+	out, err := runCLI(t, api, "", "posts", "list", "-limit", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "ID  TITLE  CREATED  UPDATED\n7   Hello  -        -\n") {
+		t.Errorf("unexpected table:\n%s", out)
+	}
+}
+
+func TestPostsGetNotFound(t *testing.T) {
+	_, err := runCLI(t, &fakeAPI{}, "", "posts", "get", "3")
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found: post not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestPostsCreateFromStdin(t *testing.T) {
+	api := &fakeAPI{}
+
+	_, err := runCLI(t, api, "Body from stdin", "posts", "create", "-title", "Title", "-content-file", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.saved == nil || api.saved.Title != "Title" || api.saved.Content != "Body from stdin" {
+		t.Errorf("unexpected post sent: %+v", api.saved)
+	}
+}
+
+func TestPostsEdit(t *testing.T) {
+	// the "editor" replaces the title and content, keeping the help
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\n{ printf 'New title\\n\\nNew content\\n'; sed -n '/>8/,$p' \"$1\"; } > \"$1.new\" && mv \"$1.new\" \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	api := &fakeAPI{posts: []*models.Post{{ID: 1, Title: "Old title", Content: "Old content", Category: "world"}}}
+	if _, err := runCLI(t, api, "", "posts", "edit", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if api.saved == nil || api.saved.Title != "New title" || api.saved.Content != "New content" || api.saved.Category != "world" {
+		t.Errorf("unexpected post sent: %+v", api.saved)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	api := &fakeAPI{}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	newsctl := func(args ...string) (string, error) {
+		var stdout bytes.Buffer
+		c := &cli{stdin: strings.NewReader(""), stdout: &stdout, stderr: &bytes.Buffer{}}
+		err := c.run(append([]string{"-config", configPath}, args...))
+		return stdout.String(), err
+	}
+
+	if _, err := newsctl("config", "set", "local", "-server", "http://localhost:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newsctl("config", "set", "staging", "-server", srv.URL, "-token", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newsctl("config", "use", "staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newsctl("posts", "list"); err != nil {
+		t.Fatal(err)
+	}
+	if api.token != "Bearer secret" {
+		t.Errorf("expected the staging token, got %q", api.token)
+	}
+
+	out, err := newsctl("config", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "secret") || !strings.Contains(out, "*        staging") {
+		t.Errorf("unexpected profile list:\n%s", out)
+	}
+
+	if _, err := newsctl("-profile", "missing", "posts", "list"); err == nil {
+		t.Error("expected an error for a missing profile")
+	}
+}
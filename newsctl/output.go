@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+	"github.com/freshusername/news-api/openapi"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// maxTitleWidth is the number of title characters shown in the table view of a list
+const maxTitleWidth = 60
+
+func validFormat(format string) bool {
+	return format == formatTable || format == formatJSON || format == formatYAML
+}
+
+// printPosts writes a list of posts in the chosen format
+func printPosts(w io.Writer, format string, posts []*models.Post) error {
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+	if format != formatTable {
+		return printData(w, format, posts)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tCREATED\tUPDATED")
+	for _, post := range posts {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", post.ID, truncate(post.Title, maxTitleWidth), formatTime(post.CreatedAt), formatTime(post.UpdatedAt))
+	}
+	return tw.Flush()
+}
+
+// printPost writes a single post in the chosen format, with its full content
+func printPost(w io.Writer, format string, post *models.Post) error {
+	if format != formatTable {
+		return printData(w, format, post)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%d\n", post.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", post.Title)
+	fmt.Fprintf(tw, "Created:\t%s\n", formatTime(post.CreatedAt))
+	fmt.Fprintf(tw, "Updated:\t%s\n", formatTime(post.UpdatedAt))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%s\n", post.Content)
+	return err
+}
+
+// printData writes v as indented JSON, or as YAML with the same field names
+func printData(w io.Writer, format string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if format == formatYAML {
+		data, err = openapi.JSONToYAML(data)
+		if err != nil {
+			return err
+		}
+	} else {
+		data = append(data, '\n')
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-1]) + "…"
+}
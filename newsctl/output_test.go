@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+)
+
+func TestPrintPostYAML(t *testing.T) {
+	post := &models.Post{
+		ID:        1,
+		Title:     "Title",
+		Content:   "Content",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	var buf bytes.Buffer
+	if err := printPost(&buf, formatYAML, post); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id: 1\ntitle: Title\ncontent: Content\ncreated_at: \"2024-01-02T03:04:05Z\"\nupdated_at: \"2024-01-02T03:04:05Z\"\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("Привіт, світе", 6); got != "Приві…" {
+		t.Errorf("got %q", got)
+	}
+	if got := truncate("short", 6); got != "short" {
+		t.Errorf("got %q", got)
+	}
+}
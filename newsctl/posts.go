@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/freshusername/news-api/client"
+	"github.com/freshusername/news-api/models"
+)
+
+// listPageSize is the number of posts fetched per request by posts list
+const listPageSize = 100
+
+func (c *cli) posts(command string, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch command {
+	case "list":
+		return c.postsList(ctx, args)
+	case "get":
+		return c.postsGet(ctx, args)
+	case "create":
+		return c.postsCreate(ctx, args)
+	case "edit":
+		return c.postsEdit(ctx, args)
+	case "delete":
+		return c.postsDelete(ctx, args)
+	}
+	return fmt.Errorf("unknown command %q, expected list, get, create, edit or delete", "posts "+command)
+}
+
+// client creates an API client for the resolved profile
+func (c *cli) client() (*client.Client, error) {
+	profile, err := c.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Option{client.WithUserAgent("newsctl")}
+	if profile.Token != "" {
+		opts = append(opts, client.WithToken(profile.Token))
+	}
+	return client.New(profile.Server, opts...)
+}
+
+func (c *cli) postsList(ctx context.Context, args []string) error {
+	fs := c.subcommand("posts list", "[-since duration] [-limit n]")
+	since := fs.Duration("since", 0, "only list posts created within this duration, e.g. 24h")
+	limit := fs.Int("limit", 0, "list at most n posts (0 lists all)")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *since < 0 || *limit < 0 {
+		return errors.New("-since and -limit must not be negative")
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	if *since > 0 {
+		cutoff = time.Now().Add(-*since)
+	}
+
+	pageSize := listPageSize
+	if *limit > 0 && *limit < pageSize {
+		pageSize = *limit
+	}
+
+	// posts come newest first, so the first one older than the cutoff ends the listing
+	var posts []*models.Post
+	it := api.Posts(pageSize)
+	for it.Next(ctx) {
+		post := it.Post()
+		if !cutoff.IsZero() && post.CreatedAt.Before(cutoff) {
+			break
+		}
+		posts = append(posts, post)
+		if *limit > 0 && len(posts) == *limit {
+			break
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	return printPosts(c.stdout, c.output, posts)
+}
+
+func (c *cli) postsGet(ctx context.Context, args []string) error {
+	fs := c.subcommand("posts get", "<id>")
+	rest, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest[0])
+	if err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	post, err := api.GetPost(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printPost(c.stdout, c.output, post)
+}
+
+func (c *cli) postsCreate(ctx context.Context, args []string) error {
+	fs := c.subcommand("posts create", "-title <title> (-content <text> | -content-file <file|->)")
+	title := fs.String("title", "", "title of the post")
+	content := fs.String("content", "", "content of the post")
+	contentFile := fs.String("content-file", "", "read the content from this file, or from stdin with -")
+	if _, err := c.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *content != "" && *contentFile != "" {
+		return errors.New("-content and -content-file are mutually exclusive")
+	}
+	if *contentFile != "" {
+		data, err := c.readFile(*contentFile)
+		if err != nil {
+			return err
+		}
+		*content = string(data)
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	post, err := api.CreatePost(ctx, &models.Post{Title: *title, Content: *content})
+	if err != nil {
+		return err
+	}
+	return printPost(c.stdout, c.output, post)
+}
+
+func (c *cli) postsEdit(ctx context.Context, args []string) error {
+	fs := c.subcommand("posts edit", "<id>")
+	rest, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest[0])
+	if err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	post, err := api.GetPost(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	edited, err := c.editPost(post)
+	if errors.Is(err, errEditCancelled) {
+		fmt.Fprintln(c.stderr, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// the update replaces every field, and only the title and content are edited
+	edited.Category = post.Category
+
+	updated, err := api.UpdatePost(ctx, id, edited)
+	if err != nil {
+		return err
+	}
+	return printPost(c.stdout, c.output, updated)
+}
+
+func (c *cli) postsDelete(ctx context.Context, args []string) error {
+	fs := c.subcommand("posts delete", "<id>")
+	rest, err := c.parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseID(rest[0])
+	if err != nil {
+		return err
+	}
+
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	if err := api.DeletePost(ctx, id); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "post %d deleted\n", id)
+	return nil
+}
+
+// readFile reads name, or stdin when name is "-"
+func (c *cli) readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+func parseID(s string) (int32, error) {
+	id, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid post id %q", s)
+	}
+	return int32(id), nil
+}
@@ -0,0 +1,26 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+// SpecJSON is the generated OpenAPI document of the API, regenerated with
+// `go generate ./api` whenever routes, models or validation rules change
+//
+//go:embed openapi.json
+var SpecJSON []byte
+
+// SpecYAML is SpecJSON in YAML form
+//
+//go:embed openapi.yaml
+var SpecYAML []byte
+
+// Load parses the embedded OpenAPI document
+func Load() (*Document, error) {
+	doc := new(Document)
+	if err := json.Unmarshal(SpecJSON, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
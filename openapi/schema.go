@@ -0,0 +1,217 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/freshusername/news-api/validation"
+)
+
+// Schema is a JSON Schema (draft 2020-12) object as used by OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// componentsPrefix is the $ref prefix of schemas held in Components
+const componentsPrefix = "#/components/schemas/"
+
+// Schemas generates schemas for Go types, following encoding/json naming and
+// translating `validate` tags into JSON Schema keywords. Named struct types
+// become reusable components referenced with $ref.
+type Schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+// NewSchemas creates an empty schema generator
+func NewSchemas() *Schemas {
+	return &Schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// Components returns the component schemas collected so far
+func (s *Schemas) Components() map[string]*Schema {
+	return s.components
+}
+
+// Component returns the component schema registered under $ref ref
+func (s *Schemas) Component(ref string) (*Schema, bool) {
+	schema, ok := s.components[strings.TrimPrefix(ref, componentsPrefix)]
+	return schema, ok
+}
+
+// Named registers struct type t as a component called name and returns a reference to it
+func (s *Schemas) Named(name string, t reflect.Type) *Schema {
+	s.names[t] = name
+	return s.For(t)
+}
+
+// For returns the schema of Go type t
+func (s *Schemas) For(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.For(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.For(t.Elem())}
+	case reflect.Struct:
+		return s.structRef(t)
+	}
+	// interfaces and anything else accept any JSON value
+	return &Schema{}
+}
+
+// structRef returns a $ref to the component for a named struct type, or the inline schema of an anonymous one
+func (s *Schemas) structRef(t reflect.Type) *Schema {
+	name, ok := s.names[t]
+	if !ok && t.Name() == "" {
+		return s.structSchema(t)
+	}
+	if !ok {
+		name = t.Name()
+		s.names[t] = name
+	}
+
+	if _, built := s.components[name]; !built {
+		// reserve the name first so self-referencing types terminate
+		s.components[name] = &Schema{}
+		*s.components[name] = *s.structSchema(t)
+	}
+	return &Schema{Ref: componentsPrefix + name}
+}
+
+func (s *Schemas) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// embedded structs without a JSON name are flattened by encoding/json
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := s.structSchema(field.Type)
+			for prop, propSchema := range embedded.Properties {
+				schema.Properties[prop] = propSchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := s.For(field.Type)
+		if applyRules(prop, validation.ParseTagRules(field.Tag.Get(validation.TagName))) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+	return schema
+}
+
+// applyRules translates validation rules into schema keywords, reporting
+// whether the value is required. Rules without a JSON Schema equivalent,
+// such as cross-field comparisons, are left out.
+func applyRules(schema *Schema, rules []validation.TagRule) bool {
+	required := false
+	for i, rule := range rules {
+		switch rule.Name {
+		case "required":
+			required = true
+			if schema.Type == "string" && schema.MinLength == nil {
+				schema.MinLength = intp(1)
+			}
+		case "len":
+			lo, hi, _ := strings.Cut(rule.Param, "..")
+			if min, err := strconv.Atoi(lo); err == nil {
+				schema.MinLength = intp(min)
+			}
+			if max, err := strconv.Atoi(hi); err == nil {
+				schema.MaxLength = intp(max)
+			}
+		case "notblank":
+			schema.Pattern = NotBlankPattern
+		case "match":
+			schema.Pattern = rule.Param
+		case "oneof":
+			schema.Enum = strings.Split(rule.Param, "|")
+		case "url":
+			schema.Format = "uri"
+		case "email":
+			schema.Format = "email"
+		case "min":
+			if min, err := strconv.ParseFloat(rule.Param, 64); err == nil {
+				schema.Minimum = float(min)
+			}
+		case "max":
+			if max, err := strconv.ParseFloat(rule.Param, 64); err == nil {
+				schema.Maximum = float(max)
+			}
+		case validation.DiveTag:
+			elem := schema.Items
+			if elem == nil {
+				elem = schema.AdditionalProperties
+			}
+			if elem != nil {
+				applyRules(elem, rules[i+1:])
+			}
+			return required
+		}
+	}
+	return required
+}
+
+func intp(n int) *int {
+	return &n
+}
+
+func float(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,85 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type node struct {
+	Name     string            `json:"name" validate:"required,len=1..10"`
+	Kind     string            `json:"kind" validate:"oneof=a|b"`
+	Link     string            `json:"link,omitempty" validate:"url"`
+	Children []*node           `json:"children"`
+	Labels   map[string]string `json:"labels" validate:"dive,notblank"`
+	Tags     []string          `json:"tags" validate:"dive,len=1..5"`
+	Weight   uint              `json:"weight" validate:"max=100"`
+	Seen     time.Time         `json:"seen"`
+	Skipped  string            `json:"-"`
+	internal string
+}
+
+func TestSchemasFor(t *testing.T) {
+	schemas := NewSchemas()
+
+	ref := schemas.For(reflect.TypeOf(&node{}))
+	if ref.Ref != "#/components/schemas/node" {
+		t.Fatalf("expected a component reference, got %+v", ref)
+	}
+
+	schema, ok := schemas.Component(ref.Ref)
+	if !ok {
+		t.Fatal("node component was not registered")
+	}
+
+	if !reflect.DeepEqual(schema.Required, []string{"name"}) {
+		t.Errorf("unexpected required list: %v", schema.Required)
+	}
+	if len(schema.Properties) != 8 {
+		t.Errorf("expected 8 properties, got %d", len(schema.Properties))
+	}
+
+	name := schema.Properties["name"]
+	if *name.MinLength != 1 || *name.MaxLength != 10 {
+		t.Errorf("unexpected name length bounds: %d..%d", *name.MinLength, *name.MaxLength)
+	}
+	if !reflect.DeepEqual(schema.Properties["kind"].Enum, []string{"a", "b"}) {
+		t.Errorf("unexpected kind enum: %v", schema.Properties["kind"].Enum)
+	}
+	if schema.Properties["link"].Format != "uri" {
+		t.Errorf("unexpected link format: %q", schema.Properties["link"].Format)
+	}
+	if schema.Properties["children"].Items.Ref != ref.Ref {
+		t.Errorf("expected children to reference node, got %+v", schema.Properties["children"].Items)
+	}
+	if schema.Properties["labels"].AdditionalProperties.Pattern != `\S` {
+		t.Errorf("expected dive rules on map values, got %+v", schema.Properties["labels"].AdditionalProperties)
+	}
+	if *schema.Properties["tags"].Items.MaxLength != 5 {
+		t.Errorf("expected dive rules on slice items, got %+v", schema.Properties["tags"].Items)
+	}
+	if weight := schema.Properties["weight"]; *weight.Minimum != 0 || *weight.Maximum != 100 {
+		t.Errorf("unexpected weight bounds: %+v", weight)
+	}
+	if schema.Properties["seen"].Format != "date-time" {
+		t.Errorf("unexpected seen format: %q", schema.Properties["seen"].Format)
+	}
+}
+
+func TestDocumentYAMLKeepsKeyOrder(t *testing.T) {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: "Test", Version: "1.0.0"},
+		Paths:   map[string]*PathItem{},
+	}
+
+	out, err := doc.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "openapi: 3.1.0\ninfo:\n  title: Test\n  version: 1.0.0\npaths: {}\ncomponents: {}\n"
+	if string(out) != want {
+		t.Errorf("unexpected YAML:\n%s\nwant:\n%s", out, want)
+	}
+}
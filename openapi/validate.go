@@ -0,0 +1,267 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/freshusername/news-api/validation"
+)
+
+// NotBlankPattern is the pattern the `notblank` validation rule translates
+// to; violations of it are reported with validation.CodeBlank
+const NotBlankPattern = `\S`
+
+// Validator checks decoded JSON values and raw parameters against the
+// schemas of a Document, reporting violations with the validation
+// package's error codes so they can be localized like any other.
+type Validator struct {
+	doc      *Document
+	patterns sync.Map // pattern string -> *regexp.Regexp
+}
+
+// NewValidator creates a Validator resolving $refs against doc
+func NewValidator(doc *Document) *Validator {
+	return &Validator{doc: doc}
+}
+
+// Document returns the document the validator checks against
+func (v *Validator) Document() *Document {
+	return v.doc
+}
+
+// Validate checks value, as decoded by encoding/json with UseNumber, against schema
+func (v *Validator) Validate(schema *Schema, value interface{}) []*validation.ValidationError {
+	var errs []*validation.ValidationError
+	v.validate(schema, value, "", &errs)
+	return errs
+}
+
+// ValidateParam converts the raw value of a path or query parameter to its schema type and checks it
+func (v *Validator) ValidateParam(param *Parameter, raw string) []*validation.ValidationError {
+	value, err := coerce(v.resolve(param.Schema), raw)
+	if err != nil {
+		err.Field = param.Name
+		return []*validation.ValidationError{err}
+	}
+
+	var errs []*validation.ValidationError
+	v.validate(param.Schema, value, param.Name, &errs)
+	return errs
+}
+
+// resolve follows a $ref into the document's components
+func (v *Validator) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = v.doc.Components.Schemas[schema.Ref[len(componentsPrefix):]]
+	}
+	return schema
+}
+
+func (v *Validator) validate(schema *Schema, value interface{}, path string, errs *[]*validation.ValidationError) {
+	schema = v.resolve(schema)
+	if schema == nil {
+		return
+	}
+
+	report := func(err *validation.ValidationError) {
+		if err != nil {
+			err.Field = path + err.Field
+			*errs = append(*errs, err)
+		}
+	}
+
+	if schema.Type != "" && !hasType(value, schema.Type) {
+		report(validation.NewError(validation.CodeType, validation.Params{"type": schema.Type}))
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := value[name]; !ok {
+				err := validation.NewError(validation.CodeRequired, nil)
+				err.Field = joinPath(path, name)
+				*errs = append(*errs, err)
+			}
+		}
+		for _, name := range sortedNames(value) {
+			if prop, ok := schema.Properties[name]; ok {
+				v.validate(prop, value[name], joinPath(path, name), errs)
+			} else if schema.AdditionalProperties != nil {
+				v.validate(schema.AdditionalProperties, value[name], fmt.Sprintf("%s[%s]", path, name), errs)
+			}
+		}
+
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range value {
+				v.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+
+	case string:
+		report(firstError(value, v.stringRules(schema)))
+
+	case json.Number:
+		n, _ := value.Float64()
+		report(firstError(n, numberRules(schema)))
+	}
+}
+
+// stringRules translates the string keywords of schema back into validation rules
+func (v *Validator) stringRules(schema *Schema) []validation.Rule {
+	var rules []validation.Rule
+	if schema.MinLength != nil || schema.MaxLength != nil {
+		min, max := 0, math.MaxInt
+		if schema.MinLength != nil {
+			min = *schema.MinLength
+		}
+		if schema.MaxLength != nil {
+			max = *schema.MaxLength
+		}
+		rules = append(rules, validation.Length(min, max))
+	}
+	if schema.Pattern == NotBlankPattern {
+		rules = append(rules, validation.NotBlank())
+	} else if schema.Pattern != "" {
+		rules = append(rules, validation.Match(v.pattern(schema.Pattern)))
+	}
+	if len(schema.Enum) > 0 {
+		rules = append(rules, validation.OneOf(schema.Enum...))
+	}
+	switch schema.Format {
+	case "uri":
+		rules = append(rules, validation.URL())
+	case "email":
+		rules = append(rules, validation.Email())
+	case "date-time":
+		rules = append(rules, dateTime)
+	}
+	return rules
+}
+
+// numberRules translates the numeric keywords of schema back into validation rules
+func numberRules(schema *Schema) []validation.Rule {
+	var rules []validation.Rule
+	if schema.Format == "int32" {
+		rules = append(rules, int32Range)
+	}
+	if schema.Minimum != nil {
+		rules = append(rules, validation.Min(*schema.Minimum))
+	}
+	if schema.Maximum != nil {
+		rules = append(rules, validation.Max(*schema.Maximum))
+	}
+	return rules
+}
+
+// firstError runs rules in order, stopping at the first error like validation.Validator does
+func firstError(value interface{}, rules []validation.Rule) *validation.ValidationError {
+	for _, rule := range rules {
+		if err := rule(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dateTime(value interface{}) *validation.ValidationError {
+	if _, err := time.Parse(time.RFC3339, value.(string)); err != nil {
+		return validation.NewError(validation.CodeType, validation.Params{"type": "date-time"})
+	}
+	return nil
+}
+
+func int32Range(value interface{}) *validation.ValidationError {
+	if n := value.(float64); n < math.MinInt32 || n > math.MaxInt32 {
+		return validation.NewError(validation.CodeType, validation.Params{"type": "int32"})
+	}
+	return nil
+}
+
+// pattern compiles and caches a schema pattern. Patterns come from the
+// generated document, where they were valid validation tag expressions.
+func (v *Validator) pattern(expr string) *regexp.Regexp {
+	if re, ok := v.patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(expr)
+	v.patterns.Store(expr, re)
+	return re
+}
+
+// hasType reports whether a decoded JSON value is of JSON Schema type typ
+func hasType(value interface{}, typ string) bool {
+	switch value := value.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	case json.Number:
+		if typ == "number" {
+			return true
+		}
+		if typ == "integer" {
+			f, err := value.Float64()
+			return err == nil && f == math.Trunc(f)
+		}
+		return false
+	case []interface{}:
+		return typ == "array"
+	case map[string]interface{}:
+		return typ == "object"
+	}
+	return false
+}
+
+// coerce converts a raw parameter value to the JSON type its schema declares
+func coerce(schema *Schema, raw string) (interface{}, *validation.ValidationError) {
+	if schema == nil {
+		return raw, nil
+	}
+
+	switch schema.Type {
+	case "integer":
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, validation.NewError(validation.CodeType, validation.Params{"type": "integer"})
+		}
+		return json.Number(raw), nil
+	case "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, validation.NewError(validation.CodeType, validation.Params{"type": "number"})
+		}
+		return json.Number(raw), nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, validation.NewError(validation.CodeType, validation.Params{"type": "boolean"})
+		}
+		return b, nil
+	}
+	return raw, nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// sortedNames returns the keys of m in order, so errors are reported deterministically
+func sortedNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
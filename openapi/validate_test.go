@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/freshusername/news-api/validation"
+)
+
+func decode(t *testing.T, raw string) interface{} {
+	t.Helper()
+
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestValidatorValidate(t *testing.T) {
+	schemas := NewSchemas()
+	ref := schemas.For(reflect.TypeOf(node{}))
+	validator := NewValidator(&Document{Components: Components{Schemas: schemas.Components()}})
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"valid", `{"name":"root","kind":"a","weight":3,"seen":"2024-02-14T12:00:00Z"}`, ""},
+		{"missing required", `{"kind":"a"}`, "name:required"},
+		{"wrong type", `{"name":1}`, "name:type"},
+		{"too long in runes", `{"name":"ЖЖЖЖЖЖЖЖЖЖЖ"}`, "name:length"},
+		{"enum", `{"name":"n","kind":"c"}`, "kind:one_of"},
+		{"format", `{"name":"n","link":"nope","seen":"yesterday"}`, "link:url seen:type"},
+		{"maximum", `{"name":"n","weight":101}`, "weight:max"},
+		{"integer", `{"name":"n","weight":1.5}`, "weight:type"},
+		{"nested items", `{"name":"n","children":[{"name":"ok"},{"name":""}]}`, "children[1].name:length"},
+		{"map values", `{"name":"n","labels":{"a":" "}}`, "labels[a]:blank"},
+		{"not an object", `[]`, ":type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range validator.Validate(ref, decode(t, tt.body)) {
+				got = append(got, err.Field+":"+err.Code)
+			}
+
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("got %q want %q", strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatorValidateParam(t *testing.T) {
+	validator := NewValidator(&Document{})
+	param := &Parameter{Name: "id", In: "path", Schema: &Schema{Type: "integer", Format: "int32", Minimum: float(1)}}
+
+	if errs := validator.ValidateParam(param, "42"); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	tests := map[string]string{
+		"abc":         validation.CodeType,
+		"0":           validation.CodeMin,
+		"99999999999": validation.CodeType,
+	}
+	for raw, code := range tests {
+		errs := validator.ValidateParam(param, raw)
+		if len(errs) != 1 || errs[0].Field != "id" || errs[0].Code != code {
+			t.Errorf("ValidateParam(%q): expected a single id:%s error, got %v", raw, code, errs)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Package outbox relays the post events written to the outbox table, in the
+// transaction of each write, to sinks such as the in-process bus. An event is
+// marked published only once every sink accepted it, so a crash at any point
+// leads to publishing it again rather than losing it: sinks receive every
+// event at least once, and should ignore those they have seen by ID. A tail
+// follows the outbox on every instance instead, such as for live streams.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/health"
+	"github.com/freshusername/news-api/models"
+	"github.com/jackc/pgtype"
+)
+
+// relayLockKey is the transaction-level advisory lock held while relaying a
+// batch, so that instances sharing the outbox relay one batch at a time
+const relayLockKey = 0x6f7574626f78 // "outbox"
+
+const (
+	lockRelay       = `SELECT pg_try_advisory_xact_lock($1::bigint)`
+	selectUnrelayed = `
+		SELECT id, event, payload::text, occurred_at
+		FROM public.outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`
+	markPublished = `UPDATE public.outbox SET published_at = now() WHERE id = ANY ($1::bigint[])`
+	// prunePublished deletes up to 1000 events published more than $1 milliseconds ago
+	prunePublished = `
+		DELETE FROM public.outbox
+		WHERE id IN (
+			SELECT id FROM public.outbox
+			WHERE published_at < now() - $1::bigint * interval '1 millisecond'
+			LIMIT 1000
+		)
+	`
+)
+
+// pruneInterval is how often published events are pruned
+const pruneInterval = time.Minute
+
+// Event is a post event of the outbox
+type Event struct {
+	// ID orders the events; a sink seeing an ID again received a duplicate
+	ID int64 `json:"id"`
+	// Type is one of the database.EventPost* types
+	Type string `json:"type"`
+	// Post is the post after it was created or updated, and before it was deleted
+	Post       *models.Post `json:"post"`
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// Sink receives the events of the outbox
+type Sink interface {
+	// Name identifies the sink in logs and errors
+	Name() string
+	// Publish delivers e. An error has the relay publish e again later,
+	// along with every event after it.
+	Publish(ctx context.Context, e Event) error
+}
+
+// Relay publishes the events of the outbox to its sinks, in the order of
+// their IDs, which is the order their writes committed in. Several instances
+// may run a relay; each batch is relayed by one.
+type Relay struct {
+	DB    database.Connector
+	Sinks []Sink
+	// BatchSize bounds the events read, and published, at once
+	BatchSize int
+	// PollInterval is the wait between reads while nothing is left to
+	// publish, unless Wake is called
+	PollInterval time.Duration
+	// Retention is how long published events are kept before being pruned
+	Retention time.Duration
+	// Heartbeat, when set, is beaten on every cycle for readiness checks
+	Heartbeat *health.Heartbeat
+	Logger    *slog.Logger
+
+	wake chan struct{}
+}
+
+// RelayOptions configure NewRelay
+type RelayOptions struct {
+	BatchSize    int
+	PollInterval time.Duration
+	Retention    time.Duration
+	Heartbeat    *health.Heartbeat
+	Logger       *slog.Logger
+}
+
+// NewRelay returns a relay of the outbox in db to sinks
+func NewRelay(db database.Connector, sinks []Sink, opts RelayOptions) *Relay {
+	return &Relay{
+		DB:           db,
+		Sinks:        sinks,
+		BatchSize:    opts.BatchSize,
+		PollInterval: opts.PollInterval,
+		Retention:    opts.Retention,
+		Heartbeat:    opts.Heartbeat,
+		Logger:       opts.Logger,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Wake has a waiting relay read the outbox right away, e.g. when a post
+// changed. It never blocks.
+func (r *Relay) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays events until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	var pruned time.Time
+	for {
+		if r.Heartbeat != nil {
+			r.Heartbeat.Beat()
+		}
+		n, err := r.RelayBatch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			r.logger().Warn("relaying outbox events failed", "error", err, "published", n)
+		}
+		// a full batch suggests more are waiting
+		if err == nil && n == r.BatchSize {
+			continue
+		}
+		if err == nil && time.Since(pruned) >= pruneInterval {
+			if err := r.prune(ctx); err != nil && ctx.Err() == nil {
+				r.logger().Warn("pruning published outbox events failed", "error", err)
+			}
+			pruned = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-time.After(r.PollInterval):
+		}
+	}
+}
+
+// RelayBatch publishes up to BatchSize unpublished events and returns how
+// many were published. Without the relay lock, held by another instance,
+// it publishes none.
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	db := r.DB.Connection()
+	if db == nil {
+		return 0, database.ErrUnavailable
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, lockRelay, relayLockKey).Scan(&locked); err != nil || !locked {
+		return 0, err
+	}
+
+	events, err := readEvents(ctx, tx, selectUnrelayed, r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	n, publishErr := Publish(ctx, r.Sinks, events)
+	if n == 0 {
+		return 0, publishErr
+	}
+
+	ids := make([]int64, n)
+	for i, e := range events[:n] {
+		ids[i] = e.ID
+	}
+	array := &pgtype.Int8Array{}
+	if err := array.Set(ids); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, markPublished, array); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, publishErr
+}
+
+// Publish passes events to every sink in order, stopping at the first
+// failure, and returns how many every sink accepted
+func Publish(ctx context.Context, sinks []Sink, events []Event) (int, error) {
+	for i, e := range events {
+		for _, sink := range sinks {
+			if err := sink.Publish(ctx, e); err != nil {
+				return i, fmt.Errorf("publishing event %d to %s: %w", e.ID, sink.Name(), err)
+			}
+		}
+	}
+	return len(events), nil
+}
+
+// queryer is a *sql.DB or a *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// readEvents reads the events selected by query, such as selectUnrelayed
+func readEvents(ctx context.Context, q queryer, query string, args ...interface{}) ([]Event, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var payload string
+		if err := rows.Scan(&e.ID, &e.Type, &payload, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(payload), &e.Post); err != nil {
+			return nil, fmt.Errorf("decoding outbox event %d: %w", e.ID, err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// prune deletes events published longer than Retention ago
+func (r *Relay) prune(ctx context.Context) error {
+	db := r.DB.Connection()
+	if db == nil {
+		return database.ErrUnavailable
+	}
+	_, err := db.ExecContext(ctx, prunePublished, r.Retention.Milliseconds())
+	return err
+}
+
+func (r *Relay) logger() *slog.Logger {
+	if r.Logger != nil {
+		return r.Logger
+	}
+	return slog.Default()
+}
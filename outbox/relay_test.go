@@ -0,0 +1,89 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+)
+
+// recordingSink keeps the IDs it received and fails on the ID in failOn
+type recordingSink struct {
+	ids    []int64
+	failOn int64
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Publish(ctx context.Context, e Event) error {
+	if e.ID == s.failOn {
+		return errors.New("down")
+	}
+	s.ids = append(s.ids, e.ID)
+	return nil
+}
+
+func TestPublishStopsAtFirstFailure(t *testing.T) {
+	first := &recordingSink{}
+	second := &recordingSink{failOn: 2}
+	events := []Event{testEvent(1), testEvent(2), testEvent(3)}
+
+	n, err := Publish(context.Background(), []Sink{first, second}, events)
+	if n != 1 || err == nil {
+		t.Fatalf("expected 1 event published before the failure, got %d, %v", n, err)
+	}
+	// the first sink received event 2, and will again when it is retried
+	if len(first.ids) != 2 || len(second.ids) != 1 {
+		t.Errorf("expected the first sink to receive 1 and 2, the second only 1, got %v and %v", first.ids, second.ids)
+	}
+
+	n, err = Publish(context.Background(), []Sink{first}, events)
+	if n != 3 || err != nil {
+		t.Errorf("expected every event published, got %d, %v", n, err)
+	}
+}
+
+// disconnected is a database.Connector not connected yet
+type disconnected struct{}
+
+func (disconnected) Connection() *sql.DB {
+	return nil
+}
+
+func TestRelayBatchUnavailable(t *testing.T) {
+	relay := NewRelay(disconnected{}, nil, RelayOptions{BatchSize: 10})
+	if _, err := relay.RelayBatch(context.Background()); !errors.Is(err, database.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable before connecting, got %v", err)
+	}
+}
+
+func TestWakeNeverBlocks(t *testing.T) {
+	relay := NewRelay(disconnected{}, nil, RelayOptions{})
+	relay.Wake()
+	relay.Wake()
+	if len(relay.wake) != 1 {
+		t.Errorf("expected a single pending wake-up, got %d", len(relay.wake))
+	}
+}
+
+func TestRelayRunStops(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	relay := NewRelay(disconnected{}, nil, RelayOptions{BatchSize: 10, PollInterval: 10 * time.Millisecond})
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return when its context is done")
+	}
+}
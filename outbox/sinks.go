@@ -0,0 +1,118 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sink names selectable in the configuration
+const (
+	SinkBus    = "bus"
+	SinkFile   = "file"
+	SinkStdout = "stdout"
+)
+
+// Bus is a sink passing events to in-process handlers, such as the one
+// queueing webhook deliveries
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []busHandler
+}
+
+type busHandler struct {
+	name   string
+	handle func(context.Context, Event) error
+}
+
+// NewBus returns a bus without handlers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe adds a handler, called with every event in order. An error has
+// the event published again later, to every handler.
+func (b *Bus) Subscribe(name string, handle func(context.Context, Event) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, busHandler{name: name, handle: handle})
+}
+
+func (b *Bus) Name() string {
+	return SinkBus
+}
+
+// Publish calls the handlers in the order they subscribed, stopping at the first failure
+func (b *Bus) Publish(ctx context.Context, e Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		if err := h.handle(ctx, e); err != nil {
+			return fmt.Errorf("%s: %w", h.name, err)
+		}
+	}
+	return nil
+}
+
+// WriterSink writes every event as a line of JSON
+type WriterSink struct {
+	name string
+
+	mu sync.Mutex
+	w  io.Writer
+	// file is set for the file sink, synced after every event
+	file *os.File
+}
+
+// NewWriterSink returns a sink called name writing to w
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{name: name, w: w}
+}
+
+// NewStdoutSink returns a sink writing to standard output
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(SinkStdout, os.Stdout)
+}
+
+// NewFileSink returns a sink appending to the file at path, created when
+// missing. Every event is synced to disk before it counts as published.
+func NewFileSink(path string) (*WriterSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterSink{name: SinkFile, w: f, file: f}, nil
+}
+
+func (s *WriterSink) Name() string {
+	return s.name
+}
+
+func (s *WriterSink) Publish(ctx context.Context, e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(line); err != nil {
+		return err
+	}
+	if s.file != nil {
+		return s.file.Sync()
+	}
+	return nil
+}
+
+// Close closes the file of a file sink
+func (s *WriterSink) Close() error {
+	if s.file != nil {
+		return s.file.Close()
+	}
+	return nil
+}
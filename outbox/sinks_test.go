@@ -0,0 +1,92 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freshusername/news-api/models"
+)
+
+func testEvent(id int64) Event {
+	return Event{ID: id, Type: "post.created", Post: &models.Post{ID: int(id), Title: "Title"}, OccurredAt: time.Now().UTC()}
+}
+
+func TestBusPublishesInOrder(t *testing.T) {
+	bus := NewBus()
+	var calls []string
+	bus.Subscribe("first", func(ctx context.Context, e Event) error {
+		calls = append(calls, "first")
+		return nil
+	})
+	bus.Subscribe("second", func(ctx context.Context, e Event) error {
+		calls = append(calls, "second")
+		if e.ID == 2 {
+			return errors.New("down")
+		}
+		return nil
+	})
+
+	if err := bus.Publish(context.Background(), testEvent(1)); err != nil {
+		t.Fatal(err)
+	}
+	err := bus.Publish(context.Background(), testEvent(2))
+	if err == nil || !strings.HasPrefix(err.Error(), "second: ") {
+		t.Errorf("expected the failing handler named in the error, got %v", err)
+	}
+	if strings.Join(calls, ",") != "first,second,first,second" {
+		t.Errorf("expected every handler called in order, got %v", calls)
+	}
+}
+
+func TestWriterSinkWritesJSONLines(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewWriterSink("test", &out)
+	for id := range int64(2) {
+		if err := sink.Publish(context.Background(), testEvent(id+1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", out.String())
+	}
+	var e Event
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != 2 || e.Type != "post.created" || e.Post.Title != "Title" {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestFileSinkAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	for id := range int64(2) {
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Publish(context.Background(), testEvent(id+1)); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("expected the second sink to append, got %d lines", n)
+	}
+}
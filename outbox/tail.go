@@ -0,0 +1,137 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/health"
+)
+
+const (
+	selectLastEvent = `SELECT coalesce(max(id), 0) FROM public.outbox`
+	selectAfter     = `
+		SELECT id, event, payload::text, occurred_at
+		FROM public.outbox
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+)
+
+// Tail follows the events committed to the outbox from when it starts,
+// published or not. Unlike a relay it takes no lock and marks nothing, so
+// every instance runs its own, e.g. to stream the events to its clients. As
+// the IDs follow the commit order, reading after the last one seen misses
+// no event.
+type Tail struct {
+	DB database.Connector
+	// BatchSize bounds the events read at once
+	BatchSize int
+	// PollInterval is the wait between reads while nothing new was
+	// committed, unless Wake is called
+	PollInterval time.Duration
+	// Heartbeat, when set, is beaten on every cycle for readiness checks
+	Heartbeat *health.Heartbeat
+	Logger    *slog.Logger
+
+	wake chan struct{}
+	// last is the ID of the last event read, -1 until the end of the outbox
+	// was read on start
+	last int64
+}
+
+// TailOptions configure NewTail
+type TailOptions struct {
+	BatchSize    int
+	PollInterval time.Duration
+	Heartbeat    *health.Heartbeat
+	Logger       *slog.Logger
+}
+
+// NewTail returns a tail of the outbox in db
+func NewTail(db database.Connector, opts TailOptions) *Tail {
+	return &Tail{
+		DB:           db,
+		BatchSize:    opts.BatchSize,
+		PollInterval: opts.PollInterval,
+		Heartbeat:    opts.Heartbeat,
+		Logger:       opts.Logger,
+		wake:         make(chan struct{}, 1),
+		last:         -1,
+	}
+}
+
+// Wake has a waiting tail read the outbox right away, e.g. when a post
+// changed. It never blocks.
+func (t *Tail) Wake() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run passes the events committed after it started to handle, in order,
+// until ctx is done
+func (t *Tail) Run(ctx context.Context, handle func(Event)) {
+	for {
+		if t.Heartbeat != nil {
+			t.Heartbeat.Beat()
+		}
+		n, err := t.Next(ctx, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			t.logger().Warn("reading outbox events failed", "error", err)
+		}
+		// a full batch suggests more are waiting
+		if err == nil && n == t.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.wake:
+		case <-time.After(t.PollInterval):
+		}
+	}
+}
+
+// Next passes up to BatchSize events committed since the previous call to
+// handle and returns how many it passed. The first call only finds where the
+// outbox ends.
+func (t *Tail) Next(ctx context.Context, handle func(Event)) (int, error) {
+	db := t.DB.Connection()
+	if db == nil {
+		return 0, database.ErrUnavailable
+	}
+
+	if t.last < 0 {
+		var last int64
+		if err := db.QueryRowContext(ctx, selectLastEvent).Scan(&last); err != nil {
+			return 0, err
+		}
+		t.last = last
+		return 0, nil
+	}
+
+	events, err := readEvents(ctx, db, selectAfter, t.last, t.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		handle(e)
+		t.last = e.ID
+	}
+	return len(events), nil
+}
+
+func (t *Tail) logger() *slog.Logger {
+	if t.Logger != nil {
+		return t.Logger
+	}
+	return slog.Default()
+}
@@ -0,0 +1,19 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/freshusername/news-api/database"
+)
+
+func TestTailNextUnavailable(t *testing.T) {
+	tail := NewTail(disconnected{}, TailOptions{BatchSize: 10})
+	if _, err := tail.Next(context.Background(), func(Event) {}); !errors.Is(err, database.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable before connecting, got %v", err)
+	}
+	if tail.last != -1 {
+		t.Errorf("expected the start still to be read, got %d", tail.last)
+	}
+}
@@ -0,0 +1,104 @@
+// Package tracing sets up OpenTelemetry tracing with W3C trace context
+// propagation and an OTLP, console or in-memory span exporter.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters accepted by Setup, named like the values of OTEL_TRACES_EXPORTER
+const (
+	ExporterNone    = "none"
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console"
+)
+
+// instrumentationName identifies the spans created by this module
+const instrumentationName = "github.com/freshusername/news-api"
+
+// Config selects where spans go and how the service is described
+type Config struct {
+	// Exporter is one of none, otlp or console
+	Exporter       string
+	ServiceName    string
+	ServiceVersion string
+	// Console receives the spans of the console exporter
+	Console io.Writer
+}
+
+// Setup installs the W3C trace context propagator and a global tracer
+// provider exporting to cfg.Exporter. The OTLP exporter is configured through
+// the standard OTEL_EXPORTER_OTLP_* variables, the sampler through
+// OTEL_TRACES_SAMPLER and the resource through OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES. The returned function flushes and stops the provider.
+func Setup(ctx context.Context, cfg Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	case ExporterConsole:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(cfg.Console))
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q, expected none, otlp or console", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName), semconv.ServiceVersion(cfg.ServiceVersion)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("describing the service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// NewInMemory returns a provider that records spans synchronously into the
+// returned exporter, so tests can assert them without a collector.
+func NewInMemory() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+// Tracer returns the tracer of this module from the global provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`(^|[^$\w.])-?\d+(?:\.\d+)?\b`)
+)
+
+// SanitizeSQL prepares a statement for a span attribute: whitespace is
+// collapsed and string and numeric literals are replaced by ?, so values
+// inlined into a query never leave the process. Placeholders like $1 are kept.
+func SanitizeSQL(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	query = stringLiteral.ReplaceAllString(query, "?")
+	return numericLiteral.ReplaceAllString(query, "${1}?")
+}
@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"\n\t\tSELECT id\n\t\tFROM public.posts\n\t\tWHERE id = $1\n", "SELECT id FROM public.posts WHERE id = $1"},
+		{"SELECT * FROM posts WHERE title = 'it''s secret' AND id > 42 LIMIT 1.5", "SELECT * FROM posts WHERE title = ? AND id > ? LIMIT ?"},
+		{"SELECT t2.id FROM t2 WHERE x=-7", "SELECT t2.id FROM t2 WHERE x=?"},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeSQL(tt.query); got != tt.want {
+			t.Errorf("SanitizeSQL(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSetupConsole(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterConsole, ServiceName: "news-api", Console: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := Tracer().Start(context.Background(), "test span")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"Name":"test span"`)) {
+		t.Errorf("span not exported to the console: %s", buf.String())
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"reflect"
+	"time"
+)
+
+// Error codes reported by the built-in cross-field rules
+const (
+	CodeBeforeField = "before_field"
+	CodeAfterField  = "after_field"
+	CodeEqualField  = "equal_field"
+)
+
+// CrossFieldRule validates a field's value against a sibling field of the same struct
+type CrossFieldRule struct {
+	// Field is the Go name of the sibling field
+	Field string
+	// Check compares value with other, the sibling's value found at otherPath
+	Check func(value, other interface{}, otherPath string) *ValidationError
+}
+
+// crossFieldFactories are the cross-field rules available to struct tags,
+// taking the Go name of the sibling field as parameter
+var crossFieldFactories = map[string]func(field string) CrossFieldRule{
+	"beforefield": BeforeField,
+	"afterfield":  AfterField,
+	"eqfield":     EqualField,
+}
+
+// BeforeField validates the time is strictly before the time in field.
+// Unset (zero) times on either side are not compared.
+func BeforeField(field string) CrossFieldRule {
+	return CrossFieldRule{
+		Field: field,
+		Check: func(value, other interface{}, otherPath string) *ValidationError {
+			t, bound, err := timePair(value, other)
+			if err != nil || t.IsZero() || bound.IsZero() {
+				return err
+			}
+			if !t.Before(bound) {
+				return NewError(CodeBeforeField, Params{"field": otherPath})
+			}
+			return nil
+		},
+	}
+}
+
+// AfterField validates the time is strictly after the time in field.
+// Unset (zero) times on either side are not compared.
+func AfterField(field string) CrossFieldRule {
+	return CrossFieldRule{
+		Field: field,
+		Check: func(value, other interface{}, otherPath string) *ValidationError {
+			t, bound, err := timePair(value, other)
+			if err != nil || t.IsZero() || bound.IsZero() {
+				return err
+			}
+			if !t.After(bound) {
+				return NewError(CodeAfterField, Params{"field": otherPath})
+			}
+			return nil
+		},
+	}
+}
+
+// EqualField validates the value equals the value of field, e.g. for password confirmation
+func EqualField(field string) CrossFieldRule {
+	return CrossFieldRule{
+		Field: field,
+		Check: func(value, other interface{}, otherPath string) *ValidationError {
+			if !reflect.DeepEqual(value, other) {
+				return NewError(CodeEqualField, Params{"field": otherPath})
+			}
+			return nil
+		},
+	}
+}
+
+// timePair unwraps value and other as times, dereferencing *time.Time
+func timePair(value, other interface{}) (time.Time, time.Time, *ValidationError) {
+	t, ok := asTime(value)
+	if !ok {
+		return time.Time{}, time.Time{}, notA("time")
+	}
+	bound, ok := asTime(other)
+	if !ok {
+		return time.Time{}, time.Time{}, notA("time")
+	}
+	return t, bound, nil
+}
+
+func asTime(value interface{}) (time.Time, bool) {
+	switch t := value.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, true
+		}
+		return *t, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,169 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Params are the values a message template refers to, e.g. {min} and {max}
+type Params map[string]interface{}
+
+// Catalog holds message templates per locale and error code. Templates
+// refer to the error's Params by name in braces, e.g. "must be at least {min}".
+type Catalog struct {
+	mu       sync.RWMutex
+	fallback string
+	messages map[string]map[string]string
+}
+
+// NewCatalog creates an empty Catalog that falls back to the fallback locale
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{
+		fallback: normalizeLocale(fallback),
+		messages: make(map[string]map[string]string),
+	}
+}
+
+// DefaultCatalog holds English and Ukrainian messages for the built-in rules
+var DefaultCatalog = newDefaultCatalog()
+
+func newDefaultCatalog() *Catalog {
+	c := NewCatalog("en")
+	c.AddMessages("en", englishMessages)
+	c.AddMessages("uk", ukrainianMessages)
+	return c
+}
+
+// Add sets the template for code in locale, e.g. for a custom rule
+func (c *Catalog) Add(locale, code, template string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	locale = normalizeLocale(locale)
+	if c.messages[locale] == nil {
+		c.messages[locale] = make(map[string]string)
+	}
+	c.messages[locale][code] = template
+}
+
+// AddMessages sets the templates, keyed by code, for locale
+func (c *Catalog) AddMessages(locale string, templates map[string]string) {
+	for code, template := range templates {
+		c.Add(locale, code, template)
+	}
+}
+
+// Locales returns the locales in the catalog, fallback first
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locales := []string{c.fallback}
+	for locale := range c.messages {
+		if locale != c.fallback {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Strings(locales[1:])
+	return locales
+}
+
+// Message renders err in locale, trying the locale itself, its base language
+// ("uk" for "uk-UA") and the fallback locale before giving up and returning err.Error
+func (c *Catalog) Message(locale string, err *ValidationError) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	locale = normalizeLocale(locale)
+	base, _, _ := strings.Cut(locale, "-")
+
+	for _, candidate := range []string{locale, base, c.fallback} {
+		if template, ok := c.messages[candidate][err.Code]; ok {
+			return render(template, err.Params)
+		}
+	}
+	return err.Error
+}
+
+// Localize replaces the message of every error with its rendering in locale
+func (c *Catalog) Localize(locale string, errs []*ValidationError) {
+	for _, err := range errs {
+		err.Error = c.Message(locale, err)
+	}
+}
+
+// NewError creates a ValidationError with the default English message for
+// code, for checks outside this package that report the built-in codes
+func NewError(code string, params Params) *ValidationError {
+	return &ValidationError{
+		Code:   code,
+		Params: params,
+		Error:  render(englishMessages[code], params),
+	}
+}
+
+// render substitutes params into template
+func render(template string, params Params) string {
+	if len(params) == 0 {
+		return template
+	}
+
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", formatParam(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+func formatParam(value interface{}) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []string:
+		return strings.Join(v, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+var englishMessages = map[string]string{
+	CodeRequired:    "is required",
+	CodeType:        "is not a valid {type}",
+	CodeLength:      "must be between {min} and {max} characters",
+	CodeBlank:       "must not be blank",
+	CodePattern:     "must match pattern {pattern}",
+	CodeOneOf:       "must be one of: {values}",
+	CodeURL:         "must be a valid URL",
+	CodeEmail:       "must be a valid email address",
+	CodeMin:         "must be at least {min}",
+	CodeMax:         "must be at most {max}",
+	CodeBefore:      "must be before {time}",
+	CodeAfter:       "must be after {time}",
+	CodeBeforeField: "must be before {field}",
+	CodeAfterField:  "must be after {field}",
+	CodeEqualField:  "must equal {field}",
+}
+
+var ukrainianMessages = map[string]string{
+	CodeRequired:    "є обов'язковим",
+	CodeType:        "має некоректний тип (очікується {type})",
+	CodeLength:      "має містити від {min} до {max} символів",
+	CodeBlank:       "не може бути порожнім",
+	CodePattern:     "має відповідати шаблону {pattern}",
+	CodeOneOf:       "має бути одним із: {values}",
+	CodeURL:         "має бути коректною URL-адресою",
+	CodeEmail:       "має бути коректною адресою електронної пошти",
+	CodeMin:         "має бути не менше {min}",
+	CodeMax:         "має бути не більше {max}",
+	CodeBefore:      "має бути раніше {time}",
+	CodeAfter:       "має бути пізніше {time}",
+	CodeBeforeField: "має бути раніше {field}",
+	CodeAfterField:  "має бути пізніше {field}",
+	CodeEqualField:  "має збігатися з {field}",
+}
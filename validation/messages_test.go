@@ -0,0 +1,81 @@
+package validation
+
+import (
+	"testing"
+)
+
+func TestCatalogMessage(t *testing.T) {
+	err := Length(1, 255)("")
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"en", "must be between 1 and 255 characters"},
+		{"uk", "має містити від 1 до 255 символів"},
+		{"uk-UA", "має містити від 1 до 255 символів"},
+		{"uk_UA", "має містити від 1 до 255 символів"},
+		{"de", "must be between 1 and 255 characters"},
+		{"", "must be between 1 and 255 characters"},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultCatalog.Message(tt.locale, err); got != tt.want {
+			t.Errorf("Message(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestEveryCodeIsTranslated(t *testing.T) {
+	for code := range englishMessages {
+		if _, ok := ukrainianMessages[code]; !ok {
+			t.Errorf("code %q has no Ukrainian message", code)
+		}
+	}
+	for code := range ukrainianMessages {
+		if _, ok := englishMessages[code]; !ok {
+			t.Errorf("code %q has no English message", code)
+		}
+	}
+}
+
+func TestCatalogCustomCode(t *testing.T) {
+	catalog := NewCatalog("en")
+	catalog.Add("en", "slug", "must be a slug of at most {max} characters")
+	catalog.Add("uk", "slug", "має бути slug довжиною до {max} символів")
+
+	err := &ValidationError{Code: "slug", Params: Params{"max": 40}, Error: "bad slug"}
+
+	if got := catalog.Message("uk", err); got != "має бути slug довжиною до 40 символів" {
+		t.Errorf("unexpected Ukrainian message: %q", got)
+	}
+
+	unknown := &ValidationError{Code: "unknown", Error: "custom rule failed"}
+	if got := catalog.Message("uk", unknown); got != "custom rule failed" {
+		t.Errorf("expected the rule's own message for an unknown code, got %q", got)
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	errs := NewValidator().Validate(struct {
+		Title string `json:"title" validate:"required"`
+		Email string `json:"email" validate:"email"`
+	}{Email: "nope"})
+
+	DefaultCatalog.Localize("uk", errs)
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if errs[0].Error != "є обов'язковим" || errs[1].Error != "має бути коректною адресою електронної пошти" {
+		t.Errorf("unexpected messages: %q, %q", errs[0].Error, errs[1].Error)
+	}
+}
+
+func TestCatalogLocales(t *testing.T) {
+	locales := DefaultCatalog.Locales()
+
+	if len(locales) != 2 || locales[0] != "en" || locales[1] != "uk" {
+		t.Errorf("unexpected locales: %v", locales)
+	}
+}
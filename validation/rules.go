@@ -0,0 +1,232 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Error codes reported by the built-in rules
+const (
+	CodeRequired = "required"
+	CodeType     = "type"
+	CodeLength   = "length"
+	CodePattern  = "pattern"
+	CodeOneOf    = "one_of"
+	CodeURL      = "url"
+	CodeEmail    = "email"
+	CodeMin      = "min"
+	CodeMax      = "max"
+	CodeBefore   = "before"
+	CodeAfter    = "after"
+	CodeBlank    = "blank"
+)
+
+// Validation rules
+
+// Required validates that the given value is not empty
+func Required() Rule {
+	return func(value interface{}) *ValidationError {
+		if value == nil || reflect.ValueOf(value).IsZero() {
+			return NewError(CodeRequired, nil)
+		}
+		return nil
+	}
+}
+
+// Length validates the string length, counted in characters rather than bytes, is within the specified range
+func Length(min, max int) Rule {
+	return func(value interface{}) *ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return notA("string")
+		}
+		if n := utf8.RuneCountInString(str); n < min || n > max {
+			return NewError(CodeLength, Params{"min": min, "max": max})
+		}
+		return nil
+	}
+}
+
+// NotBlank validates the string contains at least one non-whitespace character
+func NotBlank() Rule {
+	return func(value interface{}) *ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return notA("string")
+		}
+		if strings.TrimFunc(str, unicode.IsSpace) == "" {
+			return NewError(CodeBlank, nil)
+		}
+		return nil
+	}
+}
+
+// Match validates the string matches the regular expression re
+func Match(re *regexp.Regexp) Rule {
+	return func(value interface{}) *ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return notA("string")
+		}
+		if !re.MatchString(str) {
+			return NewError(CodePattern, Params{"pattern": re.String()})
+		}
+		return nil
+	}
+}
+
+// OneOf validates the string is one of the allowed values
+func OneOf(allowed ...string) Rule {
+	return func(value interface{}) *ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return notA("string")
+		}
+		for _, a := range allowed {
+			if str == a {
+				return nil
+			}
+		}
+		return NewError(CodeOneOf, Params{"values": allowed})
+	}
+}
+
+// URL validates the string is an absolute http or https URL
+func URL() Rule {
+	return func(value interface{}) *ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return notA("string")
+		}
+		u, err := url.ParseRequestURI(str)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return NewError(CodeURL, nil)
+		}
+		return nil
+	}
+}
+
+// Email validates the string is a bare email address, without a display name
+func Email() Rule {
+	return func(value interface{}) *ValidationError {
+		str, ok := value.(string)
+		if !ok {
+			return notA("string")
+		}
+		addr, err := mail.ParseAddress(str)
+		if err != nil || addr.Address != str {
+			return NewError(CodeEmail, nil)
+		}
+		return nil
+	}
+}
+
+// Min validates the number is greater than or equal to min
+func Min(min float64) Rule {
+	return func(value interface{}) *ValidationError {
+		n, ok := toFloat(value)
+		if !ok {
+			return notA("number")
+		}
+		if n < min {
+			return NewError(CodeMin, Params{"min": min})
+		}
+		return nil
+	}
+}
+
+// Max validates the number is less than or equal to max
+func Max(max float64) Rule {
+	return func(value interface{}) *ValidationError {
+		n, ok := toFloat(value)
+		if !ok {
+			return notA("number")
+		}
+		if n > max {
+			return NewError(CodeMax, Params{"max": max})
+		}
+		return nil
+	}
+}
+
+// Before validates the time is strictly before t
+func Before(t time.Time) Rule {
+	return before(func() time.Time { return t })
+}
+
+// After validates the time is strictly after t
+func After(t time.Time) Rule {
+	return after(func() time.Time { return t })
+}
+
+func before(bound func() time.Time) Rule {
+	return func(value interface{}) *ValidationError {
+		t, ok := value.(time.Time)
+		if !ok {
+			return notA("time")
+		}
+		if b := bound(); !t.Before(b) {
+			return NewError(CodeBefore, Params{"time": b})
+		}
+		return nil
+	}
+}
+
+func after(bound func() time.Time) Rule {
+	return func(value interface{}) *ValidationError {
+		t, ok := value.(time.Time)
+		if !ok {
+			return notA("time")
+		}
+		if b := bound(); !t.After(b) {
+			return NewError(CodeAfter, Params{"time": b})
+		}
+		return nil
+	}
+}
+
+// Each applies rules to every element of a slice or array, stopping at the
+// first failing element. The error's Field holds the element index, e.g. "[2]".
+func Each(rules ...Rule) Rule {
+	return func(value interface{}) *ValidationError {
+		val := reflect.ValueOf(value)
+		if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+			return notA("list")
+		}
+		for i := 0; i < val.Len(); i++ {
+			elem := val.Index(i).Interface()
+			for _, rule := range rules {
+				if err := rule(elem); err != nil {
+					err.Field = fmt.Sprintf("[%d]%s", i, err.Field)
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func notA(kind string) *ValidationError {
+	return NewError(CodeType, Params{"type": kind})
+}
+
+// toFloat converts any Go integer or float to float64
+func toFloat(value interface{}) (float64, bool) {
+	val := reflect.ValueOf(value)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,122 @@
+package validation
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRules(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		rule     Rule
+		value    interface{}
+		wantCode string
+	}{
+		{"required ok", Required(), "a", ""},
+		{"required empty string", Required(), "", CodeRequired},
+		{"required zero int", Required(), 0, CodeRequired},
+		{"required nil", Required(), nil, CodeRequired},
+		{"length counts runes", Length(1, 5), "Новин", ""},
+		{"length too long", Length(1, 5), "Новини", CodeLength},
+		{"length not a string", Length(1, 5), 42, CodeType},
+		{"notblank ok", NotBlank(), " a ", ""},
+		{"notblank whitespace", NotBlank(), " \t\n", CodeBlank},
+		{"match ok", Match(regexp.MustCompile(`^[a-z-]+$`)), "breaking-news", ""},
+		{"match fails", Match(regexp.MustCompile(`^[a-z-]+$`)), "Breaking News", CodePattern},
+		{"oneof ok", OneOf("draft", "published"), "draft", ""},
+		{"oneof fails", OneOf("draft", "published"), "archived", CodeOneOf},
+		{"url ok", URL(), "https://example.com/a?b=c", ""},
+		{"url relative", URL(), "/a/b", CodeURL},
+		{"url scheme", URL(), "ftp://example.com", CodeURL},
+		{"email ok", Email(), "editor@example.com", ""},
+		{"email display name", Email(), "Editor <editor@example.com>", CodeEmail},
+		{"email invalid", Email(), "editor", CodeEmail},
+		{"min ok", Min(1), int32(1), ""},
+		{"min fails", Min(1), 0.5, CodeMin},
+		{"max ok", Max(10), uint8(10), ""},
+		{"max fails", Max(10), int64(11), CodeMax},
+		{"max not a number", Max(10), "11", CodeType},
+		{"before ok", Before(now), now.Add(-time.Minute), ""},
+		{"before fails", Before(now), now, CodeBefore},
+		{"after ok", After(now), now.Add(time.Minute), ""},
+		{"after fails", After(now), now.Add(-time.Minute), CodeAfter},
+		{"after not a time", After(now), "tomorrow", CodeType},
+		{"each ok", Each(Required(), URL()), []string{"https://a.example", "https://b.example"}, ""},
+		{"each fails", Each(Required(), URL()), []string{"https://a.example", ""}, CodeRequired},
+		{"each not a list", Each(Required()), "a", CodeType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule(tt.value)
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %+v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error with code %q, got none", tt.wantCode)
+			}
+			if err.Code != tt.wantCode {
+				t.Errorf("expected code %q, got %q", tt.wantCode, err.Code)
+			}
+		})
+	}
+}
+
+func TestEachReportsElementIndex(t *testing.T) {
+	type withLinks struct {
+		Links []string `validate:"dive,url"`
+	}
+
+	errs := NewValidator().Validate(withLinks{Links: []string{"https://a.example", "nope"}})
+
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	if errs[0].Field != "Links[1]" || errs[0].Code != CodeURL {
+		t.Errorf("unexpected error: %+v", errs[0])
+	}
+}
+
+func TestTagRules(t *testing.T) {
+	type article struct {
+		Slug     string    `validate:"match=^[a-z]{1\\,3}$"`
+		Status   string    `validate:"oneof=draft|published"`
+		Rating   int       `validate:"min=1,max=5"`
+		Embargo  time.Time `validate:"after=2024-01-01T00:00:00Z"`
+		Author   string    `validate:"email"`
+		Source   string    `validate:"url"`
+		Headline string    `validate:"notblank"`
+	}
+
+	valid := article{
+		Slug:     "abc",
+		Status:   "draft",
+		Rating:   3,
+		Embargo:  time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Author:   "editor@example.com",
+		Source:   "https://example.com",
+		Headline: "Headline",
+	}
+	if errs := NewValidator().Validate(valid); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	errs := NewValidator().Validate(article{Slug: "abcd", Status: "gone", Rating: 9, Headline: "  "})
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Field+":"+err.Code)
+	}
+
+	want := "Slug:pattern Status:one_of Rating:max Embargo:after Author:email Source:url Headline:blank"
+	if strings.Join(got, " ") != want {
+		t.Errorf("unexpected errors:\n got %s\nwant %s", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,294 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagName is the struct tag the Validator reads rules from
+const TagName = "validate"
+
+// RuleFactory builds a Rule from the parameter of a struct tag entry,
+// e.g. "1..255" for `validate:"len=1..255"`
+type RuleFactory func(param string) (Rule, error)
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]RuleFactory
+}{
+	factories: map[string]RuleFactory{
+		"required": noParam(Required),
+		"len":      lengthFactory,
+		"notblank": noParam(NotBlank),
+		"match":    matchFactory,
+		"oneof":    oneOfFactory,
+		"url":      noParam(URL),
+		"email":    noParam(Email),
+		"min":      minFactory,
+		"max":      maxFactory,
+		"before":   beforeFactory,
+		"after":    afterFactory,
+	},
+}
+
+// RegisterRule makes a rule available to struct tags under name, replacing
+// any rule already registered with that name. Rules should be registered
+// before the first Validate call for a type that uses them, since parsed
+// tags are cached per type.
+func RegisterRule(name string, factory RuleFactory) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.factories[name] = factory
+}
+
+func lookupRule(name string) (RuleFactory, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	factory, ok := registry.factories[name]
+	return factory, ok
+}
+
+// tagSpec holds the rules parsed from a single `validate` tag
+type tagSpec struct {
+	rules []Rule
+	cross []CrossFieldRule
+	// dive holds the rules following a dive entry, applied to each element
+	dive *tagSpec
+}
+
+// tagCache maps a struct reflect.Type to its parsed []*tagSpec, indexed by field
+var tagCache sync.Map
+
+// tagRules returns the parsed tag rules of struct type typ, parsing them on
+// first use. The result has one entry per field, nil for untagged fields.
+func tagRules(typ reflect.Type) ([]*tagSpec, error) {
+	if cached, ok := tagCache.Load(typ); ok {
+		return cached.([]*tagSpec), nil
+	}
+
+	specs := make([]*tagSpec, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, ok := field.Tag.Lookup(TagName)
+		if !ok || tag == "" || tag == "-" {
+			continue
+		}
+
+		spec, err := parseTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("validation: %s.%s: %w", typ.Name(), field.Name, err)
+		}
+		for _, c := range spec.cross {
+			if _, ok := typ.FieldByName(c.Field); !ok {
+				return nil, fmt.Errorf("validation: %s.%s: no field %s to compare with", typ.Name(), field.Name, c.Field)
+			}
+		}
+
+		specs[i] = spec
+	}
+
+	cached, _ := tagCache.LoadOrStore(typ, specs)
+	return cached.([]*tagSpec), nil
+}
+
+// DiveTag marks the point in a tag after which rules apply to each element
+// of a slice, array or map, e.g. `validate:"required,dive,url"`
+const DiveTag = "dive"
+
+// TagRule is a single entry of a `validate` tag, e.g. {Name: "len", Param: "1..255"}
+type TagRule struct {
+	Name  string
+	Param string
+}
+
+// ParseTagRules splits a `validate` tag into its entries without resolving
+// them, for tools that describe rules rather than run them
+func ParseTagRules(tag string) []TagRule {
+	var entries []TagRule
+	for _, entry := range splitTag(tag) {
+		name, param, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		if name != "" {
+			entries = append(entries, TagRule{Name: name, Param: param})
+		}
+	}
+	return entries
+}
+
+// parseTag turns a tag value such as "required,len=1..255" into rules
+func parseTag(tag string) (*tagSpec, error) {
+	return parseEntries(ParseTagRules(tag))
+}
+
+func parseEntries(entries []TagRule) (*tagSpec, error) {
+	spec := &tagSpec{}
+	for i, entry := range entries {
+		name, param := entry.Name, entry.Param
+
+		if name == DiveTag {
+			elemSpec, err := parseEntries(entries[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			if hasCrossRules(elemSpec) {
+				return nil, errors.New("cross-field rules cannot follow dive")
+			}
+			spec.dive = elemSpec
+			return spec, nil
+		}
+
+		if factory, ok := crossFieldFactories[name]; ok {
+			if param == "" {
+				return nil, fmt.Errorf("rule %q: expected a field name", name)
+			}
+			spec.cross = append(spec.cross, factory(param))
+			continue
+		}
+
+		factory, ok := lookupRule(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+
+		rule, err := factory(param)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", name, err)
+		}
+		spec.rules = append(spec.rules, rule)
+	}
+	return spec, nil
+}
+
+func hasCrossRules(spec *tagSpec) bool {
+	for ; spec != nil; spec = spec.dive {
+		if len(spec.cross) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTag splits a tag on commas, treating "\," as a literal comma so that
+// parameters such as regular expressions can contain one
+func splitTag(tag string) []string {
+	var entries []string
+	var entry strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			entry.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			entries = append(entries, entry.String())
+			entry.Reset()
+		default:
+			entry.WriteByte(tag[i])
+		}
+	}
+	return append(entries, entry.String())
+}
+
+// noParam adapts a parameterless rule constructor to a RuleFactory
+func noParam(rule func() Rule) RuleFactory {
+	return func(param string) (Rule, error) {
+		if param != "" {
+			return nil, fmt.Errorf("takes no parameter, got %q", param)
+		}
+		return rule(), nil
+	}
+}
+
+func lengthFactory(param string) (Rule, error) {
+	min, max, err := parseRange(param)
+	if err != nil {
+		return nil, err
+	}
+	return Length(min, max), nil
+}
+
+func matchFactory(param string) (Rule, error) {
+	re, err := regexp.Compile(param)
+	if err != nil {
+		return nil, err
+	}
+	return Match(re), nil
+}
+
+func oneOfFactory(param string) (Rule, error) {
+	if param == "" {
+		return nil, errors.New("expected values separated by |")
+	}
+	return OneOf(strings.Split(param, "|")...), nil
+}
+
+func minFactory(param string) (Rule, error) {
+	min, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", param)
+	}
+	return Min(min), nil
+}
+
+func maxFactory(param string) (Rule, error) {
+	max, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", param)
+	}
+	return Max(max), nil
+}
+
+func beforeFactory(param string) (Rule, error) {
+	bound, err := parseTimeBound(param)
+	if err != nil {
+		return nil, err
+	}
+	return before(bound), nil
+}
+
+func afterFactory(param string) (Rule, error) {
+	bound, err := parseTimeBound(param)
+	if err != nil {
+		return nil, err
+	}
+	return after(bound), nil
+}
+
+// parseTimeBound parses "now" or an RFC 3339 timestamp
+func parseTimeBound(param string) (func() time.Time, error) {
+	if param == "now" {
+		return time.Now, nil
+	}
+	t, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return nil, fmt.Errorf("expected now or an RFC 3339 time, got %q", param)
+	}
+	return func() time.Time { return t }, nil
+}
+
+// parseRange parses "min..max" into its bounds
+func parseRange(param string) (int, int, error) {
+	lo, hi, ok := strings.Cut(param, "..")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected min..max, got %q", param)
+	}
+
+	min, err := strconv.Atoi(lo)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid min %q", lo)
+	}
+	max, err := strconv.Atoi(hi)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid max %q", hi)
+	}
+	if min > max {
+		return 0, 0, fmt.Errorf("min %d is greater than max %d", min, max)
+	}
+	return min, max, nil
+}
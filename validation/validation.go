@@ -3,12 +3,16 @@ package validation
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 // ValidationError wraps a validation rule error
 type ValidationError struct {
-	Field string
-	Error string
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Params Params `json:"params,omitempty"`
+	Error  string `json:"message"`
 }
 
 func (e *ValidationError) PrintError() string {
@@ -20,73 +24,224 @@ type Rule func(value interface{}) *ValidationError
 
 // Validator struct to hold validation rules
 type Validator struct {
-	rules map[string][]Rule
+	rules      map[string][]Rule
+	crossRules map[string][]CrossFieldRule
+	collectAll bool
+}
+
+// Option configures a Validator
+type Option func(*Validator)
+
+// WithAllErrors makes the Validator report every failing rule of a field
+// instead of stopping at the first one
+func WithAllErrors() Option {
+	return func(v *Validator) {
+		v.collectAll = true
+	}
 }
 
 // NewValidator creates a new Validator instance
-func NewValidator() *Validator {
-	return &Validator{
-		rules: make(map[string][]Rule),
+func NewValidator(opts ...Option) *Validator {
+	v := &Validator{
+		rules:      make(map[string][]Rule),
+		crossRules: make(map[string][]CrossFieldRule),
 	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
 }
 
-// AddRule adds a new validation rule for a field
+// AddRule adds a new validation rule for a field. The field is either the
+// Go name of a top-level field or a JSON path such as "attachments[2].url".
 func (v *Validator) AddRule(field string, rule Rule) {
 	v.rules[field] = append(v.rules[field], rule)
 }
 
-// Validate executes the validation rules and returns errors
+// AddCrossFieldRule adds a rule comparing a top-level field against one of its siblings
+func (v *Validator) AddCrossFieldRule(field string, rule CrossFieldRule) {
+	v.crossRules[field] = append(v.crossRules[field], rule)
+}
+
+// Validate executes the validation rules and returns errors. It descends
+// into nested structs, pointers, slices, arrays and maps, naming fields by
+// their JSON path, e.g. "attachments[2].url". Rules declared in `validate`
+// struct tags run before rules added with AddRule. Validate panics if a
+// struct tag refers to an unknown rule or has a malformed parameter.
 func (v *Validator) Validate(obj interface{}) []*ValidationError {
 	var errors []*ValidationError
+	v.descend(reflect.ValueOf(obj), "", true, &errors)
+	return errors
+}
 
-	val := reflect.ValueOf(obj)
-	if val.Kind() == reflect.Ptr {
+// descend validates any structs reachable from val
+func (v *Validator) descend(val reflect.Value, path string, top bool, errors *[]*ValidationError) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return
+		}
 		val = val.Elem()
 	}
 
+	switch val.Kind() {
+	case reflect.Struct:
+		v.validateStruct(val, path, top, errors)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			v.descend(val.Index(i), indexPath(path, i), false, errors)
+		}
+	case reflect.Map:
+		for _, key := range sortedKeys(val) {
+			v.descend(val.MapIndex(key), keyPath(path, key), false, errors)
+		}
+	}
+}
+
+func (v *Validator) validateStruct(val reflect.Value, path string, top bool, errors *[]*ValidationError) {
 	typ := val.Type()
+	specs, err := tagRules(typ)
+	if err != nil {
+		panic(err)
+	}
+
 	for i := 0; i < val.NumField(); i++ {
 		field := typ.Field(i)
-		value := val.Field(i).Interface()
-
-		rules, exists := v.rules[field.Name]
-		if !exists {
+		name, ok := fieldName(field)
+		if !ok {
 			continue
 		}
+		fieldPath := joinPath(path, name)
+		fieldVal := val.Field(i)
+		spec := specs[i]
+
+		var rules []Rule
+		if spec != nil {
+			rules = append(rules, spec.rules...)
+		}
+		if top && field.Name != fieldPath {
+			rules = append(rules, v.rules[field.Name]...)
+		}
+		rules = append(rules, v.rules[fieldPath]...)
+
+		var cross []CrossFieldRule
+		if spec != nil {
+			cross = append(cross, spec.cross...)
+		}
+		if top {
+			cross = append(cross, v.crossRules[field.Name]...)
+		}
+		for _, c := range cross {
+			rules = append(rules, v.bindCrossRule(val, path, c))
+		}
+
+		v.check(fieldVal.Interface(), fieldPath, rules, errors)
+
+		if spec != nil && spec.dive != nil {
+			v.validateElems(fieldVal, fieldPath, spec.dive, errors)
+		}
+
+		v.descend(fieldVal, fieldPath, false, errors)
+	}
+}
+
+// validateElems applies the rules following a dive tag to each element of val
+func (v *Validator) validateElems(val reflect.Value, path string, spec *tagSpec, errors *[]*ValidationError) {
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			elemPath := indexPath(path, i)
+			v.check(val.Index(i).Interface(), elemPath, spec.rules, errors)
+			if spec.dive != nil {
+				v.validateElems(val.Index(i), elemPath, spec.dive, errors)
+			}
+		}
+	case reflect.Map:
+		for _, key := range sortedKeys(val) {
+			elemPath := keyPath(path, key)
+			v.check(val.MapIndex(key).Interface(), elemPath, spec.rules, errors)
+			if spec.dive != nil {
+				v.validateElems(val.MapIndex(key), elemPath, spec.dive, errors)
+			}
+		}
+	default:
+		err := notA("list")
+		err.Field = path
+		*errors = append(*errors, err)
+	}
+}
 
-		for _, rule := range rules {
-			if err := rule(value); err != nil {
-				err.Field = field.Name
-				errors = append(errors, err)
+// check runs rules against value, stopping at the first error unless collectAll is set
+func (v *Validator) check(value interface{}, path string, rules []Rule, errors *[]*ValidationError) {
+	for _, rule := range rules {
+		if err := rule(value); err != nil {
+			// rules such as Each report the element path, e.g. "[2]"
+			err.Field = path + err.Field
+			*errors = append(*errors, err)
+			if !v.collectAll {
 				break // Stop on the first error for each field
 			}
 		}
 	}
-	return errors
 }
 
-// Validation rules
+// bindCrossRule turns c into a Rule comparing against its sibling field in parent
+func (v *Validator) bindCrossRule(parent reflect.Value, path string, c CrossFieldRule) Rule {
+	sibling, ok := parent.Type().FieldByName(c.Field)
+	if !ok {
+		panic(fmt.Sprintf("validation: %s has no field %s", parent.Type().Name(), c.Field))
+	}
+
+	siblingName, _ := fieldName(sibling)
+	other := parent.FieldByIndex(sibling.Index).Interface()
 
-// Required validates that the given value is not empty
-func Required() Rule {
 	return func(value interface{}) *ValidationError {
-		if value == nil || value == "" {
-			return &ValidationError{Error: "is required"}
-		}
-		return nil
+		return c.Check(value, other, joinPath(path, siblingName))
 	}
 }
 
-// Length validates the string length is within the specified range
-func Length(min, max int) Rule {
-	return func(value interface{}) *ValidationError {
-		str, ok := value.(string)
-		if !ok {
-			return &ValidationError{Error: "is not a valid string"}
-		}
-		if len(str) < min || len(str) > max {
-			return &ValidationError{Error: fmt.Sprintf("must be between %d and %d characters", min, max)}
-		}
-		return nil
+// fieldName returns the JSON name of an exported field, or false if the field is unexported or skipped by encoding/json
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
 	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+	return name, true
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indexPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+func keyPath(path string, key reflect.Value) string {
+	return fmt.Sprintf("%s[%v]", path, key.Interface())
+}
+
+// sortedKeys returns the keys of map val in a stable order so errors are reported deterministically
+func sortedKeys(val reflect.Value) []reflect.Value {
+	keys := val.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
 }
@@ -0,0 +1,231 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type taggedPost struct {
+	Title   string `validate:"required,len=1..10"`
+	Content string `validate:"required"`
+	Slug    string
+}
+
+func TestValidateStructTags(t *testing.T) {
+	validator := NewValidator()
+
+	errs := validator.Validate(&taggedPost{Title: "this title is too long"})
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Field != "Title" || errs[0].Error != "must be between 1 and 10 characters" {
+		t.Errorf("unexpected Title error: %+v", errs[0])
+	}
+	if errs[1].Field != "Content" || errs[1].Error != "is required" {
+		t.Errorf("unexpected Content error: %+v", errs[1])
+	}
+}
+
+func TestValidateTagsAndAddRule(t *testing.T) {
+	validator := NewValidator()
+	validator.AddRule("Slug", Required())
+
+	errs := validator.Validate(taggedPost{Title: "ok", Content: "ok"})
+
+	if len(errs) != 1 || errs[0].Field != "Slug" {
+		t.Fatalf("expected a single Slug error, got %v", errs)
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	RegisterRule("prefix", func(param string) (Rule, error) {
+		return func(value interface{}) *ValidationError {
+			if str, _ := value.(string); !strings.HasPrefix(str, param) {
+				return &ValidationError{Error: "must start with " + param}
+			}
+			return nil
+		}, nil
+	})
+	// the registry is shared by every test of the package
+	t.Cleanup(func() {
+		registry.Lock()
+		defer registry.Unlock()
+		delete(registry.factories, "prefix")
+	})
+
+	type withCustomRule struct {
+		Name string `validate:"prefix=news-"`
+	}
+
+	if errs := NewValidator().Validate(withCustomRule{Name: "news-1"}); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+
+	errs := NewValidator().Validate(withCustomRule{Name: "post-1"})
+	if len(errs) != 1 || errs[0].Error != "must start with news-" {
+		t.Errorf("expected prefix error, got %v", errs)
+	}
+}
+
+func TestValidateUnknownRulePanics(t *testing.T) {
+	type withUnknownRule struct {
+		Name string `validate:"nonexistent"`
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Validate to panic on an unknown rule")
+		}
+	}()
+
+	NewValidator().Validate(withUnknownRule{})
+}
+
+func TestParseTagErrors(t *testing.T) {
+	tests := []string{
+		"len",
+		"len=10",
+		"len=a..5",
+		"len=5..1",
+		"required=yes",
+		"afterfield",
+		"dive,eqfield=Other",
+	}
+
+	for _, tag := range tests {
+		if _, err := parseTag(tag); err == nil {
+			t.Errorf("parseTag(%q): expected an error, got none", tag)
+		}
+	}
+}
+
+type attachment struct {
+	URL     string `json:"url" validate:"required,url"`
+	Caption string `json:"caption,omitempty" validate:"len=0..10"`
+}
+
+type schedule struct {
+	PublishAt   time.Time  `json:"publish_at" validate:"required"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty" validate:"afterfield=PublishAt"`
+}
+
+type nestedPost struct {
+	Title       string                 `json:"title" validate:"required"`
+	Attachments []attachment           `json:"attachments"`
+	Cover       *attachment            `json:"cover,omitempty"`
+	Schedule    schedule               `json:"schedule"`
+	Tags        []string               `json:"tags" validate:"dive,notblank"`
+	Sources     map[string]*attachment `json:"sources"`
+	internal    attachment
+}
+
+func TestValidateNested(t *testing.T) {
+	publishAt := time.Date(2024, 2, 14, 12, 0, 0, 0, time.UTC)
+	unpublishAt := publishAt.Add(-time.Hour)
+
+	post := &nestedPost{
+		Title: "Nested",
+		Attachments: []attachment{
+			{URL: "https://example.com/a.png"},
+			{URL: "https://example.com/b.png"},
+			{URL: "not a url"},
+		},
+		Cover:    &attachment{URL: "https://example.com/c.png", Caption: "far too long caption"},
+		Schedule: schedule{PublishAt: publishAt, UnpublishAt: &unpublishAt},
+		Tags:     []string{"politics", " "},
+		Sources:  map[string]*attachment{"wire": {}},
+		internal: attachment{},
+	}
+
+	errs := NewValidator().Validate(post)
+
+	var got []string
+	for _, err := range errs {
+		got = append(got, err.Field+":"+err.Code)
+	}
+
+	want := []string{
+		"attachments[2].url:url",
+		"cover.caption:length",
+		"schedule.unpublish_at:after_field",
+		"tags[1]:blank",
+		"sources[wire].url:required",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("unexpected errors:\n got %v\nwant %v", got, want)
+	}
+	if errs[2].Error != "must be after schedule.publish_at" {
+		t.Errorf("unexpected cross-field message: %q", errs[2].Error)
+	}
+}
+
+func TestValidateCrossFieldSkipsUnsetTimes(t *testing.T) {
+	errs := NewValidator().Validate(schedule{PublishAt: time.Now()})
+
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestAddRuleByPath(t *testing.T) {
+	validator := NewValidator()
+	validator.AddRule("attachments[0].caption", Required())
+	validator.AddCrossFieldRule("Schedule", CrossFieldRule{
+		Field: "Title",
+		Check: func(value, other interface{}, otherPath string) *ValidationError {
+			return &ValidationError{Code: "custom", Error: "compared with " + otherPath}
+		},
+	})
+
+	errs := validator.Validate(nestedPost{
+		Title:       "ok",
+		Attachments: []attachment{{URL: "https://example.com"}},
+		Schedule:    schedule{PublishAt: time.Now()},
+	})
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if errs[0].Field != "attachments[0].caption" || errs[0].Code != CodeRequired {
+		t.Errorf("unexpected path error: %+v", errs[0])
+	}
+	if errs[1].Field != "schedule" || errs[1].Error != "compared with title" {
+		t.Errorf("unexpected cross-field error: %+v", errs[1])
+	}
+}
+
+func TestValidateWithAllErrors(t *testing.T) {
+	type strict struct {
+		Code string `json:"code" validate:"notblank,len=3..3,match=^[A-Z]+$"`
+	}
+
+	if errs := NewValidator().Validate(strict{Code: " "}); len(errs) != 1 {
+		t.Errorf("expected the default validator to stop at 1 error, got %v", errs)
+	}
+
+	errs := NewValidator(WithAllErrors()).Validate(strict{Code: " "})
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	for i, code := range []string{CodeBlank, CodeLength, CodePattern} {
+		if errs[i].Field != "code" || errs[i].Code != code {
+			t.Errorf("error %d: expected code:%s, got %s:%s", i, code, errs[i].Field, errs[i].Code)
+		}
+	}
+}
+
+func TestCrossFieldTagErrors(t *testing.T) {
+	type missingSibling struct {
+		End time.Time `validate:"afterfield=Start"`
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Validate to panic on an unknown sibling field")
+		}
+	}()
+
+	NewValidator().Validate(missingSibling{})
+}
@@ -0,0 +1,53 @@
+// Package version reports the build of the running binary. The variables are
+// set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/freshusername/news-api/version.Version=v1.4.0 \
+//		-X github.com/freshusername/news-api/version.Commit=$(git rev-parse HEAD) \
+//		-X github.com/freshusername/news-api/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// Version is the release of the binary, "dev" for local builds
+	Version = "dev"
+	// Commit is the git revision the binary was built from
+	Commit = ""
+	// BuildTime is when the binary was built, in RFC 3339
+	BuildTime = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info. Commit and BuildTime fall back to the VCS
+// stamp the go tool embeds when they were not set at link time.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	return info
+}
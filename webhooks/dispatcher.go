@@ -0,0 +1,234 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/freshusername/news-api/health"
+)
+
+// ErrPrivateTarget is returned for a delivery to a private, loopback or
+// link-local address while those are not allowed
+var ErrPrivateTarget = errors.New("webhook target is a private address")
+
+// Dispatcher sends the queued deliveries of a Store
+type Dispatcher struct {
+	Store Store
+	// Client sends the deliveries. NewDispatcher sets one with Timeout that
+	// refuses private targets unless allowed.
+	Client *http.Client
+	// PollInterval is the wait between claims while the queue has nothing due
+	PollInterval time.Duration
+	// BatchSize bounds the deliveries claimed, and sent concurrently, at once
+	BatchSize int
+	// Timeout bounds a delivery attempt; claims are leased for twice as long
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts before a delivery is dead
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled for each
+	// following one up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Heartbeat, when set, is beaten on every cycle for readiness checks
+	Heartbeat *health.Heartbeat
+	Logger    *slog.Logger
+}
+
+// DispatcherOptions configure NewDispatcher
+type DispatcherOptions struct {
+	PollInterval        time.Duration
+	BatchSize           int
+	Timeout             time.Duration
+	MaxAttempts         int
+	InitialBackoff      time.Duration
+	MaxBackoff          time.Duration
+	AllowPrivateTargets bool
+	Heartbeat           *health.Heartbeat
+	Logger              *slog.Logger
+}
+
+// NewDispatcher returns a dispatcher of the deliveries of store
+func NewDispatcher(store Store, opts DispatcherOptions) *Dispatcher {
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	if !opts.AllowPrivateTargets {
+		dialer.Control = refusePrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// a proxy would be dialed instead of the target, bypassing the check
+	transport.Proxy = nil
+
+	return &Dispatcher{
+		Store: store,
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			// a redirect could lead anywhere; receivers must answer directly
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		PollInterval:   opts.PollInterval,
+		BatchSize:      opts.BatchSize,
+		Timeout:        opts.Timeout,
+		MaxAttempts:    opts.MaxAttempts,
+		InitialBackoff: opts.InitialBackoff,
+		MaxBackoff:     opts.MaxBackoff,
+		Heartbeat:      opts.Heartbeat,
+		Logger:         opts.Logger,
+	}
+}
+
+// refusePrivate is a net.Dialer Control refusing private addresses. It runs
+// after name resolution, so a public name resolving to a private address is
+// refused too.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrPrivateTarget, host)
+	}
+	return nil
+}
+
+// Run sends due deliveries until ctx is done. Several instances can run
+// against the same store; each delivery is attempted by one at a time.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		if d.Heartbeat != nil {
+			d.Heartbeat.Beat()
+		}
+		n, err := d.DispatchDue(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			d.logger().Warn("claiming webhook deliveries failed", "error", err)
+		}
+		// a full batch suggests more are due
+		if err == nil && n == d.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.PollInterval):
+		}
+	}
+}
+
+// DispatchDue claims a batch of due deliveries, attempts them concurrently
+// and returns how many were attempted
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	claims, err := d.Store.Claim(ctx, d.BatchSize, 2*d.Timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, claim := range claims {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcome, ok := d.attempt(ctx, claim)
+			if !ok {
+				// cut short by shutdown, not failed: the lease expires and it is attempted as it was
+				d.logger().Debug("webhook delivery interrupted", "delivery", claim.DeliveryID)
+				return
+			}
+			// record even when shutting down, or the attempt is repeated
+			recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.Timeout)
+			defer cancel()
+			if err := d.Store.Record(recordCtx, claim, outcome); err != nil {
+				d.logger().Error("recording a webhook delivery failed", "delivery", claim.DeliveryID, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+	return len(claims), nil
+}
+
+// attempt sends a claimed delivery and returns its outcome, or false when
+// ctx ended before the receiver answered, which is no attempt to count
+func (d *Dispatcher) attempt(ctx context.Context, claim *Claim) (Outcome, bool) {
+	start := time.Now()
+	attempt := Attempt{Attempt: claim.Attempts + 1, At: start}
+
+	status, err := d.send(ctx, claim, start)
+	if err != nil && ctx.Err() != nil {
+		return Outcome{}, false
+	}
+	attempt.StatusCode = status
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("unexpected status %d", status)
+	}
+
+	logger := d.logger().With("delivery", claim.DeliveryID, "webhook", claim.WebhookID, "attempt", attempt.Attempt)
+	if err == nil {
+		logger.Debug("webhook delivered", "status", status)
+		return Outcome{Attempt: attempt, Status: StatusDelivered}, true
+	}
+	attempt.Error = err.Error()
+
+	if attempt.Attempt >= d.MaxAttempts {
+		logger.Warn("webhook delivery dead, attempts exhausted", "error", err)
+		return Outcome{Attempt: attempt, Status: StatusDead}, true
+	}
+	retry := d.Backoff(attempt.Attempt)
+	logger.Info("webhook delivery failed", "error", err, "retry_in", retry)
+	return Outcome{Attempt: attempt, Status: StatusPending, NextAttemptAt: start.Add(retry)}, true
+}
+
+// send POSTs the delivery and returns the response status
+func (d *Dispatcher) send(ctx context.Context, claim *Claim, signedAt time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, claim.URL, bytes.NewReader(claim.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "news-api-webhooks")
+	req.Header.Set(EventHeader, claim.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(claim.DeliveryID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(signedAt.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(claim.Secret, signedAt, claim.Body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain a little so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// Backoff returns the wait after the failed attempt n, counted from 1
+func (d *Dispatcher) Backoff(n int) time.Duration {
+	backoff := d.InitialBackoff
+	for i := 1; i < n && backoff < d.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, d.MaxBackoff)
+}
+
+func (d *Dispatcher) logger() *slog.Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	return slog.Default()
+}
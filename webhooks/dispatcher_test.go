@@ -0,0 +1,201 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStore hands out its claims once and keeps the recorded outcomes
+type fakeStore struct {
+	Store
+
+	mu       sync.Mutex
+	claims   []*Claim
+	outcomes map[int64]Outcome
+}
+
+func (s *fakeStore) Claim(_ context.Context, limit int, _ time.Duration) ([]*Claim, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := min(limit, len(s.claims))
+	claims := s.claims[:n]
+	s.claims = s.claims[n:]
+	return claims, nil
+}
+
+func (s *fakeStore) Record(_ context.Context, claim *Claim, outcome Outcome) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcomes == nil {
+		s.outcomes = make(map[int64]Outcome)
+	}
+	s.outcomes[claim.DeliveryID] = outcome
+	return nil
+}
+
+func testDispatcher(store Store) *Dispatcher {
+	return NewDispatcher(store, DispatcherOptions{
+		PollInterval:        10 * time.Millisecond,
+		BatchSize:           10,
+		Timeout:             time.Second,
+		MaxAttempts:         3,
+		InitialBackoff:      time.Second,
+		MaxBackoff:          time.Minute,
+		AllowPrivateTargets: true,
+	})
+}
+
+func TestDispatchDelivers(t *testing.T) {
+	body := `{"event":"post.created","occurred_at":"2026-10-19T11:00:00Z","post":{"id":1}}`
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		received = r.Header
+		if err := Verify("secret", r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), got, time.Minute); err != nil {
+			t.Errorf("expected a valid signature, got %v", err)
+		}
+		if string(got) != body {
+			t.Errorf("expected the stored payload, got %s", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{claims: []*Claim{{DeliveryID: 7, WebhookID: 1, Event: EventPostCreated, URL: server.URL, Secret: "secret", Body: []byte(body)}}}
+	n, err := testDispatcher(store).DispatchDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 delivery attempted, got %d, %v", n, err)
+	}
+
+	outcome := store.outcomes[7]
+	if outcome.Status != StatusDelivered || outcome.Attempt.Attempt != 1 || outcome.Attempt.StatusCode != http.StatusNoContent {
+		t.Errorf("expected a first attempt delivered with 204, got %+v", outcome)
+	}
+	if received.Get(EventHeader) != EventPostCreated || received.Get(DeliveryHeader) != "7" {
+		t.Errorf("expected the event and delivery headers, got %v", received)
+	}
+}
+
+func TestDispatchRetriesThenDeadLetters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	store := &fakeStore{claims: []*Claim{
+		{DeliveryID: 1, URL: server.URL, Body: []byte("{}"), Attempts: 1},
+		{DeliveryID: 2, URL: server.URL, Body: []byte("{}"), Attempts: 2},
+	}}
+	before := time.Now()
+	if _, err := testDispatcher(store).DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	retried := store.outcomes[1]
+	if retried.Status != StatusPending || retried.Attempt.Attempt != 2 || retried.Attempt.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected the second attempt to be retried, got %+v", retried)
+	}
+	if wait := retried.NextAttemptAt.Sub(before); wait < 2*time.Second || wait > 3*time.Second {
+		t.Errorf("expected the retry after 2s of backoff, got %v", wait)
+	}
+	if !strings.Contains(retried.Attempt.Error, "502") {
+		t.Errorf("expected the status in the error, got %q", retried.Attempt.Error)
+	}
+
+	if dead := store.outcomes[2]; dead.Status != StatusDead || dead.Attempt.Attempt != 3 {
+		t.Errorf("expected the last allowed attempt to dead-letter, got %+v", dead)
+	}
+}
+
+func TestDispatchInterruptedIsNotRecorded(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	received, release := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		close(received)
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	store := &fakeStore{claims: []*Claim{{DeliveryID: 1, URL: server.URL, Body: []byte("{}"), Attempts: 1}}}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		testDispatcher(store).DispatchDue(ctx)
+	}()
+	// shut down while the receiver has not answered
+	<-received
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the attempt to stop with its context")
+	}
+
+	if outcome, ok := store.outcomes[1]; ok {
+		t.Errorf("expected the interrupted attempt to leave attempts and status as they were, got %+v", outcome)
+	}
+}
+
+func TestDispatchRefusesPrivateTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to a loopback address")
+	}))
+	defer server.Close()
+
+	store := &fakeStore{claims: []*Claim{{DeliveryID: 1, URL: server.URL, Body: []byte("{}")}}}
+	dispatcher := testDispatcher(store)
+	dispatcher.Client = NewDispatcher(store, DispatcherOptions{Timeout: time.Second}).Client
+	if _, err := dispatcher.DispatchDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	outcome := store.outcomes[1]
+	if outcome.Status != StatusPending || !strings.Contains(outcome.Attempt.Error, ErrPrivateTarget.Error()) {
+		t.Errorf("expected the attempt to fail as a private target, got %+v", outcome)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+	for n, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second, 4: time.Minute, 30: time.Minute} {
+		if got := d.Backoff(n); got != want {
+			t.Errorf("Backoff(%d) = %v, expected %v", n, got, want)
+		}
+	}
+}
+
+func TestRunStops(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		testDispatcher(&fakeStore{}).Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Run to return when its context is done")
+	}
+}
+
+func TestRefusePrivate(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:80", "10.1.2.3:443", "192.168.0.1:80", "169.254.169.254:80", "[::1]:80", "0.0.0.0:80"} {
+		if err := refusePrivate("tcp", address, nil); !errors.Is(err, ErrPrivateTarget) {
+			t.Errorf("expected %s to be refused, got %v", address, err)
+		}
+	}
+	if err := refusePrivate("tcp", "93.184.216.34:443", nil); err != nil {
+		t.Errorf("expected a public address to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,427 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/logging"
+	"github.com/jackc/pgtype"
+)
+
+const (
+	webhookColumns = `id, url, secret, events, paused, created_at, updated_at`
+
+	selectWebhooks = `SELECT ` + webhookColumns + ` FROM public.webhooks ORDER BY id`
+	selectWebhook  = `SELECT ` + webhookColumns + ` FROM public.webhooks WHERE id = $1`
+	insertWebhook  = `
+		INSERT INTO public.webhooks (url, secret, events, paused)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + webhookColumns
+	updateWebhook = `
+		UPDATE public.webhooks
+		SET url = $2, secret = COALESCE(NULLIF($3::text, ''), secret), events = $4, paused = $5, updated_at = now()
+		WHERE id = $1
+		RETURNING ` + webhookColumns
+	deleteWebhook = `DELETE FROM public.webhooks WHERE id = $1`
+
+	// enqueueDeliveries queues the payload $2 of event $1 for every webhook
+	// subscribed to it, skipping those it was queued for already by event ID $3
+	enqueueDeliveries = `
+		INSERT INTO public.webhook_deliveries (webhook_id, event, payload, event_id)
+		SELECT id, $1::text, $2::jsonb, NULLIF($3::bigint, 0) FROM public.webhooks
+		WHERE NOT paused AND (cardinality(events) = 0 OR $1::text = ANY (events))
+		ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`
+
+	deliveryColumns = `id, webhook_id, event, payload::text, status, attempts, next_attempt_at,
+		COALESCE(last_status_code, 0), COALESCE(last_error, ''), created_at, delivered_at`
+
+	selectDeliveries = `
+		SELECT ` + deliveryColumns + `
+		FROM public.webhook_deliveries
+		WHERE webhook_id = $1 AND ($2::text = '' OR status = $2::text)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	selectDelivery = `
+		SELECT ` + deliveryColumns + `
+		FROM public.webhook_deliveries
+		WHERE webhook_id = $1 AND id = $2
+	`
+	selectAttempts = `
+		SELECT attempt, COALESCE(status_code, 0), COALESCE(error, ''), duration_ms, attempted_at
+		FROM public.webhook_attempts
+		WHERE delivery_id = $1
+		ORDER BY id
+	`
+	redeliver = `
+		UPDATE public.webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+		WHERE webhook_id = $1 AND id = $2
+		RETURNING ` + deliveryColumns
+
+	// claimDeliveries leases up to $1 due deliveries of active webhooks for
+	// $2 milliseconds. SKIP LOCKED lets instances claim concurrently.
+	claimDeliveries = `
+		UPDATE public.webhook_deliveries d
+		SET next_attempt_at = now() + $2::bigint * interval '1 millisecond'
+		FROM public.webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT due.id
+			FROM public.webhook_deliveries due
+			JOIN public.webhooks hook ON hook.id = due.webhook_id
+			WHERE due.status = 'pending' AND due.next_attempt_at <= now() AND NOT hook.paused
+			ORDER BY due.next_attempt_at, due.id
+			LIMIT $1
+			FOR UPDATE OF due SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event, w.url, w.secret, d.payload::text, d.attempts
+	`
+	insertAttempt = `
+		INSERT INTO public.webhook_attempts (delivery_id, attempt, status_code, error, duration_ms, attempted_at)
+		VALUES ($1, $2, NULLIF($3::integer, 0), NULLIF($4::text, ''), $5, $6)
+	`
+	recordOutcome = `
+		UPDATE public.webhook_deliveries
+		SET status = $2::text, attempts = $3, next_attempt_at = $4,
+			last_status_code = NULLIF($5::integer, 0), last_error = NULLIF($6::text, ''),
+			delivered_at = CASE WHEN $2::text = 'delivered' THEN now() END
+		WHERE id = $1
+	`
+)
+
+// PostgresStore keeps webhooks in the database of the posts
+type PostgresStore struct {
+	DB database.Connector
+	// Timeout bounds each query, defaultTimeout when zero
+	Timeout time.Duration
+}
+
+const defaultTimeout = 3 * time.Second
+
+// conn returns the connection and a context bounded by the query timeout
+func (s *PostgresStore) conn(ctx context.Context) (*sql.DB, context.Context, context.CancelFunc, error) {
+	db := s.DB.Connection()
+	if db == nil {
+		return nil, ctx, func() {}, database.ErrUnavailable
+	}
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return db, ctx, cancel, nil
+}
+
+// queryError logs a failed query with the request-scoped logger and returns err
+func queryError(ctx context.Context, method string, err error) error {
+	logging.FromContext(ctx).ErrorContext(ctx, "webhook query failed", "method", method, "error", err)
+	return err
+}
+
+func (s *PostgresStore) CreateWebhook(ctx context.Context, hook *Webhook) (*Webhook, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := scanWebhook(db.QueryRowContext(ctx, insertWebhook, hook.URL, hook.Secret, textArray(hook.Events), hook.Paused))
+	if err != nil {
+		return nil, queryError(ctx, "CreateWebhook", err)
+	}
+	return created, nil
+}
+
+func (s *PostgresStore) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, selectWebhooks)
+	if err != nil {
+		return nil, queryError(ctx, "ListWebhooks", err)
+	}
+	defer rows.Close()
+
+	hooks := []*Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, queryError(ctx, "ListWebhooks", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, "ListWebhooks", err)
+	}
+	return hooks, nil
+}
+
+func (s *PostgresStore) GetWebhook(ctx context.Context, id int32) (*Webhook, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	hook, err := scanWebhook(db.QueryRowContext(ctx, selectWebhook, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, queryError(ctx, "GetWebhook", err)
+	}
+	return hook, nil
+}
+
+func (s *PostgresStore) UpdateWebhook(ctx context.Context, id int32, hook *Webhook) (*Webhook, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := scanWebhook(db.QueryRowContext(ctx, updateWebhook, id, hook.URL, hook.Secret, textArray(hook.Events), hook.Paused))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, queryError(ctx, "UpdateWebhook", err)
+	}
+	return updated, nil
+}
+
+func (s *PostgresStore) DeleteWebhook(ctx context.Context, id int32) error {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return err
+	}
+
+	result, err := db.ExecContext(ctx, deleteWebhook, id)
+	if err != nil {
+		return queryError(ctx, "DeleteWebhook", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStore) Enqueue(ctx context.Context, payload Payload) (int, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	result, err := db.ExecContext(ctx, enqueueDeliveries, payload.Event, string(body), payload.EventID)
+	if err != nil {
+		return 0, queryError(ctx, "Enqueue", err)
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *PostgresStore) ListDeliveries(ctx context.Context, webhookID int32, status string, limit int) ([]*Delivery, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, selectDeliveries, webhookID, status, limit)
+	if err != nil {
+		return nil, queryError(ctx, "ListDeliveries", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, queryError(ctx, "ListDeliveries", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, "ListDeliveries", err)
+	}
+	return deliveries, nil
+}
+
+func (s *PostgresStore) GetDelivery(ctx context.Context, webhookID int32, id int64) (*Delivery, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := scanDelivery(db.QueryRowContext(ctx, selectDelivery, webhookID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, queryError(ctx, "GetDelivery", err)
+	}
+
+	rows, err := db.QueryContext(ctx, selectAttempts, id)
+	if err != nil {
+		return nil, queryError(ctx, "GetDelivery", err)
+	}
+	defer rows.Close()
+
+	delivery.Log = []Attempt{}
+	for rows.Next() {
+		var attempt Attempt
+		if err := rows.Scan(&attempt.Attempt, &attempt.StatusCode, &attempt.Error, &attempt.DurationMS, &attempt.At); err != nil {
+			return nil, queryError(ctx, "GetDelivery", err)
+		}
+		delivery.Log = append(delivery.Log, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, "GetDelivery", err)
+	}
+	return delivery, nil
+}
+
+func (s *PostgresStore) Redeliver(ctx context.Context, webhookID int32, id int64) (*Delivery, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := scanDelivery(db.QueryRowContext(ctx, redeliver, webhookID, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, queryError(ctx, "Redeliver", err)
+	}
+	return delivery, nil
+}
+
+func (s *PostgresStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]*Claim, error) {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, claimDeliveries, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, queryError(ctx, "Claim", err)
+	}
+	defer rows.Close()
+
+	var claims []*Claim
+	for rows.Next() {
+		var claim Claim
+		var body string
+		if err := rows.Scan(&claim.DeliveryID, &claim.WebhookID, &claim.Event, &claim.URL, &claim.Secret, &body, &claim.Attempts); err != nil {
+			return nil, queryError(ctx, "Claim", err)
+		}
+		claim.Body = []byte(body)
+		claims = append(claims, &claim)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, "Claim", err)
+	}
+	return claims, nil
+}
+
+func (s *PostgresStore) Record(ctx context.Context, claim *Claim, outcome Outcome) error {
+	db, ctx, cancel, err := s.conn(ctx)
+	defer cancel()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return queryError(ctx, "Record", err)
+	}
+	defer tx.Rollback()
+
+	attempt := outcome.Attempt
+	if _, err := tx.ExecContext(ctx, insertAttempt,
+		claim.DeliveryID, attempt.Attempt, attempt.StatusCode, attempt.Error, attempt.DurationMS, attempt.At); err != nil {
+		return queryError(ctx, "Record", err)
+	}
+	next := outcome.NextAttemptAt
+	if next.IsZero() {
+		next = attempt.At
+	}
+	if _, err := tx.ExecContext(ctx, recordOutcome,
+		claim.DeliveryID, outcome.Status, attempt.Attempt, next, attempt.StatusCode, attempt.Error); err != nil {
+		return queryError(ctx, "Record", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return queryError(ctx, "Record", err)
+	}
+	return nil
+}
+
+// scanner is a *sql.Row or *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*Webhook, error) {
+	var hook Webhook
+	var events pgtype.TextArray
+	if err := row.Scan(&hook.ID, &hook.URL, &hook.Secret, &events, &hook.Paused, &hook.CreatedAt, &hook.UpdatedAt); err != nil {
+		return nil, err
+	}
+	hook.Events = []string{}
+	for _, e := range events.Elements {
+		hook.Events = append(hook.Events, e.String)
+	}
+	return &hook, nil
+}
+
+func scanDelivery(row scanner) (*Delivery, error) {
+	var delivery Delivery
+	var payload string
+	var next time.Time
+	var delivered sql.NullTime
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &payload, &delivery.Status, &delivery.Attempts, &next,
+		&delivery.LastStatusCode, &delivery.LastError, &delivery.CreatedAt, &delivered)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(payload), &delivery.Payload); err != nil {
+		return nil, fmt.Errorf("decoding the payload of delivery %d: %w", delivery.ID, err)
+	}
+	if delivery.Status == StatusPending {
+		delivery.NextAttemptAt = &next
+	}
+	if delivered.Valid {
+		delivery.DeliveredAt = &delivered.Time
+	}
+	return &delivery, nil
+}
+
+// textArray encodes events as a text[] argument
+func textArray(events []string) *pgtype.TextArray {
+	array := &pgtype.TextArray{}
+	if events == nil {
+		events = []string{}
+	}
+	// a []string always converts
+	_ = array.Set(events)
+	return array
+}
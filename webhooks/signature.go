@@ -0,0 +1,67 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256, keyed with
+	// the webhook's secret, of the timestamp, a dot and the body
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader carries the Unix time the delivery was signed at, so
+	// receivers can reject replays
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	// DeliveryHeader carries the delivery ID, the same across retries, so
+	// receivers can ignore deliveries they processed already
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+const signaturePrefix = "sha256="
+
+// ErrInvalidSignature is returned by Verify for a signature not made with the
+// secret, or made too long ago
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the SignatureHeader value of body signed at timestamp
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp header values of a received body.
+// Signatures older than tolerance are rejected; a tolerance of 0 accepts any age.
+func Verify(secret, signature, timestamp string, body []byte, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	signedAt := time.Unix(unix, 0)
+	if tolerance > 0 && time.Since(signedAt).Abs() > tolerance {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, signedAt, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// NewSecret returns a random secret for a webhook created without one
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
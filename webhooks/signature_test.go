@@ -0,0 +1,66 @@
+package webhooks
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"post.created"}`)
+	now := time.Now()
+	signature := Sign("secret", now, body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Fatalf("expected a sha256= signature, got %q", signature)
+	}
+	if err := Verify("secret", signature, timestamp, body, time.Minute); err != nil {
+		t.Errorf("expected the signature to verify, got %v", err)
+	}
+
+	tests := map[string]struct {
+		secret, signature, timestamp string
+		body                         []byte
+	}{
+		"other secret":    {"other", signature, timestamp, body},
+		"tampered body":   {"secret", signature, timestamp, []byte(`{"event":"post.deleted"}`)},
+		"other timestamp": {"secret", signature, strconv.FormatInt(now.Unix()+1, 10), body},
+		"no prefix":       {"secret", strings.TrimPrefix(signature, "sha256="), timestamp, body},
+		"bad timestamp":   {"secret", signature, "yesterday", body},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.signature, tt.timestamp, tt.body, time.Minute); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyTolerance(t *testing.T) {
+	body := []byte("{}")
+	old := time.Now().Add(-time.Hour)
+	signature := Sign("secret", old, body)
+	timestamp := strconv.FormatInt(old.Unix(), 10)
+
+	if err := Verify("secret", signature, timestamp, body, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected an hour old signature to be rejected, got %v", err)
+	}
+	if err := Verify("secret", signature, timestamp, body, 0); err != nil {
+		t.Errorf("expected any age to be accepted without a tolerance, got %v", err)
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	a, err := NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewSecret()
+	if a == b || !strings.HasPrefix(a, "whsec_") || len(a) != len("whsec_")+64 {
+		t.Errorf("expected distinct random secrets, got %q and %q", a, b)
+	}
+}
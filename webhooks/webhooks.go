@@ -0,0 +1,143 @@
+// Package webhooks notifies subscribed URLs of post changes. Deliveries are
+// queued in Postgres, signed with the webhook's secret, and retried with
+// exponential backoff until they succeed or are dead-lettered.
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/freshusername/news-api/database"
+	"github.com/freshusername/news-api/models"
+)
+
+// Event types a webhook can subscribe to
+const (
+	EventPostCreated = database.EventPostCreated
+	EventPostUpdated = database.EventPostUpdated
+	EventPostDeleted = database.EventPostDeleted
+)
+
+// Delivery statuses
+const (
+	// StatusPending deliveries wait for their next attempt
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// StatusDead deliveries failed every attempt and are no longer retried
+	// unless redelivered
+	StatusDead = "dead"
+)
+
+// ErrNotFound is returned when a webhook or delivery does not exist
+var ErrNotFound = errors.New("not found")
+
+// Webhook subscribes a URL to post events
+type Webhook struct {
+	// example: 1
+	ID int32 `json:"id"`
+	// URL receives the events in POST requests
+	// example: https://search.example/hooks/news
+	URL string `json:"url" validate:"required,url,len=1..2048"`
+	// Secret signs every payload. It is generated when left empty, and only
+	// returned when the webhook is created or the secret changed.
+	Secret string `json:"secret,omitempty" validate:"len=0..256"`
+	// Events lists the event types delivered, all when empty
+	// example: ["post.created","post.updated"]
+	Events []string `json:"events" validate:"dive,oneof=post.created|post.updated|post.deleted"`
+	// Paused webhooks queue no new events and hold their pending deliveries
+	// until resumed
+	Paused    bool      `json:"paused"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Payload is the JSON body POSTed to webhooks
+type Payload struct {
+	// EventID identifies the post event, the same for every webhook and
+	// across retries
+	EventID int64 `json:"event_id,omitempty"`
+	// example: post.created
+	Event      string       `json:"event"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Post       *models.Post `json:"post"`
+}
+
+// Delivery is an event queued for one webhook
+type Delivery struct {
+	ID        int64   `json:"id"`
+	WebhookID int32   `json:"webhook_id"`
+	Event     string  `json:"event"`
+	Payload   Payload `json:"payload"`
+	// example: pending
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// NextAttemptAt is set while the delivery is pending
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	// Log lists the attempts, oldest first, when a single delivery is read
+	Log []Attempt `json:"log,omitempty"`
+}
+
+// Attempt is one try of a delivery
+type Attempt struct {
+	Attempt int `json:"attempt"`
+	// StatusCode is the response status, 0 when no response was received
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	At         time.Time `json:"at"`
+}
+
+// Claim is a due delivery claimed for an attempt, with what sending it takes
+type Claim struct {
+	DeliveryID int64
+	WebhookID  int32
+	Event      string
+	URL        string
+	Secret     string
+	// Body is the payload exactly as stored, and signed
+	Body []byte
+	// Attempts counts the attempts made before this one
+	Attempts int
+}
+
+// Outcome is the state of a delivery after an attempt
+type Outcome struct {
+	Attempt Attempt
+	// Status is StatusDelivered, StatusDead, or StatusPending to retry at NextAttemptAt
+	Status        string
+	NextAttemptAt time.Time
+}
+
+// Store keeps webhooks and their delivery queue
+type Store interface {
+	CreateWebhook(ctx context.Context, hook *Webhook) (*Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+	GetWebhook(ctx context.Context, id int32) (*Webhook, error)
+	// UpdateWebhook replaces the webhook, keeping its secret when hook has none
+	UpdateWebhook(ctx context.Context, id int32, hook *Webhook) (*Webhook, error)
+	DeleteWebhook(ctx context.Context, id int32) error
+
+	// Enqueue queues payload for every webhook subscribed to its event and
+	// returns the number of deliveries queued. A payload with an EventID is
+	// queued at most once per webhook, however often it is enqueued.
+	Enqueue(ctx context.Context, payload Payload) (int, error)
+	// ListDeliveries returns the newest deliveries of a webhook, optionally
+	// only those with status
+	ListDeliveries(ctx context.Context, webhookID int32, status string, limit int) ([]*Delivery, error)
+	// GetDelivery returns a delivery with its log of attempts
+	GetDelivery(ctx context.Context, webhookID int32, id int64) (*Delivery, error)
+	// Redeliver queues a delivery again for an immediate attempt, with a
+	// fresh allowance of retries
+	Redeliver(ctx context.Context, webhookID int32, id int64) (*Delivery, error)
+
+	// Claim returns up to limit due deliveries, hiding them from other claims
+	// for lease so that instances sharing the queue do not send them twice
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*Claim, error)
+	// Record logs an attempt of a claimed delivery and moves it to the outcome's status
+	Record(ctx context.Context, claim *Claim, outcome Outcome) error
+}